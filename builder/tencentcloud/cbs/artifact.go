@@ -0,0 +1,105 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cbs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+type Artifact struct {
+	// TencentCloudSnapshots are the ids of snapshots keyed by region, in the
+	// order of cbs_volumes
+	TencentCloudSnapshots map[string][]string
+	BuilderIdValue        string
+	RegionClient          func(region string) cvm.APISender
+
+	// StateData should store data such as GeneratedData
+	// to be shared with post-processors
+	StateData map[string]interface{}
+}
+
+func (a *Artifact) BuilderId() string {
+	return a.BuilderIdValue
+}
+
+func (*Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	parts := make([]string, 0, len(a.TencentCloudSnapshots))
+	for region, snapshotIds := range a.TencentCloudSnapshots {
+		for _, snapshotId := range snapshotIds {
+			parts = append(parts, fmt.Sprintf("%s:%s", region, snapshotId))
+		}
+	}
+	sort.Strings(parts)
+
+	return strings.Join(parts, ",")
+}
+
+func (a *Artifact) String() string {
+	parts := make([]string, 0, len(a.TencentCloudSnapshots))
+	for region, snapshotIds := range a.TencentCloudSnapshots {
+		parts = append(parts, fmt.Sprintf("%s: %s", region, strings.Join(snapshotIds, ",")))
+	}
+	sort.Strings(parts)
+
+	return fmt.Sprintf("Tencentcloud snapshots(%s) were created.\n\n", strings.Join(parts, "\n"))
+}
+
+func (a *Artifact) State(name string) interface{} {
+	if _, ok := a.StateData[name]; ok {
+		return a.StateData[name]
+	}
+
+	switch name {
+	case "atlas.artifact.metadata":
+		return a.stateAtlasMetadata()
+	default:
+		return nil
+	}
+}
+
+func (a *Artifact) Destroy() error {
+	ctx := context.TODO()
+	errors := make([]error, 0)
+
+	for region, snapshotIds := range a.TencentCloudSnapshots {
+		client := a.RegionClient(region)
+		for _, snapshotId := range snapshotIds {
+			log.Printf("Delete tencentcloud snapshot ID(%s) from region(%s)", snapshotId, region)
+
+			if err := cvm.DeleteSnapshot(ctx, client, snapshotId); err != nil {
+				errors = append(errors, err)
+			}
+		}
+	}
+
+	if len(errors) == 1 {
+		return errors[0]
+	} else if len(errors) > 1 {
+		return &packersdk.MultiError{Errors: errors}
+	} else {
+		return nil
+	}
+}
+
+func (a *Artifact) stateAtlasMetadata() interface{} {
+	metadata := make(map[string]string)
+	for region, snapshotIds := range a.TencentCloudSnapshots {
+		k := fmt.Sprintf("region.%s", region)
+		metadata[k] = strings.Join(snapshotIds, ",")
+	}
+
+	return metadata
+}
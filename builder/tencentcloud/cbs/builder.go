@@ -0,0 +1,263 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,cbsVolume
+
+package cbs
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+const BuilderId = "tencent.cloud.cbs"
+
+type cbsVolume struct {
+	// The type of the disk, values can be `CLOUD_PREMIUM` (default),
+	// `CLOUD_BASIC`, `CLOUD_SSD`, `CLOUD_BSSD` and `CLOUD_HSSD`.
+	DiskType string `mapstructure:"disk_type" required:"false"`
+	// The size of the disk in GB, it can be omitted when `snapshot_id` is
+	// set, the size of the snapshot is used then.
+	DiskSize int64 `mapstructure:"disk_size" required:"false"`
+	// The name of the disk. Default value is `packer-<snapshot_name>`.
+	DiskName string `mapstructure:"disk_name" required:"false"`
+	// The snapshot the disk is created from, an empty disk is created if not
+	// set.
+	SnapshotId string `mapstructure:"snapshot_id" required:"false"`
+	// The name of the snapshot created from the disk, it should be composed
+	// of no more than 60 characters.
+	SnapshotName string `mapstructure:"snapshot_name" required:"true"`
+	// Key/value pair tags that will be applied to the snapshot created from
+	// the disk, and its copies.
+	SnapshotTags map[string]string `mapstructure:"snapshot_tags" required:"false"`
+}
+
+type Config struct {
+	common.PackerConfig          `mapstructure:",squash"`
+	cvm.TencentCloudAccessConfig `mapstructure:",squash"`
+	cvm.TencentCloudRunConfig    `mapstructure:",squash"`
+
+	// The data disks created and attached to the cvm after it is launched,
+	// a snapshot is created of each disk after provisioning.
+	CbsVolumes []cbsVolume `mapstructure:"cbs_volumes" required:"true"`
+	// Regions that the snapshots will be copied to after they are created.
+	SnapshotCopyRegions []string `mapstructure:"snapshot_copy_regions" required:"false"`
+	// The command to shutdown cvm gracefully through communicator before
+	// creating snapshots, such as `sudo shutdown -h now`. The cvm is stopped
+	// by api with `stop_type` if it is not set or failed.
+	ShutdownCommand string `mapstructure:"shutdown_command" required:"false"`
+	// The time to wait for the cvm to be stopped after `shutdown_command`,
+	// default value is `5m`.
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout" required:"false"`
+	// The stop type when stopping cvm by api, values can be `SOFT` (default),
+	// `HARD` and `SOFT_FIRST`.
+	StopType string `mapstructure:"stop_type" required:"false"`
+	// Do not check region and zone when validate.
+	SkipRegionValidation bool `mapstructure:"skip_region_validation" required:"false"`
+
+	ctx     interpolate.Context
+	buildId string
+}
+
+type Builder struct {
+	config Config
+	runner multistep.Runner
+}
+
+func (b *Builder) ConfigSpec() hcldec.ObjectSpec { return b.config.FlatMapstructure().HCL2Spec() }
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
+	var md mapstructure.Metadata
+	err := config.Decode(&b.config, &config.DecodeOpts{
+		Metadata:           &md,
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &b.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"run_command",
+				// rendered with build info when creating resources
+				"default_tags",
+				"run_tags",
+				// rendered with build info when launching cvm, if enabled
+				"user_data_parts",
+			},
+		},
+	}, raws...)
+	b.config.ctx.EnableEnv = true
+	if err != nil {
+		return nil, nil, err
+	}
+	b.config.TencentCloudRunConfig.SetExplicitKeys(md.Keys)
+
+	if b.config.ShutdownTimeout <= 0 {
+		b.config.ShutdownTimeout = 5 * time.Minute
+	}
+
+	// Accumulate any errors
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, cvm.PrepareAccess(&b.config.ctx, &b.config.TencentCloudAccessConfig,
+		b.config.SkipRegionValidation)...)
+	// launch template is required to check the conflicts with run config
+	if b.config.LaunchTemplateId != "" && (errs == nil || len(errs.Errors) == 0) {
+		if err := b.config.LoadLaunchTemplate(&b.config.TencentCloudAccessConfig); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
+	errs = packersdk.MultiErrorAppend(errs, b.config.TencentCloudRunConfig.Prepare(&b.config.ctx)...)
+
+	if len(b.config.CbsVolumes) == 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("cbs_volumes must be specified"))
+	}
+	for i := range b.config.CbsVolumes {
+		volume := &b.config.CbsVolumes[i]
+		if volume.DiskType == "" {
+			volume.DiskType = "CLOUD_PREMIUM"
+		}
+		if cvm.IsLocalDiskType(volume.DiskType) {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("cbs_volumes[%d]: disk_type(%s) is not a cloud disk", i, volume.DiskType))
+		}
+		if volume.DiskSize < 0 || (volume.DiskSize == 0 && volume.SnapshotId == "") {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("cbs_volumes[%d]: disk_size must be positive", i))
+		}
+		if volume.SnapshotName == "" {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("cbs_volumes[%d]: snapshot_name must be specified", i))
+		} else if utf8.RuneCountInString(volume.SnapshotName) > 60 {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("cbs_volumes[%d]: snapshot_name length should not exceed 60 characters", i))
+		}
+		if volume.DiskName == "" {
+			volume.DiskName = fmt.Sprintf("packer-%s", volume.SnapshotName)
+		}
+	}
+
+	regions, regionErrs := cvm.PrepareCopyRegions(&b.config.TencentCloudAccessConfig, b.config.SnapshotCopyRegions,
+		b.config.SkipRegionValidation)
+	b.config.SnapshotCopyRegions = regions
+	errs = packersdk.MultiErrorAppend(errs, regionErrs...)
+
+	switch b.config.StopType {
+	case "":
+		b.config.StopType = "SOFT"
+	case "SOFT", "HARD", "SOFT_FIRST":
+	default:
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("specified stop_type(%s) is invalid", b.config.StopType))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	cvm.LogSecrets(append(b.config.TencentCloudAccessConfig.SensitiveValues(),
+		b.config.TencentCloudRunConfig.SensitiveValues()...)...)
+
+	return nil, nil, nil
+}
+
+// BuildInfo returns the build info of the config for the shared steps
+func (c *Config) BuildInfo() *cvm.BuildInfo {
+	return &cvm.BuildInfo{
+		Region:      c.Region,
+		BuildId:     c.buildId,
+		BuildName:   c.PackerBuildName,
+		DefaultTags: c.DefaultTags,
+		Ctx:         c.ctx,
+	}
+}
+
+func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
+	if err := cvm.SetApiTraceFile(b.config.ApiTraceFile); err != nil {
+		return nil, err
+	}
+	defer cvm.SetApiTraceFile("")
+
+	cvmClient, vpcClient, err := b.config.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	b.config.buildId = cvm.NewBuildId()
+
+	regionClient := func(region string) cvm.APISender {
+		return cvm.NewCommonClient(&b.config.TencentCloudAccessConfig, region)
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &b.config)
+	state.Put("cvm_client", cvmClient)
+	state.Put("vpc_client", vpcClient)
+	state.Put("common_client", regionClient(b.config.Region))
+	state.Put("common_region_client", regionClientFunc(regionClient))
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+
+	var steps []multistep.Step
+	steps = append(steps, cvm.LaunchSteps(&b.config.TencentCloudAccessConfig, &b.config.TencentCloudRunConfig,
+		&b.config.PackerConfig)...)
+	steps = append(steps,
+		&stepCreateVolumes{
+			Volumes: b.config.CbsVolumes,
+		},
+		&communicator.StepConnect{
+			Config:    &b.config.TencentCloudRunConfig.Comm,
+			SSHConfig: b.config.TencentCloudRunConfig.Comm.SSHConfigFunc(),
+			Host:      cvm.SSHHost(b.config.SSHInterface, b.config.SSHInterfaceTimeout, b.config.HostName),
+		},
+		&commonsteps.StepProvision{},
+		&commonsteps.StepCleanupTempKeys{
+			Comm: &b.config.TencentCloudRunConfig.Comm,
+		},
+		// the disks are stopped with the cvm for consistent snapshots
+		&cvm.StepShutdownInstance{
+			ShutdownBehavior: "stop",
+			ShutdownCommand:  b.config.ShutdownCommand,
+			ShutdownTimeout:  b.config.ShutdownTimeout,
+			StopType:         b.config.StopType,
+		},
+		&cvm.StepDetachTempKeyPair{
+			StopType: b.config.StopType,
+		},
+		&stepCreateSnapshots{
+			Volumes: b.config.CbsVolumes,
+		},
+		&stepCopySnapshots{
+			Volumes:           b.config.CbsVolumes,
+			DesinationRegions: b.config.SnapshotCopyRegions,
+			SourceRegion:      b.config.Region,
+		},
+	)
+
+	b.runner = commonsteps.NewRunner(steps, b.config.PackerConfig, ui)
+	b.runner.Run(ctx, state)
+	cvm.ReportLeaks(state)
+
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, rawErr.(error)
+	}
+
+	if _, ok := state.GetOk("snapshots"); !ok {
+		return nil, nil
+	}
+
+	artifact := &Artifact{
+		TencentCloudSnapshots: state.Get("snapshots").(map[string][]string),
+		BuilderIdValue:        BuilderId,
+		RegionClient:          regionClient,
+		StateData:             map[string]interface{}{"generated_data": state.Get("generated_data")},
+	}
+
+	return artifact, nil
+}
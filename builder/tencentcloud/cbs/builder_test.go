@@ -0,0 +1,184 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cbs
+
+import (
+	"strings"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/mockapi"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"secret_id":                "secret-id",
+		"secret_key":               "secret-key",
+		"region":                   "ap-guangzhou",
+		"source_image_id":          "img-source01",
+		"instance_type_candidates": []string{"S5.MEDIUM2"},
+		"communicator":             "none",
+		"skip_region_validation":   true,
+		"cbs_volumes": []map[string]interface{}{
+			{
+				"disk_size":     50,
+				"snapshot_name": "packer-data",
+			},
+		},
+	}
+}
+
+func TestBuilder_Prepare(t *testing.T) {
+	raw := testConfig()
+	raw["snapshot_copy_regions"] = []string{"ap-shanghai", "ap-shanghai"}
+
+	var b Builder
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("prepare: %s", err)
+	}
+
+	volume := b.config.CbsVolumes[0]
+	if volume.DiskType != "CLOUD_PREMIUM" || volume.DiskName != "packer-packer-data" {
+		t.Errorf("unexpected defaults: disk_type %q, disk_name %q", volume.DiskType, volume.DiskName)
+	}
+	if b.config.StopType != "SOFT" {
+		t.Errorf("expected default stop_type SOFT, got %q", b.config.StopType)
+	}
+	if len(b.config.SnapshotCopyRegions) != 1 {
+		t.Errorf("expected duplicated regions removed, got %v", b.config.SnapshotCopyRegions)
+	}
+}
+
+func TestBuilder_Prepare_errors(t *testing.T) {
+	mockapi.CheckPrepareErrors(t, func() packersdk.Builder { return &Builder{} }, testConfig(), map[string]mockapi.PrepareError{
+		"no volumes":    {Key: "cbs_volumes", Err: "cbs_volumes must be specified"},
+		"disk size":     {Key: "cbs_volumes", Value: []map[string]interface{}{{"snapshot_name": "data"}}, Err: "disk_size must be positive"},
+		"snapshot name": {Key: "cbs_volumes", Value: []map[string]interface{}{{"disk_size": 50}}, Err: "snapshot_name must be specified"},
+		"local disk": {Key: "cbs_volumes", Value: []map[string]interface{}{
+			{"disk_type": "LOCAL_BASIC", "disk_size": 50, "snapshot_name": "data"},
+		}, Err: "is not a cloud disk"},
+		"stop type": {Key: "stop_type", Value: "NOW", Err: "stop_type(NOW) is invalid"},
+	})
+}
+
+// runBuild runs a build of the mock api server with the none communicator
+func runBuild(t *testing.T, server *mockapi.Server) (packersdk.Artifact, error) {
+	return server.Build(t, &Builder{}, nil, map[string]interface{}{
+		"zone":                     "ap-guangzhou-3",
+		"source_image_id":          "img-source01",
+		"instance_type_candidates": []string{"S5.MEDIUM2"},
+		"communicator":             "none",
+		"cbs_volumes": []map[string]interface{}{
+			{
+				"disk_size":     50,
+				"snapshot_name": "packer-data",
+				"snapshot_tags": map[string]string{"usage": "data"},
+			},
+			{
+				"disk_type":     "CLOUD_SSD",
+				"disk_size":     100,
+				"snapshot_name": "packer-logs",
+			},
+		},
+		"snapshot_copy_regions": []string{"ap-guangzhou", "ap-shanghai"},
+	})
+}
+
+func TestBuilder_Run(t *testing.T) {
+	cases := []struct {
+		name   string
+		faults []mockapi.Fault
+		err    string
+		// untagged means the snapshots are built without tags
+		untagged bool
+	}{
+		{
+			name: "success",
+		},
+		{
+			name:     "tag failed",
+			faults:   []mockapi.Fault{{Action: "TagResources", Code: "AuthFailure.UnauthorizedOperation"}},
+			untagged: true,
+		},
+		{
+			name:   "create snapshot failed",
+			faults: []mockapi.Fault{{Action: "CreateSnapshot", Code: mockapi.CodeQuota}},
+			err:    mockapi.CodeQuota,
+		},
+		{
+			name:   "copy snapshot failed",
+			faults: []mockapi.Fault{{Action: "CopySnapshotCrossRegions", Code: mockapi.CodeQuota}},
+			err:    mockapi.CodeQuota,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := mockapi.NewServer("AKIDmock", "mock-secret")
+			defer server.Close()
+			server.AddImage("ap-guangzhou", "img-source01", "TencentOS Server 3.1")
+			for _, f := range tc.faults {
+				server.Inject(f)
+			}
+
+			artifact, err := runBuild(t, server)
+			for _, region := range []string{"ap-guangzhou", "ap-shanghai"} {
+				server.AssertNoLeaks(t, region, "image", "snapshot")
+			}
+
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error %q, got %v", tc.err, err)
+				}
+				for _, region := range []string{"ap-guangzhou", "ap-shanghai"} {
+					if n := len(server.Snapshots(region)); n != 0 {
+						t.Errorf("expected snapshots deleted in %s, got %d snapshots", region, n)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+
+			snapshots := artifact.(*Artifact).TencentCloudSnapshots
+			for _, region := range []string{"ap-guangzhou", "ap-shanghai"} {
+				if len(snapshots[region]) != 2 {
+					t.Fatalf("expected 2 snapshots of artifact in %s, got %v", region, snapshots[region])
+				}
+				// the copies keep the order, size and tags of the volumes
+				for i, volume := range []struct {
+					name  string
+					size  int64
+					usage string
+				}{{"packer-data", 50, "data"}, {"packer-logs", 100, ""}} {
+					found := false
+					for _, sn := range server.Snapshots(region) {
+						if sn.SnapshotId == snapshots[region][i] && sn.SnapshotName == volume.name &&
+							sn.DiskSize == volume.size && sn.SnapshotState == "NORMAL" {
+							found = true
+						}
+					}
+					if !found {
+						t.Errorf("expected snapshot %q named %s of %dGB in %s", snapshots[region][i], volume.name, volume.size, region)
+					}
+					tags := server.Tags(server.ResourceName("cvm", region, "snapshot", snapshots[region][i]))
+					if !tc.untagged && (tags["usage"] != volume.usage || tags["packer-build-id"] == "") {
+						t.Errorf("unexpected tags of snapshot %s in %s: %v", volume.name, region, tags)
+					}
+				}
+			}
+
+			if err := artifact.Destroy(); err != nil {
+				t.Fatalf("destroy: %s", err)
+			}
+			for _, region := range []string{"ap-guangzhou", "ap-shanghai"} {
+				if n := len(server.Snapshots(region)); n != 0 {
+					t.Errorf("expected snapshots destroyed in %s, got %d snapshots", region, n)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cbs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// regionClientFunc returns the client sending common requests to region, it
+// is put in the state bag as "common_region_client"
+type regionClientFunc func(region string) cvm.APISender
+
+// stepCopySnapshots copies the snapshots to the destination regions, and adds
+// the copies to "snapshots".
+type stepCopySnapshots struct {
+	Volumes           []cbsVolume
+	DesinationRegions []string
+	SourceRegion      string
+	// copied are the ids of the copied snapshots keyed by region
+	copied map[string][]string
+}
+
+func (s *stepCopySnapshots) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	var regions []string
+	for _, region := range s.DesinationRegions {
+		if region != s.SourceRegion {
+			regions = append(regions, region)
+		}
+	}
+	if len(regions) == 0 {
+		return multistep.ActionContinue
+	}
+
+	client := state.Get("common_client").(cvm.APISender)
+	regionClient := state.Get("common_region_client").(regionClientFunc)
+	snapshots := state.Get("snapshots").(map[string][]string)
+
+	cvm.Say(state, strings.Join(regions, ","), "Trying to copy snapshots to")
+
+	s.copied = make(map[string][]string)
+	for i, snapshotId := range snapshots[s.SourceRegion] {
+		volume := s.Volumes[i]
+
+		copied, err := cvm.CopySnapshot(ctx, client, snapshotId, regions, volume.SnapshotName)
+		for region, id := range copied {
+			s.copied[region] = append(s.copied[region], id)
+		}
+		if err != nil {
+			return cvm.Halt(state, err, "Failed to copy snapshot")
+		}
+
+		cvm.Message(state, "Waiting for snapshot ready", "")
+		tags, err := cvm.ResourceTags(state, volume.SnapshotTags)
+		if err != nil {
+			return cvm.Halt(state, err, "Failed to get tags")
+		}
+		for _, region := range regions {
+			rc := regionClient(region)
+			if _, err := cvm.WaitForSnapshot(ctx, rc, copied[region], "NORMAL", 3600); err != nil {
+				return cvm.Halt(state, err, "Failed to wait for snapshot ready")
+			}
+			// tagging for cost allocation doesn't fail the build
+			if err := cvm.TagCvmResources(ctx, state, region, "snapshot", []string{copied[region]}, tags); err != nil {
+				cvm.Message(state, fmt.Sprintf("%s, skip tagging snapshot", err), "Failed to tag snapshot")
+			}
+		}
+	}
+
+	for region, ids := range s.copied {
+		snapshots[region] = ids
+	}
+	cvm.Message(state, strings.Join(regions, ","), "Snapshots copied to")
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCopySnapshots) Cleanup(state multistep.StateBag) {
+	if len(s.copied) == 0 {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	ctx := context.TODO()
+	regionClient := state.Get("common_region_client").(regionClientFunc)
+
+	cvm.SayClean(state, "copied snapshots")
+
+	for region, ids := range s.copied {
+		rc := regionClient(region)
+		for _, snapshotId := range ids {
+			// the copy may be still in progress
+			if _, err := cvm.WaitForSnapshot(ctx, rc, snapshotId, "NORMAL", 3600); err != nil {
+				cvm.Leak(state, err, "snapshot", snapshotId)
+				continue
+			}
+			if err := cvm.DeleteSnapshot(ctx, rc, snapshotId); err != nil {
+				cvm.Leak(state, err, "snapshot", snapshotId)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cbs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// stepCreateSnapshots creates a snapshot of each disk of the stopped cvm.
+//
+// Produces:
+//
+//	snapshots map[string][]string - The ids of the snapshots keyed by region,
+//	in the order of Volumes
+type stepCreateSnapshots struct {
+	Volumes     []cbsVolume
+	snapshotIds []string
+}
+
+func (s *stepCreateSnapshots) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("common_client").(cvm.APISender)
+	config := state.Get("config").(*Config)
+	diskIds := state.Get("volume_ids").([]string)
+
+	for i, volume := range s.Volumes {
+		cvm.Say(state, diskIds[i], "Trying to create a snapshot of disk")
+
+		snapshotId, err := cvm.CreateSnapshot(ctx, client, diskIds[i], volume.SnapshotName)
+		if err != nil {
+			return cvm.Halt(state, err, "Failed to create snapshot")
+		}
+		s.snapshotIds = append(s.snapshotIds, snapshotId)
+
+		cvm.Message(state, "Waiting for snapshot ready", "")
+		if _, err := cvm.WaitForSnapshot(ctx, client, snapshotId, "NORMAL", 3600); err != nil {
+			return cvm.Halt(state, err, "Failed to wait for snapshot ready")
+		}
+
+		tags, err := cvm.ResourceTags(state, volume.SnapshotTags)
+		if err != nil {
+			return cvm.Halt(state, err, "Failed to get tags")
+		}
+		// tagging for cost allocation doesn't fail the build
+		if err := cvm.TagCvmResources(ctx, state, config.Region, "snapshot", []string{snapshotId}, tags); err != nil {
+			cvm.Message(state, fmt.Sprintf("%s, skip tagging snapshot", err), "Failed to tag snapshot")
+		}
+
+		cvm.Message(state, snapshotId, "Snapshot created")
+	}
+
+	snapshots := make(map[string][]string)
+	snapshots[config.Region] = s.snapshotIds
+	state.Put("snapshots", snapshots)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateSnapshots) Cleanup(state multistep.StateBag) {
+	if len(s.snapshotIds) == 0 {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	ctx := context.TODO()
+	client := state.Get("common_client").(cvm.APISender)
+
+	cvm.SayClean(state, "snapshots")
+
+	for _, snapshotId := range s.snapshotIds {
+		if err := cvm.DeleteSnapshot(ctx, client, snapshotId); err != nil {
+			cvm.Leak(state, err, "snapshot", snapshotId)
+		}
+	}
+}
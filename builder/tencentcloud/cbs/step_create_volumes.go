@@ -0,0 +1,106 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cbs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	cvmapi "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// stepCreateVolumes creates the disks in the zone of the cvm, and attaches
+// them to it.
+//
+// Produces:
+//
+//	volume_ids []string - The ids of the disks, in the order of Volumes
+type stepCreateVolumes struct {
+	Volumes  []cbsVolume
+	diskIds  []string
+	attached map[string]bool
+}
+
+func (s *stepCreateVolumes) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("common_client").(cvm.APISender)
+	config := state.Get("config").(*Config)
+	instance := state.Get("instance").(*cvmapi.Instance)
+
+	tags, err := cvm.ResourceTags(state, nil)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to get tags")
+	}
+
+	s.attached = make(map[string]bool)
+	for _, volume := range s.Volumes {
+		cvm.Say(state, volume.DiskName, "Trying to create a disk")
+
+		diskId, err := cvm.CreateDisk(ctx, client, &cvm.CbsDiskOptions{
+			Zone:       *instance.Placement.Zone,
+			DiskType:   volume.DiskType,
+			DiskSize:   volume.DiskSize,
+			DiskName:   volume.DiskName,
+			SnapshotId: volume.SnapshotId,
+		})
+		if err != nil {
+			return cvm.Halt(state, err, "Failed to create disk")
+		}
+		s.diskIds = append(s.diskIds, diskId)
+
+		if _, err := cvm.WaitForDisk(ctx, client, diskId, "UNATTACHED", 1800); err != nil {
+			return cvm.Halt(state, err, "Failed to wait for disk ready")
+		}
+		// tagging for cost allocation doesn't fail the build
+		if err := cvm.TagCvmResources(ctx, state, config.Region, "volume", []string{diskId}, tags); err != nil {
+			cvm.Message(state, fmt.Sprintf("%s, skip tagging disk", err), "Failed to tag disk")
+		}
+
+		cvm.Say(state, fmt.Sprintf("%s to %s", diskId, *instance.InstanceId), "Trying to attach disk")
+		if err := cvm.AttachDisk(ctx, client, diskId, *instance.InstanceId); err != nil {
+			return cvm.Halt(state, err, "Failed to attach disk")
+		}
+		s.attached[diskId] = true
+
+		if _, err := cvm.WaitForDisk(ctx, client, diskId, "ATTACHED", 1800); err != nil {
+			return cvm.Halt(state, err, "Failed to wait for disk attached")
+		}
+		cvm.Message(state, diskId, "Disk attached")
+	}
+
+	state.Put("volume_ids", s.diskIds)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateVolumes) Cleanup(state multistep.StateBag) {
+	if len(s.diskIds) == 0 {
+		return
+	}
+
+	ctx := context.TODO()
+	client := state.Get("common_client").(cvm.APISender)
+
+	cvm.SayClean(state, "disks")
+
+	// the disks are detached before the cvm is terminated, otherwise they
+	// are left as pay-as-you-go disks
+	for _, diskId := range s.diskIds {
+		if s.attached[diskId] {
+			if err := cvm.DetachDisk(ctx, client, diskId); err != nil {
+				cvm.Leak(state, err, "disk", diskId)
+				continue
+			}
+			if _, err := cvm.WaitForDisk(ctx, client, diskId, "UNATTACHED", 1800); err != nil {
+				cvm.Leak(state, err, "disk", diskId)
+				continue
+			}
+		}
+		if err := cvm.TerminateDisk(ctx, client, diskId); err != nil {
+			cvm.Leak(state, err, "disk", diskId)
+		}
+	}
+}
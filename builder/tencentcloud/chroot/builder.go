@@ -0,0 +1,295 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package chroot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/chroot"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+const BuilderId = "tencent.cloud.chroot"
+
+type Config struct {
+	common.PackerConfig          `mapstructure:",squash"`
+	cvm.TencentCloudAccessConfig `mapstructure:",squash"`
+	cvm.TencentCloudImageConfig  `mapstructure:",squash"`
+
+	// The base image id of image you want to create your customized image
+	// from, the disk is created from the snapshot of its system disk.
+	SourceImageId string `mapstructure:"source_image_id" required:"true"`
+	// The type of the disk created from the source image, values can be
+	// `CLOUD_PREMIUM` (default), `CLOUD_SSD`, `CLOUD_BSSD` and `CLOUD_HSSD`.
+	RootVolumeType string `mapstructure:"root_volume_type" required:"false"`
+	// The size of the disk created from the source image in GB, it can not
+	// be smaller than the system disk of the source image, which is the
+	// default value.
+	RootVolumeSize int64 `mapstructure:"root_volume_size" required:"false"`
+	// The path of the device of the disk once it is attached. Default value
+	// is `/dev/disk/by-id/virtio-<disk id>`, which is created by udev for
+	// cbs disks.
+	DevicePath string `mapstructure:"device_path" required:"false"`
+	// The path where the device is mounted. Default value is
+	// `/mnt/packer-tencentcloud-chroot-volumes/{{.Device}}`, where
+	// `{{.Device}}` is the name of the device.
+	MountPath string `mapstructure:"mount_path" required:"false"`
+	// The partition of the device to mount, `0` means the whole device.
+	// Default value is `1`.
+	MountPartition string `mapstructure:"mount_partition" required:"false"`
+	// Options passed to `mount -o` when mounting the device.
+	MountOptions []string `mapstructure:"mount_options" required:"false"`
+	// A list of devices to mount into the chroot, each item is a list of
+	// the filesystem type, the source and the mount path in chroot. Default
+	// value mounts `/proc`, `/sys`, `/dev`, `/dev/pts` and
+	// `/proc/sys/fs/binfmt_misc`.
+	ChrootMounts [][]string `mapstructure:"chroot_mounts" required:"false"`
+	// Paths of files copied from the host into the chroot before
+	// provisioning, and removed afterwards. Default value is
+	// `["/etc/resolv.conf"]`.
+	CopyFiles []string `mapstructure:"copy_files" required:"false"`
+	// How to run the commands on the host, such as `sudo {{.Command}}`.
+	// Default value is `{{.Command}}`.
+	CommandWrapper string `mapstructure:"command_wrapper" required:"false"`
+	// Commands run on the host before the device is mounted, `{{.Device}}`
+	// is available as template.
+	PreMountCommands []string `mapstructure:"pre_mount_commands" required:"false"`
+	// Commands run on the host after the device is mounted, `{{.Device}}`
+	// and `{{.MountPath}}` are available as template.
+	PostMountCommands []string `mapstructure:"post_mount_commands" required:"false"`
+	// Key/value pair tags that will be applied to every resource the builder
+	// creates, including the disk, the snapshot and the image.
+	// `packer-build-id` and `packer-build-name` tags are always added. Build
+	// info such as `{{ .BuildRegion }}`, `{{ .SourceImageId }}` and
+	// `{{ .SourceImageName }}` can be used in the tags as template.
+	DefaultTags map[string]string `mapstructure:"default_tags" required:"false"`
+	// Do not check region when validate.
+	SkipRegionValidation bool `mapstructure:"skip_region_validation" required:"false"`
+
+	ctx     interpolate.Context
+	buildId string
+}
+
+type wrappedCommandTemplate struct {
+	Command string
+}
+
+type Builder struct {
+	config Config
+	runner multistep.Runner
+}
+
+func (b *Builder) ConfigSpec() hcldec.ObjectSpec { return b.config.FlatMapstructure().HCL2Spec() }
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
+	err := config.Decode(&b.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &b.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				// rendered when running the commands
+				"command_wrapper",
+				"mount_path",
+				"pre_mount_commands",
+				"post_mount_commands",
+				// rendered with build info when creating resources
+				"default_tags",
+				"image_tags",
+			},
+		},
+	}, raws...)
+	b.config.ctx.EnableEnv = true
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if b.config.RootVolumeType == "" {
+		b.config.RootVolumeType = "CLOUD_PREMIUM"
+	}
+	if b.config.MountPath == "" {
+		b.config.MountPath = "/mnt/packer-tencentcloud-chroot-volumes/{{.Device}}"
+	}
+	if b.config.MountPartition == "" {
+		b.config.MountPartition = "1"
+	}
+	if b.config.ChrootMounts == nil {
+		b.config.ChrootMounts = [][]string{
+			{"proc", "proc", "/proc"},
+			{"sysfs", "sysfs", "/sys"},
+			{"bind", "/dev", "/dev"},
+			{"devpts", "devpts", "/dev/pts"},
+			{"binfmt_misc", "binfmt_misc", "/proc/sys/fs/binfmt_misc"},
+		}
+	}
+	if b.config.CopyFiles == nil {
+		b.config.CopyFiles = []string{"/etc/resolv.conf"}
+	}
+	if b.config.CommandWrapper == "" {
+		b.config.CommandWrapper = "{{.Command}}"
+	}
+
+	// Accumulate any errors
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, cvm.PrepareAccessAndImage(&b.config.ctx, &b.config.TencentCloudAccessConfig,
+		&b.config.TencentCloudImageConfig, b.config.SkipRegionValidation)...)
+
+	if b.config.SourceImageId == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("source_image_id must be specified"))
+	}
+	if b.config.RootVolumeSize < 0 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("root_volume_size should not be negative"))
+	}
+	for _, mount := range b.config.ChrootMounts {
+		if len(mount) != 3 {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("each chroot_mounts entry should have three elements"))
+			break
+		}
+	}
+	if runtime.GOOS != "linux" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("the tencentcloud-chroot builder only works on Linux"))
+	}
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	cvm.LogSecrets(b.config.SensitiveValues()...)
+
+	return nil, nil, nil
+}
+
+// GetContext returns the context to render the commands run on host
+func (c *Config) GetContext() interpolate.Context {
+	return c.ctx
+}
+
+// BuildInfo returns the build info of the config for the shared steps
+func (c *Config) BuildInfo() *cvm.BuildInfo {
+	return &cvm.BuildInfo{
+		Region:      c.Region,
+		BuildId:     c.buildId,
+		BuildName:   c.PackerBuildName,
+		DefaultTags: c.DefaultTags,
+		ImageName:   c.ImageName,
+		ImageTags:   c.ImageTags,
+		Ctx:         c.ctx,
+	}
+}
+
+func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
+	if err := cvm.SetApiTraceFile(b.config.ApiTraceFile); err != nil {
+		return nil, err
+	}
+	defer cvm.SetApiTraceFile("")
+
+	cvmClient, err := cvm.NewCvmClient(&b.config.TencentCloudAccessConfig, b.config.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	b.config.buildId = cvm.NewBuildId()
+
+	wrappedCommand := func(command string) (string, error) {
+		ictx := b.config.ctx
+		ictx.Data = &wrappedCommandTemplate{Command: command}
+		return interpolate.Render(b.config.CommandWrapper, &ictx)
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &b.config)
+	state.Put("cvm_client", cvmClient)
+	state.Put("common_client", cvm.NewCommonClient(&b.config.TencentCloudAccessConfig, b.config.Region))
+	state.Put("cvm_region_client", cvm.RegionCVMClientFunc(func(region string) (cvm.CVMAPI, error) {
+		return cvm.NewCvmClient(&b.config.TencentCloudAccessConfig, region)
+	}))
+	state.Put("wrappedCommand", common.CommandWrapper(wrappedCommand))
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+
+	steps := []multistep.Step{
+		&stepInstanceInfo{},
+		&cvm.StepPreValidate{
+			ForceDelete:  b.config.ImageForceDelete,
+			SkipIfExists: b.config.SkipIfExists,
+		},
+		&stepCheckSourceImage{
+			SourceImageId: b.config.SourceImageId,
+		},
+		&stepCreateDisk{
+			DiskType: b.config.RootVolumeType,
+			DiskSize: b.config.RootVolumeSize,
+		},
+		&stepAttachDisk{
+			DevicePath: b.config.DevicePath,
+		},
+		&chroot.StepPreMountCommands{
+			Commands: b.config.PreMountCommands,
+		},
+		&stepMountDevice{
+			MountPath:      b.config.MountPath,
+			MountOptions:   b.config.MountOptions,
+			MountPartition: b.config.MountPartition,
+		},
+		&chroot.StepPostMountCommands{
+			Commands: b.config.PostMountCommands,
+		},
+		&chroot.StepMountExtra{
+			ChrootMounts: b.config.ChrootMounts,
+		},
+		&chroot.StepCopyFiles{
+			Files: b.config.CopyFiles,
+		},
+		&chroot.StepChrootProvision{},
+		&chroot.StepEarlyCleanup{},
+		&stepCreateSnapshot{},
+		&stepRegisterImage{},
+		&cvm.StepShareImage{
+			ShareAccounts: b.config.ImageShareAccounts,
+		},
+		&cvm.StepCopyImage{
+			DesinationRegions: b.config.ImageCopyRegions,
+			SourceRegion:      b.config.Region,
+			Encrypted:         b.config.ImageCopyEncrypted,
+			KmsKeyIds:         b.config.ImageCopyKmsKeyIds,
+		},
+	}
+
+	b.runner = commonsteps.NewRunner(steps, b.config.PackerConfig, ui)
+	b.runner.Run(ctx, state)
+	cvm.ReportLeaks(state)
+
+	if rawErr, ok := state.GetOk("error"); ok {
+		if b.config.SkipIfExists && errors.Is(rawErr.(error), cvm.ImageExistsError) {
+			cvm.Say(state, "Image exists, Skipping...", "")
+			return nil, nil
+		}
+		return nil, rawErr.(error)
+	}
+
+	if _, ok := state.GetOk("image"); !ok {
+		return nil, nil
+	}
+
+	artifact := &cvm.Artifact{
+		TencentCloudImages: state.Get("tencentcloudimages").(map[string]string),
+		BuilderIdValue:     BuilderId,
+		Client:             cvmClient,
+		StateData:          map[string]interface{}{"generated_data": state.Get("generated_data")},
+	}
+
+	return artifact, nil
+}
@@ -0,0 +1,132 @@
+// Code generated by "packer-sdc mapstructure-to-hcl2"; DO NOT EDIT.
+
+package chroot
+
+import (
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatConfig struct {
+	PackerBuildName      *string                             `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType    *string                             `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion    *string                             `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug          *bool                               `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce          *bool                               `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError        *string                             `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars       map[string]string                   `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars  []string                            `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	SecretId             *string                             `mapstructure:"secret_id" required:"true" cty:"secret_id" hcl:"secret_id"`
+	SecretKey            *string                             `mapstructure:"secret_key" required:"true" cty:"secret_key" hcl:"secret_key"`
+	Region               *string                             `mapstructure:"region" required:"true" cty:"region" hcl:"region"`
+	Zone                 *string                             `mapstructure:"zone" required:"true" cty:"zone" hcl:"zone"`
+	CvmEndpoint          *string                             `mapstructure:"cvm_endpoint" required:"false" cty:"cvm_endpoint" hcl:"cvm_endpoint"`
+	VpcEndpoint          *string                             `mapstructure:"vpc_endpoint" required:"false" cty:"vpc_endpoint" hcl:"vpc_endpoint"`
+	Endpoints            map[string]string                   `mapstructure:"endpoints" required:"false" cty:"endpoints" hcl:"endpoints"`
+	UseInternalEndpoint  *bool                               `mapstructure:"use_internal_endpoint" required:"false" cty:"use_internal_endpoint" hcl:"use_internal_endpoint"`
+	HttpProxy            *string                             `mapstructure:"http_proxy" required:"false" cty:"http_proxy" hcl:"http_proxy"`
+	CACertFile           *string                             `mapstructure:"ca_cert_file" required:"false" cty:"ca_cert_file" hcl:"ca_cert_file"`
+	RequestTimeout       *string                             `mapstructure:"request_timeout" required:"false" cty:"request_timeout" hcl:"request_timeout"`
+	ApiRetry             *cvm.FlatTencentCloudApiRetryConfig `mapstructure:"api_retry" required:"false" cty:"api_retry" hcl:"api_retry"`
+	ApiTraceFile         *string                             `mapstructure:"api_trace_file" required:"false" cty:"api_trace_file" hcl:"api_trace_file"`
+	ImageName            *string                             `mapstructure:"image_name" required:"true" cty:"image_name" hcl:"image_name"`
+	ImageDescription     *string                             `mapstructure:"image_description" required:"false" cty:"image_description" hcl:"image_description"`
+	Reboot               *bool                               `mapstructure:"reboot" required:"false" cty:"reboot" hcl:"reboot"`
+	ShutdownBehavior     *string                             `mapstructure:"shutdown_behavior" required:"false" cty:"shutdown_behavior" hcl:"shutdown_behavior"`
+	ShutdownCommand      *string                             `mapstructure:"shutdown_command" required:"false" cty:"shutdown_command" hcl:"shutdown_command"`
+	ShutdownTimeout      *string                             `mapstructure:"shutdown_timeout" required:"false" cty:"shutdown_timeout" hcl:"shutdown_timeout"`
+	StopType             *string                             `mapstructure:"stop_type" required:"false" cty:"stop_type" hcl:"stop_type"`
+	ForcePoweroff        *bool                               `mapstructure:"force_poweroff" required:"false" cty:"force_poweroff" hcl:"force_poweroff"`
+	Sysprep              *bool                               `mapstructure:"sysprep" required:"false" cty:"sysprep" hcl:"sysprep"`
+	ImageForceDelete     *bool                               `mapstructure:"image_force_delete" cty:"image_force_delete" hcl:"image_force_delete"`
+	ImageCopyRegions     []string                            `mapstructure:"image_copy_regions" required:"false" cty:"image_copy_regions" hcl:"image_copy_regions"`
+	ImageCopyEncrypted   *bool                               `mapstructure:"image_copy_encrypted" required:"false" cty:"image_copy_encrypted" hcl:"image_copy_encrypted"`
+	ImageCopyKmsKeyIds   map[string]string                   `mapstructure:"image_copy_kms_key_ids" required:"false" cty:"image_copy_kms_key_ids" hcl:"image_copy_kms_key_ids"`
+	ImageShareAccounts   []string                            `mapstructure:"image_share_accounts" required:"false" cty:"image_share_accounts" hcl:"image_share_accounts"`
+	ImageTags            map[string]string                   `mapstructure:"image_tags" required:"false" cty:"image_tags" hcl:"image_tags"`
+	SkipIfExists         *bool                               `mapstructure:"skip_if_exists" required:"false" cty:"skip_if_exists" hcl:"skip_if_exists"`
+	SourceImageId        *string                             `mapstructure:"source_image_id" required:"true" cty:"source_image_id" hcl:"source_image_id"`
+	RootVolumeType       *string                             `mapstructure:"root_volume_type" required:"false" cty:"root_volume_type" hcl:"root_volume_type"`
+	RootVolumeSize       *int64                              `mapstructure:"root_volume_size" required:"false" cty:"root_volume_size" hcl:"root_volume_size"`
+	DevicePath           *string                             `mapstructure:"device_path" required:"false" cty:"device_path" hcl:"device_path"`
+	MountPath            *string                             `mapstructure:"mount_path" required:"false" cty:"mount_path" hcl:"mount_path"`
+	MountPartition       *string                             `mapstructure:"mount_partition" required:"false" cty:"mount_partition" hcl:"mount_partition"`
+	MountOptions         []string                            `mapstructure:"mount_options" required:"false" cty:"mount_options" hcl:"mount_options"`
+	ChrootMounts         [][]string                          `mapstructure:"chroot_mounts" required:"false" cty:"chroot_mounts" hcl:"chroot_mounts"`
+	CopyFiles            []string                            `mapstructure:"copy_files" required:"false" cty:"copy_files" hcl:"copy_files"`
+	CommandWrapper       *string                             `mapstructure:"command_wrapper" required:"false" cty:"command_wrapper" hcl:"command_wrapper"`
+	PreMountCommands     []string                            `mapstructure:"pre_mount_commands" required:"false" cty:"pre_mount_commands" hcl:"pre_mount_commands"`
+	PostMountCommands    []string                            `mapstructure:"post_mount_commands" required:"false" cty:"post_mount_commands" hcl:"post_mount_commands"`
+	DefaultTags          map[string]string                   `mapstructure:"default_tags" required:"false" cty:"default_tags" hcl:"default_tags"`
+	SkipRegionValidation *bool                               `mapstructure:"skip_region_validation" required:"false" cty:"skip_region_validation" hcl:"skip_region_validation"`
+}
+
+// FlatMapstructure returns a new FlatConfig.
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatConfig)
+}
+
+// HCL2Spec returns the hcl spec of a Config.
+// This spec is used by HCL to read the fields of Config.
+// The decoded values from this spec will then be applied to a FlatConfig.
+func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"packer_build_name":          &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":        &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":        &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":               &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":               &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":            &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":      &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables": &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"secret_id":                  &hcldec.AttrSpec{Name: "secret_id", Type: cty.String, Required: false},
+		"secret_key":                 &hcldec.AttrSpec{Name: "secret_key", Type: cty.String, Required: false},
+		"region":                     &hcldec.AttrSpec{Name: "region", Type: cty.String, Required: false},
+		"zone":                       &hcldec.AttrSpec{Name: "zone", Type: cty.String, Required: false},
+		"cvm_endpoint":               &hcldec.AttrSpec{Name: "cvm_endpoint", Type: cty.String, Required: false},
+		"vpc_endpoint":               &hcldec.AttrSpec{Name: "vpc_endpoint", Type: cty.String, Required: false},
+		"endpoints":                  &hcldec.AttrSpec{Name: "endpoints", Type: cty.Map(cty.String), Required: false},
+		"use_internal_endpoint":      &hcldec.AttrSpec{Name: "use_internal_endpoint", Type: cty.Bool, Required: false},
+		"http_proxy":                 &hcldec.AttrSpec{Name: "http_proxy", Type: cty.String, Required: false},
+		"ca_cert_file":               &hcldec.AttrSpec{Name: "ca_cert_file", Type: cty.String, Required: false},
+		"request_timeout":            &hcldec.AttrSpec{Name: "request_timeout", Type: cty.String, Required: false},
+		"api_retry":                  &hcldec.BlockSpec{TypeName: "api_retry", Nested: hcldec.ObjectSpec((*cvm.FlatTencentCloudApiRetryConfig)(nil).HCL2Spec())},
+		"api_trace_file":             &hcldec.AttrSpec{Name: "api_trace_file", Type: cty.String, Required: false},
+		"image_name":                 &hcldec.AttrSpec{Name: "image_name", Type: cty.String, Required: false},
+		"image_description":          &hcldec.AttrSpec{Name: "image_description", Type: cty.String, Required: false},
+		"reboot":                     &hcldec.AttrSpec{Name: "reboot", Type: cty.Bool, Required: false},
+		"shutdown_behavior":          &hcldec.AttrSpec{Name: "shutdown_behavior", Type: cty.String, Required: false},
+		"shutdown_command":           &hcldec.AttrSpec{Name: "shutdown_command", Type: cty.String, Required: false},
+		"shutdown_timeout":           &hcldec.AttrSpec{Name: "shutdown_timeout", Type: cty.String, Required: false},
+		"stop_type":                  &hcldec.AttrSpec{Name: "stop_type", Type: cty.String, Required: false},
+		"force_poweroff":             &hcldec.AttrSpec{Name: "force_poweroff", Type: cty.Bool, Required: false},
+		"sysprep":                    &hcldec.AttrSpec{Name: "sysprep", Type: cty.Bool, Required: false},
+		"image_force_delete":         &hcldec.AttrSpec{Name: "image_force_delete", Type: cty.Bool, Required: false},
+		"image_copy_regions":         &hcldec.AttrSpec{Name: "image_copy_regions", Type: cty.List(cty.String), Required: false},
+		"image_copy_encrypted":       &hcldec.AttrSpec{Name: "image_copy_encrypted", Type: cty.Bool, Required: false},
+		"image_copy_kms_key_ids":     &hcldec.AttrSpec{Name: "image_copy_kms_key_ids", Type: cty.Map(cty.String), Required: false},
+		"image_share_accounts":       &hcldec.AttrSpec{Name: "image_share_accounts", Type: cty.List(cty.String), Required: false},
+		"image_tags":                 &hcldec.AttrSpec{Name: "image_tags", Type: cty.Map(cty.String), Required: false},
+		"skip_if_exists":             &hcldec.AttrSpec{Name: "skip_if_exists", Type: cty.Bool, Required: false},
+		"source_image_id":            &hcldec.AttrSpec{Name: "source_image_id", Type: cty.String, Required: false},
+		"root_volume_type":           &hcldec.AttrSpec{Name: "root_volume_type", Type: cty.String, Required: false},
+		"root_volume_size":           &hcldec.AttrSpec{Name: "root_volume_size", Type: cty.Number, Required: false},
+		"device_path":                &hcldec.AttrSpec{Name: "device_path", Type: cty.String, Required: false},
+		"mount_path":                 &hcldec.AttrSpec{Name: "mount_path", Type: cty.String, Required: false},
+		"mount_partition":            &hcldec.AttrSpec{Name: "mount_partition", Type: cty.String, Required: false},
+		"mount_options":              &hcldec.AttrSpec{Name: "mount_options", Type: cty.List(cty.String), Required: false},
+		"chroot_mounts":              &hcldec.AttrSpec{Name: "chroot_mounts", Type: cty.List(cty.List(cty.String)), Required: false},
+		"copy_files":                 &hcldec.AttrSpec{Name: "copy_files", Type: cty.List(cty.String), Required: false},
+		"command_wrapper":            &hcldec.AttrSpec{Name: "command_wrapper", Type: cty.String, Required: false},
+		"pre_mount_commands":         &hcldec.AttrSpec{Name: "pre_mount_commands", Type: cty.List(cty.String), Required: false},
+		"post_mount_commands":        &hcldec.AttrSpec{Name: "post_mount_commands", Type: cty.List(cty.String), Required: false},
+		"default_tags":               &hcldec.AttrSpec{Name: "default_tags", Type: cty.Map(cty.String), Required: false},
+		"skip_region_validation":     &hcldec.AttrSpec{Name: "skip_region_validation", Type: cty.Bool, Required: false},
+	}
+	return s
+}
@@ -0,0 +1,206 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package chroot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/mockapi"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"secret_id":              "secret-id",
+		"secret_key":             "secret-key",
+		"region":                 "ap-guangzhou",
+		"image_name":             "packer-test",
+		"source_image_id":        "img-source01",
+		"skip_region_validation": true,
+	}
+}
+
+func TestBuilder_Prepare(t *testing.T) {
+	var b Builder
+	if _, _, err := b.Prepare(testConfig()); err != nil {
+		t.Fatalf("prepare: %s", err)
+	}
+
+	c := b.config
+	if c.RootVolumeType != "CLOUD_PREMIUM" || c.MountPartition != "1" || c.CommandWrapper != "{{.Command}}" {
+		t.Errorf("unexpected defaults: root_volume_type %q, mount_partition %q, command_wrapper %q",
+			c.RootVolumeType, c.MountPartition, c.CommandWrapper)
+	}
+	if c.MountPath != "/mnt/packer-tencentcloud-chroot-volumes/{{.Device}}" {
+		t.Errorf("mount_path should be rendered when mounting, got %q", c.MountPath)
+	}
+	if len(c.ChrootMounts) != 5 {
+		t.Errorf("expected default chroot_mounts, got %v", c.ChrootMounts)
+	}
+	if !reflect.DeepEqual(c.CopyFiles, []string{"/etc/resolv.conf"}) {
+		t.Errorf("expected default copy_files, got %v", c.CopyFiles)
+	}
+}
+
+func TestBuilder_Prepare_errors(t *testing.T) {
+	mockapi.CheckPrepareErrors(t, func() packersdk.Builder { return &Builder{} }, testConfig(), map[string]mockapi.PrepareError{
+		"source image":  {Key: "source_image_id", Err: "source_image_id must be specified"},
+		"volume size":   {Key: "root_volume_size", Value: -1, Err: "root_volume_size should not be negative"},
+		"chroot mounts": {Key: "chroot_mounts", Value: [][]string{{"proc", "/proc"}}, Err: "should have three elements"},
+		"image name":    {Key: "image_name", Err: "image_name must be specified"},
+	})
+}
+
+// provisionHook runs command in chroot as a shell provisioner does
+type provisionHook struct {
+	command string
+}
+
+func (h *provisionHook) Run(ctx context.Context, name string, ui packersdk.Ui, comm packersdk.Communicator, data interface{}) error {
+	if name != packersdk.HookProvision {
+		return nil
+	}
+	cmd := &packersdk.RemoteCmd{Command: h.command}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+		return err
+	}
+	if status := cmd.ExitStatus(); status != 0 {
+		return fmt.Errorf("command exited with %d", status)
+	}
+	return nil
+}
+
+// runBuild runs a build of the mock api server on the cvm ins-host01, the
+// loop device stands for the attached disk.
+func runBuild(t *testing.T, server *mockapi.Server, device, mountDir string) (packersdk.Artifact, error) {
+	hook := &provisionHook{command: "echo provisioned > /etc/packer-chroot"}
+	return server.Build(t, &Builder{}, hook, map[string]interface{}{
+		"image_name":         "packer-test",
+		"source_image_id":    "img-source01",
+		"image_copy_regions": []string{"ap-guangzhou", "ap-shanghai"},
+		"device_path":        device,
+		"mount_partition":    "0",
+		"mount_path":         filepath.Join(mountDir, "{{.Device}}"),
+		"chroot_mounts":      [][]string{{"proc", "proc", "/proc"}},
+	})
+}
+
+func TestBuilder_Run(t *testing.T) {
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ins-host01")
+	}))
+	defer metadata.Close()
+	defer func(url string) { metadataInstanceIdURL = url }(metadataInstanceIdURL)
+	metadataInstanceIdURL = metadata.URL
+
+	cases := []struct {
+		name   string
+		faults []mockapi.Fault
+		err    string
+	}{
+		{
+			name: "success",
+		},
+		{
+			name:   "tag failed",
+			faults: []mockapi.Fault{{Action: "TagResources", Code: "AuthFailure.UnauthorizedOperation"}},
+		},
+		{
+			name:   "register image failed",
+			faults: []mockapi.Fault{{Action: "CreateImage", Code: mockapi.CodeQuota}},
+			err:    mockapi.CodeQuota,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			device := loopDevice(t)
+			mountDir := t.TempDir()
+			mountPath := filepath.Join(mountDir, filepath.Base(device))
+
+			server := mockapi.NewServer("AKIDmock", "mock-secret")
+			defer server.Close()
+			server.AddImage("ap-guangzhou", "img-source01", "TencentOS Server 3.1")
+			server.AddInstance("ap-guangzhou", "ap-guangzhou-3", "ins-host01")
+			for _, f := range tc.faults {
+				server.Inject(f)
+			}
+
+			artifact, err := runBuild(t, server, device, mountDir)
+
+			if isMounted(t, mountPath) || isMounted(t, filepath.Join(mountPath, "proc")) {
+				t.Errorf("device should be unmounted from %s", mountPath)
+			}
+			if n := len(server.Disks("ap-guangzhou")); n != 0 {
+				t.Errorf("expected disk terminated, got %d disks", n)
+			}
+
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error %q, got %v", tc.err, err)
+				}
+				if n := len(server.Snapshots("ap-guangzhou")); n != 0 {
+					t.Errorf("expected snapshot deleted, got %d snapshots", n)
+				}
+				if n := len(server.Images("ap-guangzhou")); n != 1 {
+					t.Errorf("expected only source image left, got %d images", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+			// the disk is detached after unmounting, and kept until the image
+			// is registered from its snapshot
+			order := "AttachDisks,DetachDisks,CreateSnapshot,CreateImage,TerminateDisks"
+			if actions := server.ActionsOf(strings.Split(order, ",")...); actions != order {
+				t.Errorf("expected actions in order %s, got %s", order, actions)
+			}
+
+			images := artifact.(*cvm.Artifact).TencentCloudImages
+			snapshots := server.Snapshots("ap-guangzhou")
+			if len(snapshots) != 1 {
+				t.Fatalf("expected snapshot of disk kept with image, got %d snapshots", len(snapshots))
+			}
+			for _, region := range []string{"ap-guangzhou", "ap-shanghai"} {
+				found := false
+				for _, img := range server.Images(region) {
+					if *img.ImageId == images[region] && *img.ImageState == "NORMAL" {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("expected image %q of artifact in %s", images[region], region)
+				}
+			}
+			for _, img := range server.Images("ap-guangzhou") {
+				if *img.ImageId == images["ap-guangzhou"] && *img.SnapshotSet[0].SnapshotId != snapshots[0].SnapshotId {
+					t.Errorf("expected image registered from snapshot %s", snapshots[0].SnapshotId)
+				}
+			}
+
+			// the provisioned files are left on the device, the copied files
+			// are removed
+			root := t.TempDir()
+			runCommand(t, "mount", device, root)
+			defer runCommand(t, "umount", root)
+			if data, err := os.ReadFile(filepath.Join(root, "etc", "packer-chroot")); err != nil || string(data) != "provisioned\n" {
+				t.Errorf("expected device provisioned in chroot, got %q: %v", data, err)
+			}
+			if _, err := os.Stat(filepath.Join(root, "etc", "resolv.conf")); !os.IsNotExist(err) {
+				t.Errorf("expected copied files removed, got %v", err)
+			}
+		})
+	}
+}
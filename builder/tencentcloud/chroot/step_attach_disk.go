@@ -0,0 +1,116 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package chroot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	cvmapi "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// deviceTimeout is the timeout waiting for the device of an attached disk to
+// show up
+const deviceTimeout = 2 * time.Minute
+
+// stepAttachDisk attaches the disk to the cvm packer runs on, and waits for
+// its device.
+//
+// Produces:
+//
+//	device string - The path of the device of the disk
+//	attach_cleanup CleanupFunc - A function to detach the disk early
+type stepAttachDisk struct {
+	DevicePath string
+	attached   bool
+}
+
+func (s *stepAttachDisk) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("common_client").(cvm.APISender)
+	instance := state.Get("instance").(*cvmapi.Instance)
+	diskId := state.Get("disk_id").(string)
+
+	cvm.Say(state, fmt.Sprintf("%s to %s", diskId, *instance.InstanceId), "Trying to attach disk")
+
+	if err := cvm.AttachDisk(ctx, client, diskId, *instance.InstanceId); err != nil {
+		return cvm.Halt(state, err, "Failed to attach disk")
+	}
+	s.attached = true
+
+	if _, err := cvm.WaitForDisk(ctx, client, diskId, "ATTACHED", 1800); err != nil {
+		return cvm.Halt(state, err, "Failed to wait for disk attached")
+	}
+
+	devicePath := s.DevicePath
+	if devicePath == "" {
+		devicePath = fmt.Sprintf("/dev/disk/by-id/virtio-%s", diskId)
+	}
+	device, err := waitForDevice(ctx, devicePath, deviceTimeout)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to find device of disk")
+	}
+
+	state.Put("device", device)
+	state.Put("attach_cleanup", s)
+	cvm.Message(state, device, "Disk attached")
+
+	return multistep.ActionContinue
+}
+
+func (s *stepAttachDisk) Cleanup(state multistep.StateBag) {
+	if err := s.CleanupFunc(state); err != nil {
+		cvm.Leak(state, err, "disk", state.Get("disk_id").(string))
+	}
+}
+
+// CleanupFunc detaches the disk, it is called early before creating snapshot
+func (s *stepAttachDisk) CleanupFunc(state multistep.StateBag) error {
+	if !s.attached {
+		return nil
+	}
+
+	ctx := context.TODO()
+	client := state.Get("common_client").(cvm.APISender)
+	diskId := state.Get("disk_id").(string)
+
+	cvm.Say(state, diskId, "Detaching disk")
+	if err := cvm.DetachDisk(ctx, client, diskId); err != nil {
+		return fmt.Errorf("Failed to detach disk: %s", err)
+	}
+	if _, err := cvm.WaitForDisk(ctx, client, diskId, "UNATTACHED", 1800); err != nil {
+		return fmt.Errorf("Failed to wait for disk detached: %s", err)
+	}
+
+	s.attached = false
+	return nil
+}
+
+// waitForDevice waits for the device to show up, and returns its real path
+// as the symbolic links created by udev can not be used to find partitions.
+func waitForDevice(ctx context.Context, path string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return filepath.EvalSymlinks(path)
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("device %s not found in %s", path, timeout)
+		}
+		log.Printf("[DEBUG] Waiting for device %s", path)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
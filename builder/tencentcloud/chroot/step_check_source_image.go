@@ -0,0 +1,66 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package chroot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	cvmapi "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// stepCheckSourceImage finds the snapshot of the system disk of the source
+// image, which the disk is created from.
+type stepCheckSourceImage struct {
+	SourceImageId string
+}
+
+func (s *stepCheckSourceImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("cvm_client").(cvm.CVMAPI)
+
+	cvm.Say(state, s.SourceImageId, "Trying to check source image")
+
+	req := cvmapi.NewDescribeImagesRequest()
+	req.ImageIds = []*string{&s.SourceImageId}
+	var resp *cvmapi.DescribeImagesResponse
+	err := cvm.Retry(ctx, func(ctx context.Context) error {
+		var e error
+		resp, e = client.DescribeImagesWithContext(ctx, req)
+		return e
+	})
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to get source image info")
+	}
+	if len(resp.Response.ImageSet) == 0 {
+		return cvm.Halt(state, fmt.Errorf("No image found"), "")
+	}
+
+	image := resp.Response.ImageSet[0]
+	snapshot := systemDiskSnapshot(image)
+	if snapshot == nil {
+		return cvm.Halt(state, fmt.Errorf("image(%s) has no snapshot of system disk", s.SourceImageId), "")
+	}
+
+	state.Put("source_image", image)
+	state.Put("source_snapshot", snapshot)
+	cvm.Message(state, fmt.Sprintf("%s, system disk snapshot %s", *image.ImageName, *snapshot.SnapshotId), "Image found")
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCheckSourceImage) Cleanup(state multistep.StateBag) {}
+
+// systemDiskSnapshot returns the snapshot of the system disk of image, or
+// nil if there is none
+func systemDiskSnapshot(image *cvmapi.Image) *cvmapi.Snapshot {
+	for _, snapshot := range image.SnapshotSet {
+		if snapshot.DiskUsage != nil && *snapshot.DiskUsage == "SYSTEM_DISK" && snapshot.SnapshotId != nil {
+			return snapshot
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package chroot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	cvmapi "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// stepCreateDisk creates a disk from the system disk snapshot of the source
+// image, in the zone of the cvm packer runs on.
+type stepCreateDisk struct {
+	DiskType string
+	DiskSize int64
+	diskId   string
+}
+
+func (s *stepCreateDisk) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("common_client").(cvm.APISender)
+	config := state.Get("config").(*Config)
+	instance := state.Get("instance").(*cvmapi.Instance)
+	snapshot := state.Get("source_snapshot").(*cvmapi.Snapshot)
+
+	if s.DiskSize != 0 && snapshot.DiskSize != nil && s.DiskSize < *snapshot.DiskSize {
+		return cvm.Halt(state, fmt.Errorf("root_volume_size(%d) is smaller than the system disk(%d) of source image",
+			s.DiskSize, *snapshot.DiskSize), "")
+	}
+
+	cvm.Say(state, *snapshot.SnapshotId, "Trying to create a disk from snapshot")
+
+	diskId, err := cvm.CreateDisk(ctx, client, &cvm.CbsDiskOptions{
+		Zone:       *instance.Placement.Zone,
+		DiskType:   s.DiskType,
+		DiskSize:   s.DiskSize,
+		DiskName:   fmt.Sprintf("packer-chroot-%s", config.ImageName),
+		SnapshotId: *snapshot.SnapshotId,
+	})
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to create disk")
+	}
+	s.diskId = diskId
+
+	if _, err := cvm.WaitForDisk(ctx, client, diskId, "UNATTACHED", 1800); err != nil {
+		return cvm.Halt(state, err, "Failed to wait for disk ready")
+	}
+
+	tags, err := cvm.ResourceTags(state, nil)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to get tags")
+	}
+	// tagging for cost allocation doesn't fail the build
+	if err := cvm.TagCvmResources(ctx, state, config.Region, "volume", []string{diskId}, tags); err != nil {
+		cvm.Message(state, fmt.Sprintf("%s, skip tagging disk", err), "Failed to tag disk")
+	}
+
+	state.Put("disk_id", diskId)
+	cvm.Message(state, diskId, "Disk created")
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateDisk) Cleanup(state multistep.StateBag) {
+	if s.diskId == "" {
+		return
+	}
+
+	// the disk failed to be detached by stepAttachDisk is reported as leaked
+	// already, it can't be terminated
+	if v, ok := state.GetOk("attach_cleanup"); ok && v.(*stepAttachDisk).attached {
+		return
+	}
+
+	ctx := context.TODO()
+	client := state.Get("common_client").(cvm.APISender)
+
+	cvm.SayClean(state, "disk")
+
+	if err := cvm.TerminateDisk(ctx, client, s.diskId); err != nil {
+		cvm.Leak(state, err, "disk", s.diskId)
+	}
+}
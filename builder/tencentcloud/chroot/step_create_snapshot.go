@@ -0,0 +1,76 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package chroot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// stepCreateSnapshot creates a snapshot of the detached disk, which the
+// image is registered from.
+type stepCreateSnapshot struct {
+	snapshotId string
+}
+
+func (s *stepCreateSnapshot) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("common_client").(cvm.APISender)
+	config := state.Get("config").(*Config)
+	diskId := state.Get("disk_id").(string)
+
+	cvm.Say(state, diskId, "Trying to create a snapshot of disk")
+
+	snapshotId, err := cvm.CreateSnapshot(ctx, client, diskId, config.ImageName)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to create snapshot")
+	}
+	s.snapshotId = snapshotId
+
+	cvm.Message(state, "Waiting for snapshot ready", "")
+	if _, err := cvm.WaitForSnapshot(ctx, client, snapshotId, "NORMAL", 3600); err != nil {
+		return cvm.Halt(state, err, "Failed to wait for snapshot ready")
+	}
+
+	// the snapshot is kept with the image, tagged as the image snapshots
+	// created by other builders
+	tags, err := cvm.ResourceTags(state, config.ImageTags)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to get tags")
+	}
+	// tagging for cost allocation doesn't fail the build
+	if err := cvm.TagCvmResources(ctx, state, config.Region, "snapshot", []string{snapshotId}, tags); err != nil {
+		cvm.Message(state, fmt.Sprintf("%s, skip tagging snapshot", err), "Failed to tag snapshot")
+	}
+
+	state.Put("snapshot_id", snapshotId)
+	cvm.Message(state, snapshotId, "Snapshot created")
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateSnapshot) Cleanup(state multistep.StateBag) {
+	if s.snapshotId == "" {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	ctx := context.TODO()
+	client := state.Get("common_client").(cvm.APISender)
+
+	cvm.SayClean(state, "snapshot")
+
+	// the image registered from the snapshot is deleted before
+	if err := cvm.DeleteSnapshot(ctx, client, s.snapshotId); err != nil {
+		cvm.Leak(state, err, "snapshot", s.snapshotId)
+	}
+}
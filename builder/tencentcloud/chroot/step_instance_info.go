@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package chroot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	cvmapi "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// metadataInstanceIdURL is the url of the metadata service returning the id
+// of the cvm packer runs on
+var metadataInstanceIdURL = "http://metadata.tencentyun.com/latest/meta-data/instance-id"
+
+// stepInstanceInfo gets the cvm packer runs on, the disk is created in its
+// zone and attached to it.
+type stepInstanceInfo struct{}
+
+func (s *stepInstanceInfo) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("cvm_client").(cvm.CVMAPI)
+
+	cvm.Say(state, "the cvm packer runs on", "Trying to get")
+
+	instanceId, err := localInstanceId(ctx)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to get instance id from metadata, packer must run on a cvm")
+	}
+
+	req := cvmapi.NewDescribeInstancesRequest()
+	req.InstanceIds = []*string{&instanceId}
+	var resp *cvmapi.DescribeInstancesResponse
+	err = cvm.Retry(ctx, func(ctx context.Context) error {
+		var e error
+		resp, e = client.DescribeInstancesWithContext(ctx, req)
+		return e
+	})
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to describe instance")
+	}
+	if len(resp.Response.InstanceSet) == 0 {
+		return cvm.Halt(state, fmt.Errorf("instance(%s) not found in region", instanceId), "")
+	}
+
+	instance := resp.Response.InstanceSet[0]
+	state.Put("instance", instance)
+	cvm.Message(state, fmt.Sprintf("%s in %s", instanceId, *instance.Placement.Zone), "Instance found")
+
+	return multistep.ActionContinue
+}
+
+func (s *stepInstanceInfo) Cleanup(state multistep.StateBag) {}
+
+// localInstanceId returns the id of the cvm packer runs on from metadata
+func localInstanceId(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataInstanceIdURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d of %s", resp.StatusCode, metadataInstanceIdURL)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
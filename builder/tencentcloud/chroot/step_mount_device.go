@@ -0,0 +1,134 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package chroot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+type mountPathData struct {
+	Device string
+}
+
+// stepMountDevice mounts the partition of the device to the mount path.
+//
+// Produces:
+//
+//	mount_path string - The path where the device is mounted
+//	mount_device_cleanup CleanupFunc - A function to unmount the device early
+type stepMountDevice struct {
+	MountPath      string
+	MountOptions   []string
+	MountPartition string
+	mountPath      string
+}
+
+func (s *stepMountDevice) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	device := state.Get("device").(string)
+	wrappedCommand := state.Get("wrappedCommand").(common.CommandWrapper)
+
+	ictx := config.GetContext()
+	ictx.Data = &mountPathData{Device: filepath.Base(device)}
+	mountPath, err := interpolate.Render(s.MountPath, &ictx)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to render mount path")
+	}
+	mountPath, err = filepath.Abs(mountPath)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to render mount path")
+	}
+
+	if err := os.MkdirAll(mountPath, 0755); err != nil {
+		return cvm.Halt(state, err, "Failed to create mount path")
+	}
+
+	partition := partitionDevice(device, s.MountPartition)
+	// the partitions show up after the partition table is read by kernel
+	if partition, err = waitForDevice(ctx, partition, deviceTimeout); err != nil {
+		return cvm.Halt(state, err, "Failed to find partition of device")
+	}
+
+	cvm.Say(state, fmt.Sprintf("%s to %s", partition, mountPath), "Mounting device")
+
+	var opts string
+	if len(s.MountOptions) > 0 {
+		opts = "-o " + strings.Join(s.MountOptions, " -o ")
+	}
+	mountCommand, err := wrappedCommand(fmt.Sprintf("mount %s %s %s", opts, partition, mountPath))
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to build mount command")
+	}
+	log.Printf("[DEBUG] Mount command: %s", mountCommand)
+
+	stderr := new(bytes.Buffer)
+	cmd := common.ShellCommand(mountCommand)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return cvm.Halt(state, fmt.Errorf("%s, stderr: %s", err, stderr.String()), "Failed to mount device")
+	}
+
+	s.mountPath = mountPath
+	state.Put("mount_path", mountPath)
+	state.Put("mount_device_cleanup", s)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepMountDevice) Cleanup(state multistep.StateBag) {
+	if err := s.CleanupFunc(state); err != nil {
+		cvm.Leak(state, err, "mount", s.mountPath)
+	}
+}
+
+// CleanupFunc unmounts the device, it is called early before detaching disk
+func (s *stepMountDevice) CleanupFunc(state multistep.StateBag) error {
+	if s.mountPath == "" {
+		return nil
+	}
+
+	wrappedCommand := state.Get("wrappedCommand").(common.CommandWrapper)
+
+	cvm.Say(state, s.mountPath, "Unmounting device")
+	unmountCommand, err := wrappedCommand(fmt.Sprintf("umount %s", s.mountPath))
+	if err != nil {
+		return fmt.Errorf("Failed to build unmount command: %s", err)
+	}
+
+	stderr := new(bytes.Buffer)
+	cmd := common.ShellCommand(unmountCommand)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Failed to unmount device: %s, stderr: %s", err, stderr.String())
+	}
+
+	s.mountPath = ""
+	return nil
+}
+
+// partitionDevice returns the device of partition, partition 0 is the whole
+// device. A "p" is inserted before the partition number if the device ends
+// with a digit, such as /dev/loop0p1 and /dev/nvme0n1p1.
+func partitionDevice(device, partition string) string {
+	if partition == "" || partition == "0" {
+		return device
+	}
+	if r := rune(device[len(device)-1]); unicode.IsDigit(r) {
+		return device + "p" + partition
+	}
+	return device + partition
+}
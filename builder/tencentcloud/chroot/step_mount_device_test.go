@@ -0,0 +1,179 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package chroot
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/chroot"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func runCommand(t *testing.T, name string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s %s: %s, output: %s", name, strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// loopDevice returns a loop device of an ext4 filesystem, which has /bin/sh
+// and the libraries it needs installed to run commands in chroot. The test
+// is skipped unless it is run by root on Linux with loop devices available.
+func loopDevice(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS != "linux" || os.Geteuid() != 0 {
+		t.Skip("loop devices require root on Linux")
+	}
+	for _, tool := range []string{"losetup", "mkfs.ext4", "mount", "umount", "chroot", "ldd"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not found", tool)
+		}
+	}
+
+	image := filepath.Join(t.TempDir(), "disk.img")
+	f, err := os.Create(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(64 << 20); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	runCommand(t, "mkfs.ext4", "-q", "-F", image)
+
+	out, err := exec.Command("losetup", "--find", "--show", image).CombinedOutput()
+	if err != nil {
+		t.Skipf("no loop device available: %s", out)
+	}
+	device := strings.TrimSpace(string(out))
+	t.Cleanup(func() {
+		exec.Command("losetup", "--detach", device).Run()
+	})
+
+	root := t.TempDir()
+	runCommand(t, "mount", device, root)
+	defer runCommand(t, "umount", root)
+
+	files := []string{"/bin/sh"}
+	for _, field := range strings.Fields(runCommand(t, "ldd", "/bin/sh")) {
+		if strings.HasPrefix(field, "/") {
+			files = append(files, field)
+		}
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		target := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(target, data, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "etc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return device
+}
+
+// isMounted returns whether path is a mount point
+func isMounted(t *testing.T, path string) bool {
+	t.Helper()
+	mounts, err := os.ReadFile("/proc/mounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(mounts), "\n") {
+		if fields := strings.Fields(line); len(fields) > 1 && fields[1] == path {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPartitionDevice(t *testing.T) {
+	cases := []struct {
+		device    string
+		partition string
+		expected  string
+	}{
+		{"/dev/vdb", "1", "/dev/vdb1"},
+		{"/dev/vdb", "0", "/dev/vdb"},
+		{"/dev/vdb", "", "/dev/vdb"},
+		{"/dev/loop3", "2", "/dev/loop3p2"},
+		{"/dev/nvme1n1", "1", "/dev/nvme1n1p1"},
+	}
+	for _, tc := range cases {
+		if got := partitionDevice(tc.device, tc.partition); got != tc.expected {
+			t.Errorf("partition %q of %s: expected %s, got %s", tc.partition, tc.device, tc.expected, got)
+		}
+	}
+}
+
+func TestStepMountDevice(t *testing.T) {
+	device := loopDevice(t)
+
+	wrappedCommand := common.CommandWrapper(func(command string) (string, error) {
+		return command, nil
+	})
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &Config{})
+	state.Put("device", device)
+	state.Put("wrappedCommand", wrappedCommand)
+	state.Put("ui", &packersdk.BasicUi{Reader: new(bytes.Buffer), Writer: new(bytes.Buffer)})
+
+	dir := t.TempDir()
+	step := &stepMountDevice{
+		MountPath:      filepath.Join(dir, "{{.Device}}"),
+		MountOptions:   []string{"noatime"},
+		MountPartition: "0",
+	}
+	defer step.Cleanup(state)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("failed to mount device: %v", state.Get("error"))
+	}
+
+	mountPath := state.Get("mount_path").(string)
+	if expected := filepath.Join(dir, filepath.Base(device)); mountPath != expected {
+		t.Fatalf("expected mount path %s, got %s", expected, mountPath)
+	}
+	if !isMounted(t, mountPath) {
+		t.Fatalf("device should be mounted to %s", mountPath)
+	}
+
+	comm := &chroot.Communicator{Chroot: mountPath, CmdWrapper: wrappedCommand}
+	cmd := &packersdk.RemoteCmd{Command: "echo provisioned > /etc/packer-chroot"}
+	if err := comm.Start(context.Background(), cmd); err != nil {
+		t.Fatal(err)
+	}
+	if status := cmd.Wait(); status != 0 {
+		t.Fatalf("command in chroot exited with %d", status)
+	}
+	data, err := os.ReadFile(filepath.Join(mountPath, "etc", "packer-chroot"))
+	if err != nil || string(data) != "provisioned\n" {
+		t.Fatalf("command should write the file in chroot, got %q: %v", data, err)
+	}
+
+	if err := step.CleanupFunc(state); err != nil {
+		t.Fatalf("failed to unmount device: %s", err)
+	}
+	if isMounted(t, mountPath) {
+		t.Fatalf("device should be unmounted from %s", mountPath)
+	}
+}
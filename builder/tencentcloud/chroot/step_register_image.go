@@ -0,0 +1,103 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package chroot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvmapi "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// stepRegisterImage creates the image from the snapshot of the disk.
+type stepRegisterImage struct {
+	imageId string
+}
+
+func (s *stepRegisterImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("cvm_client").(cvm.CVMAPI)
+	config := state.Get("config").(*Config)
+	snapshotId := state.Get("snapshot_id").(string)
+
+	cvm.Say(state, config.ImageName, "Trying to register a new image")
+
+	tags, err := cvm.ResourceTags(state, config.ImageTags)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to get tags")
+	}
+
+	req := cvmapi.NewCreateImageRequest()
+	req.ImageName = &config.ImageName
+	req.ImageDescription = &config.ImageDescription
+	req.SnapshotIds = []*string{&snapshotId}
+	req.TagSpecification = []*cvmapi.TagSpecification{
+		{
+			ResourceType: common.StringPtr("image"),
+			Tags:         cvm.CvmTags(tags),
+		},
+	}
+
+	err = cvm.Retry(ctx, func(ctx context.Context) error {
+		_, e := client.CreateImageWithContext(ctx, req)
+		return e
+	})
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to register image")
+	}
+
+	cvm.Message(state, "Waiting for image ready", "")
+	err = cvm.WaitForImageReady(ctx, client, config.ImageName, "NORMAL", 3600)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to wait for image ready")
+	}
+
+	image, err := cvm.GetImageByName(ctx, client, config.ImageName)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to get image")
+	}
+	if image == nil {
+		return cvm.Halt(state, fmt.Errorf("No image return"), "Failed to register image")
+	}
+
+	s.imageId = *image.ImageId
+	state.Put("image", image)
+	cvm.Message(state, s.imageId, "Image created")
+
+	tencentCloudImages := make(map[string]string)
+	tencentCloudImages[config.Region] = s.imageId
+	state.Put("tencentcloudimages", tencentCloudImages)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepRegisterImage) Cleanup(state multistep.StateBag) {
+	if s.imageId == "" {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	ctx := context.TODO()
+	client := state.Get("cvm_client").(cvm.CVMAPI)
+
+	cvm.SayClean(state, "image")
+
+	req := cvmapi.NewDeleteImagesRequest()
+	req.ImageIds = []*string{&s.imageId}
+	err := cvm.Retry(ctx, func(ctx context.Context) error {
+		_, e := client.DeleteImagesWithContext(ctx, req)
+		return e
+	})
+	if err != nil {
+		cvm.Leak(state, err, "image", s.imageId)
+	}
+}
@@ -8,7 +8,10 @@ package cvm
 import (
 	"context"
 	"fmt"
+	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
@@ -43,6 +46,8 @@ const (
 	SaoPaulo      = Region("sa-saopaulo")
 )
 
+// ValidRegions is the builtin region list, it is used when DescribeRegions
+// is unreachable and there is no region cache, see LoadRegions.
 var ValidRegions = []Region{
 	Bangkok, Beijing, Chengdu, Chongqing, Guangzhou, GuangzhouOpen, Hongkong, Jakarta, Shanghai, Nanjing,
 	ShanghaiFsi, ShenzhenFsi,
@@ -70,8 +75,35 @@ type TencentCloudAccessConfig struct {
 	CvmEndpoint string `mapstructure:"cvm_endpoint" required:"false"`
 	// The endpoint you want to reach the cloud endpoint,
 	// if tce cloud you should set a tce vpc endpoint.
-	VpcEndpoint    string `mapstructure:"vpc_endpoint" required:"false"`
+	VpcEndpoint string `mapstructure:"vpc_endpoint" required:"false"`
+	// The endpoints of the services called by the plugin, keyed by service,
+	// which are `cvm`, `vpc`, `cbs`, `lighthouse`, `tag` and `sts`. An
+	// endpoint is a host or an url such as `https://cbs.example.com`.
+	// `cvm_endpoint` and `vpc_endpoint` are shortcuts of the `cvm` and `vpc`
+	// keys.
+	Endpoints map[string]string `mapstructure:"endpoints" required:"false"`
+	// Call the apis through the private network endpoints
+	// `<service>.internal.tencentcloudapi.com`, which are reachable from the
+	// cvms without internet access. Defaults to `false`.
+	UseInternalEndpoint bool `mapstructure:"use_internal_endpoint" required:"false"`
+	// The proxy of the api requests, such as `http://proxy.example.com:3128`.
+	// Defaults to the proxy set by the `HTTPS_PROXY` environment variable.
+	HttpProxy string `mapstructure:"http_proxy" required:"false"`
+	// The PEM file of the CA certificates trusted besides the system ones,
+	// for the endpoints or the proxy with a private CA.
+	CACertFile string `mapstructure:"ca_cert_file" required:"false"`
+	// The timeout of an api request. Defaults to `300s`.
+	RequestTimeout time.Duration `mapstructure:"request_timeout" required:"false"`
+	// The retry and rate limit options of the api calls.
+	ApiRetry TencentCloudApiRetryConfig `mapstructure:"api_retry" required:"false"`
+	// The file the api calls are appended to, one json line per call with the
+	// action, region, latency, retries, request id and error code. The
+	// passwords, user data and keys in the parameters are redacted. The calls
+	// are logged at DEBUG level whether it is set or not.
+	ApiTraceFile   string `mapstructure:"api_trace_file" required:"false"`
 	skipValidation bool
+	// regions are the known regions, discovered once by knownRegions
+	regions []string
 }
 
 func (cf *TencentCloudAccessConfig) Client() (*cvm.Client, *vpc.Client, error) {
@@ -86,11 +118,11 @@ func (cf *TencentCloudAccessConfig) Client() (*cvm.Client, *vpc.Client, error) {
 		return nil, nil, err
 	}
 
-	if cvm_client, err = NewCvmClient(cf.SecretId, cf.SecretKey, cf.Region, cf.CvmEndpoint); err != nil {
+	if cvm_client, err = NewCvmClient(cf, cf.Region); err != nil {
 		return nil, nil, err
 	}
 
-	if vpc_client, err = NewVpcClient(cf.SecretId, cf.SecretKey, cf.Region, cf.VpcEndpoint); err != nil {
+	if vpc_client, err = NewVpcClient(cf, cf.Region); err != nil {
 		return nil, nil, err
 	}
 
@@ -101,20 +133,25 @@ func (cf *TencentCloudAccessConfig) Client() (*cvm.Client, *vpc.Client, error) {
 	ctx := context.TODO()
 	err = Retry(ctx, func(ctx context.Context) error {
 		var e error
-		resp, e = cvm_client.DescribeZones(nil)
+		resp, e = cvm_client.DescribeZonesWithContext(ctx, nil)
 		return e
 	})
 	if err != nil {
 		return nil, nil, err
 	}
 
+	zones := make([]string, 0, len(resp.Response.ZoneSet))
 	for _, zone := range resp.Response.ZoneSet {
 		if cf.Zone == *zone.Zone {
 			return cvm_client, vpc_client, nil
 		}
+		zones = append(zones, *zone.Zone)
 	}
 
-	return nil, nil, fmt.Errorf("unknown zone: %s", cf.Zone)
+	if suggestion := suggest(cf.Zone, zones); suggestion != "" {
+		return nil, nil, fmt.Errorf("unknown zone: %s, did you mean %s?", cf.Zone, suggestion)
+	}
+	return nil, nil, fmt.Errorf("unknown zone: %s, available zones in %s: %s", cf.Zone, cf.Region, strings.Join(zones, ", "))
 }
 
 func (cf *TencentCloudAccessConfig) Prepare(ctx *interpolate.Context) []error {
@@ -129,6 +166,32 @@ func (cf *TencentCloudAccessConfig) Prepare(ctx *interpolate.Context) []error {
 		errs = append(errs, fmt.Errorf("parameter cvm_endpoint and vpc_endpoint must be set simultaneously"))
 	}
 
+	for service, endpoint := range map[string]string{cvmService: cf.CvmEndpoint, vpcService: cf.VpcEndpoint} {
+		if endpoint != "" && cf.Endpoints[service] != "" && cf.Endpoints[service] != endpoint {
+			errs = append(errs, fmt.Errorf("parameter %s_endpoint conflicts with endpoints.%s", service, service))
+		}
+	}
+
+	if cf.HttpProxy != "" {
+		if _, err := parseProxy(cf.HttpProxy); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if cf.CACertFile != "" {
+		if _, err := loadCACerts(cf.CACertFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if cf.RequestTimeout < 0 {
+		errs = append(errs, fmt.Errorf("parameter request_timeout should not be negative"))
+	} else if cf.RequestTimeout == 0 {
+		cf.RequestTimeout = DefaultRequestTimeout
+	}
+
+	errs = append(errs, cf.ApiRetry.Prepare()...)
+
 	if cf.Region == "" {
 		errs = append(errs, fmt.Errorf("parameter region must be set"))
 	} else if !cf.skipValidation {
@@ -162,18 +225,31 @@ func (cf *TencentCloudAccessConfig) Config() error {
 
 func (cf *TencentCloudAccessConfig) validateRegion() error {
 	// if set cvm endpoint, do not validate region
-	if cf.CvmEndpoint != "" {
+	if cf.endpoint(cvmService) != "" {
 		return nil
 	}
-	return validRegion(cf.Region)
+	return validRegion(cf.Region, cf.knownRegions())
 }
 
-func validRegion(region string) error {
-	for _, valid := range ValidRegions {
-		if Region(region) == valid {
-			return nil
-		}
+// knownRegions returns the regions got by DescribeRegions, which are cached
+// on disk for RegionCacheTTL, see LoadRegions.
+func (cf *TencentCloudAccessConfig) knownRegions() []string {
+	if cf.regions != nil {
+		return cf.regions
+	}
+
+	cachePath, err := RegionCachePath()
+	if err != nil {
+		log.Printf("[WARN] Failed to get region cache path: %s", err)
+		cachePath = ""
+	}
+	// regions are described in any region, the configured one may be a typo
+	client, err := NewCvmClient(cf, string(Guangzhou))
+	if err != nil {
+		log.Printf("[WARN] Failed to create client to describe regions: %s", err)
+		return staticRegions()
 	}
+	cf.regions = LoadRegions(context.TODO(), client, cachePath)
 
-	return fmt.Errorf("unknown region: %s", region)
+	return cf.regions
 }
@@ -0,0 +1,78 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
+)
+
+// APISender sends a request built by hand, it is used to call the apis and
+// parameters not vendored, see SendWithExtraParams. *common.Client and the
+// service clients embedding it implement it.
+type APISender interface {
+	Send(request tchttp.Request, response tchttp.Response) error
+}
+
+// CVMAPI is the cvm api used by the builder, it is put in the state bag as
+// "cvm_client", and *cvm.Client implements it.
+type CVMAPI interface {
+	APISender
+
+	CreateImageWithContext(ctx context.Context, request *cvm.CreateImageRequest) (*cvm.CreateImageResponse, error)
+	DeleteImagesWithContext(ctx context.Context, request *cvm.DeleteImagesRequest) (*cvm.DeleteImagesResponse, error)
+	DeleteKeyPairsWithContext(ctx context.Context, request *cvm.DeleteKeyPairsRequest) (*cvm.DeleteKeyPairsResponse, error)
+	DescribeHostsWithContext(ctx context.Context, request *cvm.DescribeHostsRequest) (*cvm.DescribeHostsResponse, error)
+	DescribeImageSharePermissionWithContext(ctx context.Context, request *cvm.DescribeImageSharePermissionRequest) (*cvm.DescribeImageSharePermissionResponse, error)
+	DescribeImagesWithContext(ctx context.Context, request *cvm.DescribeImagesRequest) (*cvm.DescribeImagesResponse, error)
+	DescribeInstancesWithContext(ctx context.Context, request *cvm.DescribeInstancesRequest) (*cvm.DescribeInstancesResponse, error)
+	DescribeKeyPairsWithContext(ctx context.Context, request *cvm.DescribeKeyPairsRequest) (*cvm.DescribeKeyPairsResponse, error)
+	DescribeRegionsWithContext(ctx context.Context, request *cvm.DescribeRegionsRequest) (*cvm.DescribeRegionsResponse, error)
+	DescribeLaunchTemplateVersionsWithContext(ctx context.Context, request *cvm.DescribeLaunchTemplateVersionsRequest) (*cvm.DescribeLaunchTemplateVersionsResponse, error)
+	DisassociateInstancesKeyPairsWithContext(ctx context.Context, request *cvm.DisassociateInstancesKeyPairsRequest) (*cvm.DisassociateInstancesKeyPairsResponse, error)
+	ModifyImageSharePermissionWithContext(ctx context.Context, request *cvm.ModifyImageSharePermissionRequest) (*cvm.ModifyImageSharePermissionResponse, error)
+	RebootInstancesWithContext(ctx context.Context, request *cvm.RebootInstancesRequest) (*cvm.RebootInstancesResponse, error)
+	RunInstancesWithContext(ctx context.Context, request *cvm.RunInstancesRequest) (*cvm.RunInstancesResponse, error)
+	StartInstancesWithContext(ctx context.Context, request *cvm.StartInstancesRequest) (*cvm.StartInstancesResponse, error)
+	StopInstancesWithContext(ctx context.Context, request *cvm.StopInstancesRequest) (*cvm.StopInstancesResponse, error)
+	SyncImagesWithContext(ctx context.Context, request *cvm.SyncImagesRequest) (*cvm.SyncImagesResponse, error)
+	TerminateInstancesWithContext(ctx context.Context, request *cvm.TerminateInstancesRequest) (*cvm.TerminateInstancesResponse, error)
+}
+
+// VPCAPI is the vpc api used by the builder, it is put in the state bag as
+// "vpc_client", and *vpc.Client implements it.
+type VPCAPI interface {
+	APISender
+
+	AllocateAddressesWithContext(ctx context.Context, request *vpc.AllocateAddressesRequest) (*vpc.AllocateAddressesResponse, error)
+	AssignIpv6CidrBlockWithContext(ctx context.Context, request *vpc.AssignIpv6CidrBlockRequest) (*vpc.AssignIpv6CidrBlockResponse, error)
+	AssignIpv6SubnetCidrBlockWithContext(ctx context.Context, request *vpc.AssignIpv6SubnetCidrBlockRequest) (*vpc.AssignIpv6SubnetCidrBlockResponse, error)
+	AssociateAddressWithContext(ctx context.Context, request *vpc.AssociateAddressRequest) (*vpc.AssociateAddressResponse, error)
+	CreateSecurityGroupWithContext(ctx context.Context, request *vpc.CreateSecurityGroupRequest) (*vpc.CreateSecurityGroupResponse, error)
+	CreateSecurityGroupPoliciesWithContext(ctx context.Context, request *vpc.CreateSecurityGroupPoliciesRequest) (*vpc.CreateSecurityGroupPoliciesResponse, error)
+	CreateSubnetWithContext(ctx context.Context, request *vpc.CreateSubnetRequest) (*vpc.CreateSubnetResponse, error)
+	CreateVpcWithContext(ctx context.Context, request *vpc.CreateVpcRequest) (*vpc.CreateVpcResponse, error)
+	DeleteSecurityGroupWithContext(ctx context.Context, request *vpc.DeleteSecurityGroupRequest) (*vpc.DeleteSecurityGroupResponse, error)
+	DeleteSubnetWithContext(ctx context.Context, request *vpc.DeleteSubnetRequest) (*vpc.DeleteSubnetResponse, error)
+	DeleteVpcWithContext(ctx context.Context, request *vpc.DeleteVpcRequest) (*vpc.DeleteVpcResponse, error)
+	DescribeAddressesWithContext(ctx context.Context, request *vpc.DescribeAddressesRequest) (*vpc.DescribeAddressesResponse, error)
+	DescribeSecurityGroupsWithContext(ctx context.Context, request *vpc.DescribeSecurityGroupsRequest) (*vpc.DescribeSecurityGroupsResponse, error)
+	DescribeSubnetsWithContext(ctx context.Context, request *vpc.DescribeSubnetsRequest) (*vpc.DescribeSubnetsResponse, error)
+	DescribeVpcsWithContext(ctx context.Context, request *vpc.DescribeVpcsRequest) (*vpc.DescribeVpcsResponse, error)
+	DisassociateAddressWithContext(ctx context.Context, request *vpc.DisassociateAddressRequest) (*vpc.DisassociateAddressResponse, error)
+	ReleaseAddressesWithContext(ctx context.Context, request *vpc.ReleaseAddressesRequest) (*vpc.ReleaseAddressesResponse, error)
+}
+
+// RegionCVMClientFunc returns the cvm client of a region, it is put in the
+// state bag as "cvm_region_client" for the steps working across regions.
+type RegionCVMClientFunc func(region string) (CVMAPI, error)
+
+var (
+	_ CVMAPI    = (*cvm.Client)(nil)
+	_ VPCAPI    = (*vpc.Client)(nil)
+	_ APISender = (*cvm.Client)(nil)
+)
@@ -0,0 +1,201 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// builtinRetryableCodes are the error codes always retried, the codes end
+// with "*" match by substring.
+var builtinRetryableCodes = []string{
+	"ClientError.NetworkError",
+	"ClientError.HttpStatusCodeError",
+	"InvalidKeyPair.NotSupported",
+	"InvalidParameterValue.KeyPairNotSupported",
+	"InvalidInstance.NotSupported",
+	"OperationDenied.InstanceOperationInProgress",
+	"RequestLimitExceeded*",
+	"InternalError*",
+	"ResourceInUse*",
+	"ResourceBusy*",
+}
+
+// apiRetryPolicy is the retry config and rate limiter used by all the api
+// calls, Retry and the clients read it
+type apiRetryPolicy struct {
+	config  TencentCloudApiRetryConfig
+	limiter *rate.Limiter
+}
+
+var (
+	apiRetryMu sync.RWMutex
+	apiRetry   = newApiRetryPolicy(TencentCloudApiRetryConfig{})
+
+	// requestActions keeps the api action of requests failed with retryable
+	// codes by request id, so that the retries log which api is retried. Retry
+	// removes them whether the request is retried or not.
+	requestActions sync.Map
+)
+
+func newApiRetryPolicy(c TencentCloudApiRetryConfig) *apiRetryPolicy {
+	_ = c.Prepare()
+	p := &apiRetryPolicy{config: c}
+	if c.RateLimit > 0 {
+		p.limiter = rate.NewLimiter(rate.Limit(c.RateLimit), c.RateLimitBurst)
+	}
+	return p
+}
+
+// SetApiRetry replaces the retry config and rate limiter of all the api
+// calls, it should be called with a prepared config.
+func SetApiRetry(c TencentCloudApiRetryConfig) {
+	p := newApiRetryPolicy(c)
+	apiRetryMu.Lock()
+	apiRetry = p
+	apiRetryMu.Unlock()
+}
+
+func getApiRetry() *apiRetryPolicy {
+	apiRetryMu.RLock()
+	defer apiRetryMu.RUnlock()
+	return apiRetry
+}
+
+// isRetryable reports whether the error code should be retried
+func (p *apiRetryPolicy) isRetryable(code string) bool {
+	for _, c := range builtinRetryableCodes {
+		if strings.HasSuffix(c, "*") {
+			if strings.Contains(code, strings.TrimSuffix(c, "*")) {
+				return true
+			}
+		} else if code == c {
+			return true
+		}
+	}
+	for _, c := range p.config.RetryableCodes {
+		if code == c || strings.HasPrefix(code, c+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the delay before the retry, which starts from 1
+func (p *apiRetryPolicy) delay(retry int) time.Duration {
+	d := p.config.MaxDelay
+	if retry < 32 {
+		if exp := p.config.BaseDelay * time.Duration(1<<uint(retry-1)); exp > 0 && exp < d {
+			d = exp
+		}
+	}
+	if p.config.Jitter.True() && d > 1 {
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+	return d
+}
+
+// wait blocks until the rate limiter allows an api call
+func (p *apiRetryPolicy) wait(ctx context.Context) error {
+	if p.limiter == nil {
+		return nil
+	}
+	return p.limiter.Wait(ctx)
+}
+
+// apiTransport rate limits the api calls, traces them, and remembers the
+// action of failed requests for the retry log
+type apiTransport struct {
+	base http.RoundTripper
+}
+
+func newApiTransport(base http.RoundTripper) *apiTransport {
+	return &apiTransport{base: base}
+}
+
+func (t *apiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := getApiRetry().wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	// the body is read for the trace, so a clone with a copy of it is sent,
+	// leaving the request of the caller untouched
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		body := req.Body
+		if req.GetBody != nil {
+			req.Body.Close()
+			if body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		reqBody, err = io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	call := traceRequest(req, reqBody)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		traceResponse(call, "", "ClientError.NetworkError", err.Error())
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		traceResponse(call, "", "ClientError.NetworkError", err.Error())
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var result struct {
+		Response struct {
+			Error *struct {
+				Code    string
+				Message string
+			}
+			RequestId string
+		}
+	}
+	code, message := "", ""
+	if json.Unmarshal(body, &result) == nil && result.Response.Error != nil {
+		code, message = result.Response.Error.Code, result.Response.Error.Message
+		if result.Response.RequestId != "" && getApiRetry().isRetryable(code) {
+			requestActions.Store(result.Response.RequestId, call.Action)
+		}
+	} else if resp.StatusCode != http.StatusOK {
+		code, message = fmt.Sprintf("HttpStatus.%d", resp.StatusCode), resp.Status
+	}
+	traceResponse(call, result.Response.RequestId, code, message)
+
+	return resp, nil
+}
+
+// requestAction returns the api action of the failed request
+func requestAction(requestId string) string {
+	if requestId == "" {
+		return "unknown"
+	}
+	if action, ok := requestActions.LoadAndDelete(requestId); ok && action.(string) != "" {
+		return action.(string)
+	}
+	return "unknown"
+}
@@ -0,0 +1,94 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc struct-markdown
+//go:generate packer-sdc mapstructure-to-hcl2 -type TencentCloudApiRetryConfig
+
+package cvm
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+)
+
+// TencentCloudApiRetryConfig configures how the Tencent Cloud api calls are
+// retried and rate limited. The rate limiter is shared by all the api calls
+// of a build, including the ones to the image copy regions.
+type TencentCloudApiRetryConfig struct {
+	// The max attempts of an api call, including the first one. Defaults to 60.
+	MaxAttempts int `mapstructure:"max_attempts" required:"false"`
+	// The delay before the first retry, it doubles after every retry.
+	// Defaults to `1s`.
+	BaseDelay time.Duration `mapstructure:"base_delay" required:"false"`
+	// The max delay between retries. Defaults to `5s`.
+	MaxDelay time.Duration `mapstructure:"max_delay" required:"false"`
+	// Randomize the delay between the half and the full of it, so that
+	// parallel builds throttled at the same time do not retry in lock-step.
+	// Defaults to `true`.
+	Jitter config.Trilean `mapstructure:"jitter" required:"false"`
+	// The max api calls per second sent by the build. Defaults to 10,
+	// set it to -1 to disable the rate limiter.
+	RateLimit float64 `mapstructure:"rate_limit" required:"false"`
+	// The max api calls sent at once when the rate limiter is not
+	// throttling. Defaults to the rate limit rounded up.
+	RateLimitBurst int `mapstructure:"rate_limit_burst" required:"false"`
+	// The extra error codes to retry, besides the network errors, rate
+	// limit, internal errors and resource busy errors. A code also matches
+	// its sub codes, e.g. `ResourceUnavailable` matches
+	// `ResourceUnavailable.CvmNotFound`.
+	RetryableCodes []string `mapstructure:"retryable_codes" required:"false"`
+}
+
+func (c *TencentCloudApiRetryConfig) Prepare() []error {
+	var errs []error
+
+	if c.MaxAttempts < 0 {
+		errs = append(errs, fmt.Errorf("api_retry: max_attempts should not be negative"))
+	} else if c.MaxAttempts == 0 {
+		c.MaxAttempts = 60
+	}
+
+	if c.BaseDelay < 0 || c.MaxDelay < 0 {
+		errs = append(errs, fmt.Errorf("api_retry: base_delay and max_delay should not be negative"))
+	}
+	if c.BaseDelay == 0 {
+		c.BaseDelay = time.Second
+	}
+	if c.MaxDelay == 0 {
+		c.MaxDelay = 5 * time.Second
+		if c.BaseDelay > c.MaxDelay {
+			c.MaxDelay = c.BaseDelay
+		}
+	}
+	if c.MaxDelay < c.BaseDelay {
+		errs = append(errs, fmt.Errorf("api_retry: max_delay(%s) should not be less than base_delay(%s)",
+			c.MaxDelay, c.BaseDelay))
+	}
+
+	if c.Jitter == config.TriUnset {
+		c.Jitter = config.TriTrue
+	}
+
+	if c.RateLimit == 0 {
+		c.RateLimit = 10
+	}
+	if c.RateLimit < 0 && c.RateLimit != -1 {
+		errs = append(errs, fmt.Errorf("api_retry: rate_limit should be positive, or -1 to disable it"))
+	}
+	if c.RateLimitBurst < 0 {
+		errs = append(errs, fmt.Errorf("api_retry: rate_limit_burst should not be negative"))
+	} else if c.RateLimitBurst == 0 && c.RateLimit > 0 {
+		c.RateLimitBurst = int(math.Ceil(c.RateLimit))
+	}
+
+	for _, code := range c.RetryableCodes {
+		if code == "" {
+			errs = append(errs, fmt.Errorf("api_retry: retryable_codes should not contain empty code"))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,43 @@
+// Code generated by "packer-sdc mapstructure-to-hcl2"; DO NOT EDIT.
+
+package cvm
+
+import (
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FlatTencentCloudApiRetryConfig is an auto-generated flat version of TencentCloudApiRetryConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatTencentCloudApiRetryConfig struct {
+	MaxAttempts    *int     `mapstructure:"max_attempts" required:"false" cty:"max_attempts" hcl:"max_attempts"`
+	BaseDelay      *string  `mapstructure:"base_delay" required:"false" cty:"base_delay" hcl:"base_delay"`
+	MaxDelay       *string  `mapstructure:"max_delay" required:"false" cty:"max_delay" hcl:"max_delay"`
+	Jitter         *bool    `mapstructure:"jitter" required:"false" cty:"jitter" hcl:"jitter"`
+	RateLimit      *float64 `mapstructure:"rate_limit" required:"false" cty:"rate_limit" hcl:"rate_limit"`
+	RateLimitBurst *int     `mapstructure:"rate_limit_burst" required:"false" cty:"rate_limit_burst" hcl:"rate_limit_burst"`
+	RetryableCodes []string `mapstructure:"retryable_codes" required:"false" cty:"retryable_codes" hcl:"retryable_codes"`
+}
+
+// FlatMapstructure returns a new FlatTencentCloudApiRetryConfig.
+// FlatTencentCloudApiRetryConfig is an auto-generated flat version of TencentCloudApiRetryConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*TencentCloudApiRetryConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatTencentCloudApiRetryConfig)
+}
+
+// HCL2Spec returns the hcl spec of a TencentCloudApiRetryConfig.
+// This spec is used by HCL to read the fields of TencentCloudApiRetryConfig.
+// The decoded values from this spec will then be applied to a FlatTencentCloudApiRetryConfig.
+func (*FlatTencentCloudApiRetryConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"max_attempts":     &hcldec.AttrSpec{Name: "max_attempts", Type: cty.Number, Required: false},
+		"base_delay":       &hcldec.AttrSpec{Name: "base_delay", Type: cty.String, Required: false},
+		"max_delay":        &hcldec.AttrSpec{Name: "max_delay", Type: cty.String, Required: false},
+		"jitter":           &hcldec.AttrSpec{Name: "jitter", Type: cty.Bool, Required: false},
+		"rate_limit":       &hcldec.AttrSpec{Name: "rate_limit", Type: cty.Number, Required: false},
+		"rate_limit_burst": &hcldec.AttrSpec{Name: "rate_limit_burst", Type: cty.Number, Required: false},
+		"retryable_codes":  &hcldec.AttrSpec{Name: "retryable_codes", Type: cty.List(cty.String), Required: false},
+	}
+	return s
+}
@@ -0,0 +1,142 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/mockapi"
+)
+
+func TestTencentCloudApiRetryConfig_Prepare(t *testing.T) {
+	cf := TencentCloudApiRetryConfig{}
+	if err := cf.Prepare(); err != nil {
+		t.Fatalf("shouldn't raise error: %v", err)
+	}
+	if cf.MaxAttempts != 60 || cf.BaseDelay != time.Second || cf.MaxDelay != 5*time.Second ||
+		!cf.Jitter.True() || cf.RateLimit != 10 || cf.RateLimitBurst != 10 {
+		t.Fatalf("unexpected defaults: %+v", cf)
+	}
+
+	cf = TencentCloudApiRetryConfig{BaseDelay: 10 * time.Second}
+	if err := cf.Prepare(); err != nil {
+		t.Fatalf("shouldn't raise error: %v", err)
+	}
+	if cf.MaxDelay != 10*time.Second {
+		t.Fatalf("max_delay should default to base_delay, got %s", cf.MaxDelay)
+	}
+
+	cf = TencentCloudApiRetryConfig{BaseDelay: 10 * time.Second, MaxDelay: 5 * time.Second}
+	if err := cf.Prepare(); err == nil {
+		t.Fatal("should raise error: max_delay less than base_delay")
+	}
+
+	cf = TencentCloudApiRetryConfig{MaxAttempts: -1}
+	if err := cf.Prepare(); err == nil {
+		t.Fatal("should raise error: negative max_attempts")
+	}
+
+	cf = TencentCloudApiRetryConfig{RateLimit: -2}
+	if err := cf.Prepare(); err == nil {
+		t.Fatal("should raise error: invalid rate_limit")
+	}
+
+	cf = TencentCloudApiRetryConfig{RateLimit: -1}
+	if err := cf.Prepare(); err != nil {
+		t.Fatalf("shouldn't raise error: %v", err)
+	}
+	if p := newApiRetryPolicy(cf); p.limiter != nil {
+		t.Fatal("rate limiter should be disabled")
+	}
+
+	cf = TencentCloudApiRetryConfig{RateLimit: 2.5}
+	if err := cf.Prepare(); err != nil {
+		t.Fatalf("shouldn't raise error: %v", err)
+	}
+	if cf.RateLimitBurst != 3 {
+		t.Fatalf("rate_limit_burst should be 3, got %d", cf.RateLimitBurst)
+	}
+}
+
+func TestApiRetryPolicy(t *testing.T) {
+	p := newApiRetryPolicy(TencentCloudApiRetryConfig{
+		BaseDelay:      time.Second,
+		MaxDelay:       5 * time.Second,
+		Jitter:         config.TriFalse,
+		RetryableCodes: []string{"ResourceUnavailable"},
+	})
+
+	for retry, expected := range map[int]time.Duration{
+		1:   time.Second,
+		2:   2 * time.Second,
+		3:   4 * time.Second,
+		4:   5 * time.Second,
+		100: 5 * time.Second,
+	} {
+		if d := p.delay(retry); d != expected {
+			t.Fatalf("delay of retry %d should be %s, got %s", retry, expected, d)
+		}
+	}
+
+	p.config.Jitter = config.TriTrue
+	for i := 0; i < 100; i++ {
+		if d := p.delay(3); d < 2*time.Second || d > 4*time.Second {
+			t.Fatalf("delay with jitter should be between 2s and 4s, got %s", d)
+		}
+	}
+
+	for code, expected := range map[string]bool{
+		"RequestLimitExceeded":                        true,
+		"RequestLimitExceeded.UinLimitExceeded":       true,
+		"InternalError.TradeUnknownError":             true,
+		"ClientError.NetworkError":                    true,
+		"ResourceUnavailable":                         true,
+		"ResourceUnavailable.CvmNotFound":             true,
+		"ResourceUnavailableX":                        false,
+		"InvalidParameterValue":                       false,
+		"ResourcesSoldOut.SpecifiedInstanceType":      false,
+		"AuthFailure.SignatureFailure":                false,
+		"OperationDenied.InstanceOperationInProgress": true,
+	} {
+		if p.isRetryable(code) != expected {
+			t.Fatalf("retryable of %s should be %t", code, expected)
+		}
+	}
+}
+
+func TestRetry_requestActions(t *testing.T) {
+	server := mockapi.NewServer("AKIDmock", "mock-secret")
+	defer server.Close()
+	// a non-retryable failure, then retryable failures exhausting the attempts
+	server.Inject(mockapi.Fault{Action: "DescribeZones", Code: mockapi.CodeQuota, Times: 1})
+	server.Inject(mockapi.Fault{Action: "DescribeZones", Code: mockapi.CodeRateLimit, Times: 2})
+
+	SetApiRetry(TencentCloudApiRetryConfig{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, RateLimit: -1})
+	defer SetApiRetry(TencentCloudApiRetryConfig{})
+
+	cf := &TencentCloudAccessConfig{SecretId: server.SecretId, SecretKey: server.SecretKey, CvmEndpoint: server.URL}
+	client, err := NewCvmClient(cf, "ap-guangzhou")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		err = Retry(context.Background(), func(ctx context.Context) error {
+			_, e := client.DescribeZonesWithContext(ctx, cvm.NewDescribeZonesRequest())
+			return e
+		})
+		if err == nil {
+			t.Fatalf("call %d: expected error", i)
+		}
+	}
+
+	requestActions.Range(func(requestId, action interface{}) bool {
+		t.Errorf("expected no request action kept, got %s of %s", action, requestId)
+		return true
+	})
+}
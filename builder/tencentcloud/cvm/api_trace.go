@@ -0,0 +1,164 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// redactedParams are the request parameters never written to the trace,
+// they are matched by name at any depth.
+var redactedParams = map[string]bool{
+	"Password":   true,
+	"UserData":   true,
+	"PrivateKey": true,
+	"SecretKey":  true,
+	"Token":      true,
+}
+
+// apiCall is an api request traced, it is logged at DEBUG level and written
+// to api_trace_file as a json line
+type apiCall struct {
+	Time         time.Time              `json:"time"`
+	Service      string                 `json:"service"`
+	Action       string                 `json:"action"`
+	Region       string                 `json:"region"`
+	LatencyMs    int64                  `json:"latency_ms"`
+	Retries      int                    `json:"retries"`
+	RequestId    string                 `json:"request_id,omitempty"`
+	ErrorCode    string                 `json:"error_code,omitempty"`
+	ErrorMessage string                 `json:"error_message,omitempty"`
+	Params       map[string]interface{} `json:"params,omitempty"`
+}
+
+// apiTracer writes the traced calls to the trace file
+type apiTracer struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+var tracer = &apiTracer{}
+
+// SetApiTraceFile starts writing the api calls to path as json lines, they
+// are appended if the file exists. An empty path stops writing.
+func SetApiTraceFile(path string) error {
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+
+	if tracer.file != nil {
+		tracer.file.Close()
+		tracer.file = nil
+	}
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open api_trace_file: %s", err)
+	}
+	tracer.file = f
+	return nil
+}
+
+func (t *apiTracer) trace(call *apiCall) {
+	msg := fmt.Sprintf("[DEBUG] api %s.%s region=%s latency=%dms retries=%d request_id=%s",
+		call.Service, call.Action, call.Region, call.LatencyMs, call.Retries, call.RequestId)
+	if call.ErrorCode != "" {
+		msg += " error=" + call.ErrorCode
+	}
+	log.Print(msg)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil {
+		return
+	}
+	line, err := json.Marshal(call)
+	if err != nil {
+		log.Printf("[WARN] Failed to marshal api trace: %s", err)
+		return
+	}
+	line = append([]byte(packersdk.LogSecretFilter.FilterString(string(line))), '\n')
+	if _, err := t.file.Write(line); err != nil {
+		log.Printf("[WARN] Failed to write api_trace_file: %s", err)
+	}
+}
+
+// traceRequest returns the call of request, which is completed by
+// traceResponse after the response is received
+func traceRequest(req *http.Request, body []byte) *apiCall {
+	call := &apiCall{
+		Time:    time.Now(),
+		Service: requestService(req),
+		Action:  requestHeader(req, "X-TC-Action"),
+		Region:  requestHeader(req, "X-TC-Region"),
+		Retries: retryAttempt(req.Context()) - 1,
+	}
+	var params map[string]interface{}
+	if json.Unmarshal(body, &params) == nil && len(params) > 0 {
+		call.Params = redact(params).(map[string]interface{})
+	}
+	return call
+}
+
+// traceResponse completes the call with the response and traces it
+func traceResponse(call *apiCall, requestId, code, message string) {
+	call.LatencyMs = time.Since(call.Time).Milliseconds()
+	call.RequestId = requestId
+	call.ErrorCode = code
+	call.ErrorMessage = message
+	tracer.trace(call)
+}
+
+// requestService returns the service of request from the credential scope
+// of its signature, the host may be a custom endpoint.
+func requestService(req *http.Request) string {
+	// Credential=<secret id>/<date>/<service>/tc3_request
+	auth := req.Header.Get("Authorization")
+	if i := strings.Index(auth, "Credential="); i >= 0 {
+		scope := strings.Split(strings.SplitN(auth[i+len("Credential="):], ",", 2)[0], "/")
+		if len(scope) == 4 {
+			return scope[2]
+		}
+	}
+	return strings.SplitN(req.URL.Hostname(), ".", 2)[0]
+}
+
+// requestHeader returns the header of request, the sdk sets the X-TC headers
+// without canonicalizing their keys.
+func requestHeader(req *http.Request, key string) string {
+	if values := req.Header[key]; len(values) > 0 {
+		return values[0]
+	}
+	return req.Header.Get(key)
+}
+
+// redact replaces the values of redactedParams in v
+func redact(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			if redactedParams[k] {
+				value[k] = "<sensitive>"
+			} else {
+				value[k] = redact(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redact(item)
+		}
+	}
+	return v
+}
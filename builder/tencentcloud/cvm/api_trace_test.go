@@ -0,0 +1,189 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/mockapi"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+func TestApiTrace(t *testing.T) {
+	server := mockapi.NewServer("AKIDmock", "mock-secret")
+	defer server.Close()
+	server.Inject(mockapi.Fault{Action: "DescribeZones", Code: mockapi.CodeRateLimit, Times: 2})
+
+	SetApiRetry(TencentCloudApiRetryConfig{BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, RateLimit: -1})
+	defer SetApiRetry(TencentCloudApiRetryConfig{})
+	traceFile := filepath.Join(t.TempDir(), "trace.jsonl")
+	if err := SetApiTraceFile(traceFile); err != nil {
+		t.Fatal(err)
+	}
+	defer SetApiTraceFile("")
+
+	cf := &TencentCloudAccessConfig{SecretId: server.SecretId, SecretKey: server.SecretKey, CvmEndpoint: server.URL}
+	client, err := NewCvmClient(cf, "ap-guangzhou")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Retry(context.Background(), func(ctx context.Context) error {
+		_, e := client.DescribeZonesWithContext(ctx, cvm.NewDescribeZonesRequest())
+		return e
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetApiTraceFile("")
+
+	f, err := os.Open(traceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var calls []apiCall
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var call apiCall
+		if err := json.Unmarshal(scanner.Bytes(), &call); err != nil {
+			t.Fatalf("invalid trace line %q: %s", scanner.Text(), err)
+		}
+		calls = append(calls, call)
+	}
+	if len(calls) != 3 {
+		t.Fatalf("expected 3 calls traced, got %d", len(calls))
+	}
+	for i, call := range calls {
+		if call.Service != "cvm" || call.Action != "DescribeZones" || call.Region != "ap-guangzhou" {
+			t.Errorf("call %d: unexpected call %s.%s in %s", i, call.Service, call.Action, call.Region)
+		}
+		if call.Retries != i {
+			t.Errorf("call %d: expected %d retries, got %d", i, i, call.Retries)
+		}
+		if call.RequestId == "" {
+			t.Errorf("call %d: expected request id", i)
+		}
+		if failed := call.ErrorCode == mockapi.CodeRateLimit; failed != (i < 2) {
+			t.Errorf("call %d: unexpected error code %q", i, call.ErrorCode)
+		}
+	}
+}
+
+func TestApiTrace_unrelatedRequests(t *testing.T) {
+	server := mockapi.NewServer("AKIDmock", "mock-secret")
+	defer server.Close()
+	server.Inject(mockapi.Fault{Action: "DescribeZones", Code: mockapi.CodeQuota, Times: 2})
+
+	SetApiRetry(TencentCloudApiRetryConfig{BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, RateLimit: -1})
+	defer SetApiRetry(TencentCloudApiRetryConfig{})
+	traceFile := filepath.Join(t.TempDir(), "trace.jsonl")
+	if err := SetApiTraceFile(traceFile); err != nil {
+		t.Fatal(err)
+	}
+	defer SetApiTraceFile("")
+
+	cf := &TencentCloudAccessConfig{SecretId: server.SecretId, SecretKey: server.SecretKey, CvmEndpoint: server.URL}
+	client, err := NewCvmClient(cf, "ap-guangzhou")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the same request failed with a non-retryable code is not retried
+	for i := 0; i < 2; i++ {
+		_ = Retry(context.Background(), func(ctx context.Context) error {
+			_, e := client.DescribeZonesWithContext(ctx, cvm.NewDescribeZonesRequest())
+			return e
+		})
+	}
+	SetApiTraceFile("")
+
+	data, err := os.ReadFile(traceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 calls traced, got %d", len(lines))
+	}
+	for i, line := range lines {
+		var call apiCall
+		if err := json.Unmarshal([]byte(line), &call); err != nil {
+			t.Fatalf("invalid trace line %q: %s", line, err)
+		}
+		if call.Retries != 0 {
+			t.Errorf("call %d: expected no retries, got %d", i, call.Retries)
+		}
+	}
+}
+
+func TestTraceRequest_redact(t *testing.T) {
+	body := []byte(`{"InstanceIds":["ins-1"],"LoginSettings":{"Password":"s3cret"},"UserData":"c2VjcmV0"}`)
+	req, err := http.NewRequest("POST", "https://cvm.tencentcloudapi.com", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization",
+		"TC3-HMAC-SHA256 Credential=AKIDmock/2021-01-01/cvm/tc3_request, SignedHeaders=content-type;host, Signature=x")
+	req.Header.Set("X-TC-Action", "RunInstances")
+
+	call := traceRequest(req, body)
+	line, err := json.Marshal(call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"s3cret", "c2VjcmV0"} {
+		if strings.Contains(string(line), secret) {
+			t.Errorf("expected %s redacted, got %s", secret, line)
+		}
+	}
+	if !strings.Contains(string(line), "ins-1") || call.Service != "cvm" || call.Action != "RunInstances" {
+		t.Errorf("unexpected trace %s", line)
+	}
+}
+
+func TestApiTransport_keepRequest(t *testing.T) {
+	body := []byte(`{"InstanceIds":["ins-1"]}`)
+	req, err := http.NewRequest("POST", "https://cvm.tencentcloudapi.com", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	origBody := req.Body
+
+	var sent *http.Request
+	var sentBody []byte
+	transport := newApiTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		sent = r
+		sentBody, _ = io.ReadAll(r.Body)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"Response":{"RequestId":"req-1"}}`)),
+		}, nil
+	}))
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+
+	if sent == req {
+		t.Fatal("expected a clone of the request to be sent")
+	}
+	if req.Body != origBody {
+		t.Fatal("expected the body of the request to be kept")
+	}
+	if !bytes.Equal(sentBody, body) {
+		t.Fatalf("unexpected body sent: %s", sentBody)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
@@ -17,7 +17,7 @@ import (
 type Artifact struct {
 	TencentCloudImages map[string]string
 	BuilderIdValue     string
-	Client             *cvm.Client
+	Client             CVMAPI
 
 	// StateData should store data such as GeneratedData
 	// to be shared with post-processors
@@ -77,7 +77,7 @@ func (a *Artifact) Destroy() error {
 		var describeResp *cvm.DescribeImagesResponse
 		err := Retry(ctx, func(ctx context.Context) error {
 			var e error
-			describeResp, e = a.Client.DescribeImages(describeReq)
+			describeResp, e = a.Client.DescribeImagesWithContext(ctx, describeReq)
 			return e
 		})
 		if err != nil {
@@ -96,7 +96,7 @@ func (a *Artifact) Destroy() error {
 		var describeShareResp *cvm.DescribeImageSharePermissionResponse
 		err = Retry(ctx, func(ctx context.Context) error {
 			var e error
-			describeShareResp, e = a.Client.DescribeImageSharePermission(describeShareReq)
+			describeShareResp, e = a.Client.DescribeImageSharePermissionWithContext(ctx, describeShareReq)
 			return e
 		})
 		if err != nil {
@@ -114,7 +114,7 @@ func (a *Artifact) Destroy() error {
 			CANCEL := "CANCEL"
 			cancelShareReq.Permission = &CANCEL
 			err := Retry(ctx, func(ctx context.Context) error {
-				_, e := a.Client.ModifyImageSharePermission(cancelShareReq)
+				_, e := a.Client.ModifyImageSharePermissionWithContext(ctx, cancelShareReq)
 				return e
 			})
 			if err != nil {
@@ -125,7 +125,7 @@ func (a *Artifact) Destroy() error {
 		deleteReq := cvm.NewDeleteImagesRequest()
 		deleteReq.ImageIds = []*string{&imageId}
 		err = Retry(ctx, func(ctx context.Context) error {
-			_, e := a.Client.DeleteImages(deleteReq)
+			_, e := a.Client.DeleteImagesWithContext(ctx, deleteReq)
 			return e
 		})
 		if err != nil {
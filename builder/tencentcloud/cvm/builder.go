@@ -8,7 +8,6 @@ package cvm
 import (
 	"context"
 	"errors"
-	"fmt"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-sdk/common"
@@ -18,6 +17,7 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/mitchellh/mapstructure"
 )
 
 const BuilderId = "tencent.cloud"
@@ -28,10 +28,14 @@ type Config struct {
 	TencentCloudImageConfig  `mapstructure:",squash"`
 	TencentCloudRunConfig    `mapstructure:",squash"`
 
-	// Do not check region and zone when validate.
+	// Do not check region and zone when validate. Regions are checked against
+	// the regions got by DescribeRegions, which are cached in the packer cache
+	// directory for 24 hours, the builtin region list is used when the api is
+	// unreachable.
 	SkipRegionValidation bool `mapstructure:"skip_region_validation" required:"false"`
 
-	ctx interpolate.Context
+	ctx     interpolate.Context
+	buildId string
 }
 
 type Builder struct {
@@ -42,13 +46,21 @@ type Builder struct {
 func (b *Builder) ConfigSpec() hcldec.ObjectSpec { return b.config.FlatMapstructure().HCL2Spec() }
 
 func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
+	var md mapstructure.Metadata
 	err := config.Decode(&b.config, &config.DecodeOpts{
+		Metadata:           &md,
 		PluginType:         BuilderId,
 		Interpolate:        true,
 		InterpolateContext: &b.config.ctx,
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"run_command",
+				// rendered with build info when creating resources
+				"default_tags",
+				"run_tags",
+				"image_tags",
+				// rendered with build info when launching cvm, if enabled
+				"user_data_parts",
 			},
 		},
 	}, raws...)
@@ -56,118 +68,194 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 	if err != nil {
 		return nil, nil, err
 	}
-
-	// Propagate SkipRegionValidation to Access/Image configs
-	b.config.TencentCloudAccessConfig.skipValidation = b.config.SkipRegionValidation
-	b.config.TencentCloudImageConfig.skipValidation = b.config.SkipRegionValidation
+	b.config.TencentCloudRunConfig.SetExplicitKeys(md.Keys)
 
 	// Accumulate any errors
 	var errs *packersdk.MultiError
-	errs = packersdk.MultiErrorAppend(errs, b.config.TencentCloudAccessConfig.Prepare(&b.config.ctx)...)
-	errs = packersdk.MultiErrorAppend(errs, b.config.TencentCloudImageConfig.Prepare(&b.config.ctx)...)
+	errs = packersdk.MultiErrorAppend(errs, PrepareAccessAndImage(&b.config.ctx, &b.config.TencentCloudAccessConfig,
+		&b.config.TencentCloudImageConfig, b.config.SkipRegionValidation)...)
+	// launch template is required to check the conflicts with run config
+	if b.config.LaunchTemplateId != "" && (errs == nil || len(errs.Errors) == 0) {
+		if err := b.config.LoadLaunchTemplate(&b.config.TencentCloudAccessConfig); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+	}
 	errs = packersdk.MultiErrorAppend(errs, b.config.TencentCloudRunConfig.Prepare(&b.config.ctx)...)
 	if errs != nil && len(errs.Errors) > 0 {
 		return nil, nil, errs
 	}
 
-	packersdk.LogSecretFilter.Set(b.config.SecretId, b.config.SecretKey)
+	LogSecrets(b.config.sensitiveValues()...)
 
 	return nil, nil, nil
 }
 
+// PrepareAccess prepares the access config shared by the builders of this
+// plugin, and applies the api retry config.
+func PrepareAccess(ctx *interpolate.Context, access *TencentCloudAccessConfig, skipRegionValidation bool) []error {
+	access.skipValidation = skipRegionValidation
+
+	errs := access.Prepare(ctx)
+	SetApiRetry(access.ApiRetry)
+	return errs
+}
+
+// PrepareAccessAndImage prepares the access and image config shared by the
+// builders creating images.
+func PrepareAccessAndImage(ctx *interpolate.Context, access *TencentCloudAccessConfig, image *TencentCloudImageConfig,
+	skipRegionValidation bool) []error {
+	image.skipValidation = skipRegionValidation
+
+	errs := PrepareAccess(ctx, access, skipRegionValidation)
+	if !skipRegionValidation && access.endpoint(cvmService) == "" && len(image.ImageCopyRegions) > 0 {
+		image.regions = access.knownRegions()
+	}
+	return append(errs, image.Prepare(ctx)...)
+}
+
+// PrepareCopyRegions removes the duplicated regions the resources are copied
+// to, and checks they are known regions unless region validation is skipped.
+func PrepareCopyRegions(access *TencentCloudAccessConfig, regions []string, skipRegionValidation bool) ([]string, []error) {
+	var errs []error
+	regionSet := make(map[string]struct{})
+	prepared := make([]string, 0, len(regions))
+
+	for _, region := range regions {
+		if _, ok := regionSet[region]; ok {
+			continue
+		}
+		regionSet[region] = struct{}{}
+
+		if !skipRegionValidation && access.endpoint(cvmService) == "" {
+			if err := validRegion(region, access.knownRegions()); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		prepared = append(prepared, region)
+	}
+
+	return prepared, errs
+}
+
+// BuildInfo returns the build info of the config for the shared steps
+func (c *Config) BuildInfo() *BuildInfo {
+	return &BuildInfo{
+		Region:      c.Region,
+		BuildId:     c.buildId,
+		BuildName:   c.PackerBuildName,
+		DefaultTags: c.DefaultTags,
+		ImageName:   c.ImageName,
+		ImageTags:   c.ImageTags,
+		Ctx:         c.ctx,
+	}
+}
+
+// sensitiveValues returns the values of the sensitive options, which are
+// never logged. The user data and private keys are registered by the steps
+// reading them.
+func (c *Config) sensitiveValues() []string {
+	return append(c.TencentCloudAccessConfig.SensitiveValues(), c.TencentCloudRunConfig.SensitiveValues()...)
+}
+
 func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
+	if err := SetApiTraceFile(b.config.ApiTraceFile); err != nil {
+		return nil, err
+	}
+	defer SetApiTraceFile("")
+
 	cvmClient, vpcClient, err := b.config.Client()
 	if err != nil {
 		return nil, err
 	}
 
+	b.config.buildId = NewBuildId()
+
 	state := new(multistep.BasicStateBag)
 	state.Put("config", &b.config)
 	state.Put("cvm_client", cvmClient)
 	state.Put("vpc_client", vpcClient)
+	state.Put("common_client", NewCommonClient(&b.config.TencentCloudAccessConfig, b.config.Region))
+	state.Put("cvm_region_client", RegionCVMClientFunc(func(region string) (CVMAPI, error) {
+		return NewCvmClient(&b.config.TencentCloudAccessConfig, region)
+	}))
 	state.Put("hook", hook)
 	state.Put("ui", ui)
 
 	// Build the steps
-	var steps []multistep.Step
-	steps = []multistep.Step{
-		&stepPreValidate{
+	steps := []multistep.Step{
+		&StepPreValidate{
 			ForceDelete:  b.config.ImageForceDelete,
 			SkipIfExists: b.config.SkipIfExists,
 		},
-		&stepCheckSourceImage{
-			b.config.SourceImageId,
-		},
-		&stepConfigKeyPair{
-			Debug:        b.config.PackerDebug,
-			Comm:         &b.config.Comm,
-			DebugKeyPath: fmt.Sprintf("cvm_%s.pem", b.config.PackerBuildName),
-		},
-		// 创建 VPC 或选择 VPC, 结果一定有且只有一个 VpcId
-		&stepConfigVPC{
-			VpcId:     b.config.VpcId,
-			CidrBlock: b.config.CidrBlock,
-			VpcName:   b.config.VpcName,
-		},
-		// 创建 subnet 或者选择 subnet 列表, 结果一定有 (subnet, zone) 列表
-		&stepConfigSubnet{
-			SubnetId:        b.config.SubnetId,
-			SubnetCidrBlock: b.config.SubnectCidrBlock,
-			SubnetName:      b.config.SubnetName,
-			Zone:            b.config.Zone,
-		},
-		&stepConfigSecurityGroup{
-			SecurityGroupId:   b.config.SecurityGroupId,
-			SecurityGroupName: b.config.SecurityGroupName,
-			Description:       "securitygroup for packer",
-		},
-		// 遍历 subnet 列表, 尝试创建机器，直到创建成功或最终失败
-		&stepRunInstance{
-			InstanceTypeCandidates:   b.config.InstanceTypeCandidates,
-			InstanceChargeType:       b.config.InstanceChargeType,
-			UserData:                 b.config.UserData,
-			UserDataFile:             b.config.UserDataFile,
-			InstanceName:             b.config.InstanceName,
-			DiskType:                 b.config.DiskType,
-			DiskSize:                 b.config.DiskSize,
-			DataDisks:                b.config.DataDisks,
-			HostName:                 b.config.HostName,
-			InternetChargeType:       b.config.InternetChargeType,
-			InternetMaxBandwidthOut:  b.config.InternetMaxBandwidthOut,
-			BandwidthPackageId:       b.config.BandwidthPackageId,
-			AssociatePublicIpAddress: b.config.AssociatePublicIpAddress,
-			Tags:                     b.config.RunTags,
-			PlacementGroupId:         b.config.PlacementGroupId,
-		},
+	}
+	steps = append(steps, LaunchSteps(&b.config.TencentCloudAccessConfig, &b.config.TencentCloudRunConfig, &b.config.PackerConfig)...)
+	steps = append(steps,
 		&communicator.StepConnect{
 			Config:    &b.config.TencentCloudRunConfig.Comm,
 			SSHConfig: b.config.TencentCloudRunConfig.Comm.SSHConfigFunc(),
-			Host:      SSHHost(b.config.AssociatePublicIpAddress),
+			Host:      SSHHost(b.config.SSHInterface, b.config.SSHInterfaceTimeout, b.config.HostName),
 		},
 		&commonsteps.StepProvision{},
 		&commonsteps.StepCleanupTempKeys{
 			Comm: &b.config.TencentCloudRunConfig.Comm,
 		},
-		// We need this step to detach keypair from instance, otherwise
-		// it always fails to delete the key.
-		&stepDetachTempKeyPair{},
+	)
+
+	if !b.config.SkipCreateImage {
+		if b.config.OsType == "windows" && b.config.Comm.Type == "winrm" && b.config.Sysprep {
+			steps = append(steps, &stepSysprepInstance{})
+		}
+		// shutdown through communicator before it is lost by detaching keypair
+		steps = append(steps, &StepShutdownInstance{
+			ShutdownBehavior: b.config.ShutdownBehavior,
+			ShutdownCommand:  b.config.ShutdownCommand,
+			ShutdownTimeout:  b.config.ShutdownTimeout,
+			StopType:         b.config.StopType,
+		})
+	}
+
+	// We need this step to detach keypair from instance, otherwise
+	// it always fails to delete the key.
+	detachTempKeyPair := &StepDetachTempKeyPair{
+		StopType: b.config.StopType,
+	}
+	// the live cvm is stopped to detach keypair after the image is created
+	liveImage := !b.config.SkipCreateImage && b.config.ShutdownBehavior == "live"
+	if !liveImage {
+		steps = append(steps, detachTempKeyPair)
 	}
 
 	if !b.config.SkipCreateImage {
 		steps = append(steps,
+			&stepDetachCamRole{
+				DetachCamRole: b.config.DetachCamRole,
+			},
+			&stepRebootInstance{
+				ShutdownBehavior: b.config.ShutdownBehavior,
+				StopType:         b.config.StopType,
+			},
 			&stepCreateImage{},
-			&stepShareImage{
+		)
+		if liveImage {
+			steps = append(steps, detachTempKeyPair)
+		}
+		steps = append(steps,
+			&StepShareImage{
 				b.config.ImageShareAccounts,
 			},
-			&stepCopyImage{
+			&StepCopyImage{
 				DesinationRegions: b.config.ImageCopyRegions,
 				SourceRegion:      b.config.Region,
+				Encrypted:         b.config.ImageCopyEncrypted,
+				KmsKeyIds:         b.config.ImageCopyKmsKeyIds,
 			},
 		)
 	}
 
 	b.runner = commonsteps.NewRunner(steps, b.config.PackerConfig, ui)
 	b.runner.Run(ctx, state)
+	ReportLeaks(state)
 
 	if rawErr, ok := state.GetOk("error"); ok {
 		if b.config.SkipIfExists && errors.Is(rawErr.(error), ImageExistsError) {
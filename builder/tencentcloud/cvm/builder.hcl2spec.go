@@ -11,113 +11,143 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName           *string                    `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType         *string                    `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion         *string                    `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug               *bool                      `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce               *bool                      `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError             *string                    `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars            map[string]string          `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars       []string                   `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	SecretId                  *string                    `mapstructure:"secret_id" required:"true" cty:"secret_id" hcl:"secret_id"`
-	SecretKey                 *string                    `mapstructure:"secret_key" required:"true" cty:"secret_key" hcl:"secret_key"`
-	Region                    *string                    `mapstructure:"region" required:"true" cty:"region" hcl:"region"`
-	Zone                      *string                    `mapstructure:"zone" required:"true" cty:"zone" hcl:"zone"`
-	CvmEndpoint               *string                    `mapstructure:"cvm_endpoint" required:"false" cty:"cvm_endpoint" hcl:"cvm_endpoint"`
-	VpcEndpoint               *string                    `mapstructure:"vpc_endpoint" required:"false" cty:"vpc_endpoint" hcl:"vpc_endpoint"`
-	ImageName                 *string                    `mapstructure:"image_name" required:"true" cty:"image_name" hcl:"image_name"`
-	ImageDescription          *string                    `mapstructure:"image_description" required:"false" cty:"image_description" hcl:"image_description"`
-	Reboot                    *bool                      `mapstructure:"reboot" required:"false" cty:"reboot" hcl:"reboot"`
-	ForcePoweroff             *bool                      `mapstructure:"force_poweroff" required:"false" cty:"force_poweroff" hcl:"force_poweroff"`
-	Sysprep                   *bool                      `mapstructure:"sysprep" required:"false" cty:"sysprep" hcl:"sysprep"`
-	ImageForceDelete          *bool                      `mapstructure:"image_force_delete" cty:"image_force_delete" hcl:"image_force_delete"`
-	ImageCopyRegions          []string                   `mapstructure:"image_copy_regions" required:"false" cty:"image_copy_regions" hcl:"image_copy_regions"`
-	ImageShareAccounts        []string                   `mapstructure:"image_share_accounts" required:"false" cty:"image_share_accounts" hcl:"image_share_accounts"`
-	ImageTags                 map[string]string          `mapstructure:"image_tags" required:"false" cty:"image_tags" hcl:"image_tags"`
-	SkipIfExists              *bool                      `mapstructure:"skip_if_exists" required:"false" cty:"skip_if_exists" hcl:"skip_if_exists"`
-	AssociatePublicIpAddress  *bool                      `mapstructure:"associate_public_ip_address" required:"false" cty:"associate_public_ip_address" hcl:"associate_public_ip_address"`
-	SourceImageId             *string                    `mapstructure:"source_image_id" required:"false" cty:"source_image_id" hcl:"source_image_id"`
-	SourceImageName           *string                    `mapstructure:"source_image_name" required:"false" cty:"source_image_name" hcl:"source_image_name"`
-	InstanceChargeType        *string                    `mapstructure:"instance_charge_type" required:"false" cty:"instance_charge_type" hcl:"instance_charge_type"`
-	InstanceTypeCandidates    []string                   `mapstructure:"instance_type_candidates" required:"false" cty:"instance_type_candidates" hcl:"instance_type_candidates"`
-	InstanceType              *string                    `mapstructure:"instance_type" required:"false" cty:"instance_type" hcl:"instance_type"`
-	InstanceName              *string                    `mapstructure:"instance_name" required:"false" cty:"instance_name" hcl:"instance_name"`
-	DiskType                  *string                    `mapstructure:"disk_type" required:"false" cty:"disk_type" hcl:"disk_type"`
-	DiskSize                  *int64                     `mapstructure:"disk_size" required:"false" cty:"disk_size" hcl:"disk_size"`
-	DataDisks                 []FlattencentCloudDataDisk `mapstructure:"data_disks" cty:"data_disks" hcl:"data_disks"`
-	VpcId                     *string                    `mapstructure:"vpc_id" required:"false" cty:"vpc_id" hcl:"vpc_id"`
-	VpcName                   *string                    `mapstructure:"vpc_name" required:"false" cty:"vpc_name" hcl:"vpc_name"`
-	VpcIp                     *string                    `mapstructure:"vpc_ip" cty:"vpc_ip" hcl:"vpc_ip"`
-	SubnetId                  *string                    `mapstructure:"subnet_id" required:"false" cty:"subnet_id" hcl:"subnet_id"`
-	SubnetName                *string                    `mapstructure:"subnet_name" required:"false" cty:"subnet_name" hcl:"subnet_name"`
-	CidrBlock                 *string                    `mapstructure:"cidr_block" required:"false" cty:"cidr_block" hcl:"cidr_block"`
-	SubnectCidrBlock          *string                    `mapstructure:"subnect_cidr_block" required:"false" cty:"subnect_cidr_block" hcl:"subnect_cidr_block"`
-	InternetChargeType        *string                    `mapstructure:"internet_charge_type" required:"false" cty:"internet_charge_type" hcl:"internet_charge_type"`
-	InternetMaxBandwidthOut   *int64                     `mapstructure:"internet_max_bandwidth_out" required:"false" cty:"internet_max_bandwidth_out" hcl:"internet_max_bandwidth_out"`
-	BandwidthPackageId        *string                    `mapstructure:"bandwidth_package_id" required:"false" cty:"bandwidth_package_id" hcl:"bandwidth_package_id"`
-	SecurityGroupId           *string                    `mapstructure:"security_group_id" required:"false" cty:"security_group_id" hcl:"security_group_id"`
-	SecurityGroupName         *string                    `mapstructure:"security_group_name" required:"false" cty:"security_group_name" hcl:"security_group_name"`
-	UserData                  *string                    `mapstructure:"user_data" required:"false" cty:"user_data" hcl:"user_data"`
-	UserDataFile              *string                    `mapstructure:"user_data_file" required:"false" cty:"user_data_file" hcl:"user_data_file"`
-	HostName                  *string                    `mapstructure:"host_name" required:"false" cty:"host_name" hcl:"host_name"`
-	RunTags                   map[string]string          `mapstructure:"run_tags" required:"false" cty:"run_tags" hcl:"run_tags"`
-	RunTag                    []config.FlatKeyValue      `mapstructure:"run_tag" required:"false" cty:"run_tag" hcl:"run_tag"`
-	Type                      *string                    `mapstructure:"communicator" cty:"communicator" hcl:"communicator"`
-	PauseBeforeConnect        *string                    `mapstructure:"pause_before_connecting" cty:"pause_before_connecting" hcl:"pause_before_connecting"`
-	SSHHost                   *string                    `mapstructure:"ssh_host" cty:"ssh_host" hcl:"ssh_host"`
-	SSHPort                   *int                       `mapstructure:"ssh_port" cty:"ssh_port" hcl:"ssh_port"`
-	SSHUsername               *string                    `mapstructure:"ssh_username" cty:"ssh_username" hcl:"ssh_username"`
-	SSHPassword               *string                    `mapstructure:"ssh_password" cty:"ssh_password" hcl:"ssh_password"`
-	SSHKeyPairName            *string                    `mapstructure:"ssh_keypair_name" undocumented:"true" cty:"ssh_keypair_name" hcl:"ssh_keypair_name"`
-	SSHTemporaryKeyPairName   *string                    `mapstructure:"temporary_key_pair_name" undocumented:"true" cty:"temporary_key_pair_name" hcl:"temporary_key_pair_name"`
-	SSHTemporaryKeyPairType   *string                    `mapstructure:"temporary_key_pair_type" cty:"temporary_key_pair_type" hcl:"temporary_key_pair_type"`
-	SSHTemporaryKeyPairBits   *int                       `mapstructure:"temporary_key_pair_bits" cty:"temporary_key_pair_bits" hcl:"temporary_key_pair_bits"`
-	SSHCiphers                []string                   `mapstructure:"ssh_ciphers" cty:"ssh_ciphers" hcl:"ssh_ciphers"`
-	SSHClearAuthorizedKeys    *bool                      `mapstructure:"ssh_clear_authorized_keys" cty:"ssh_clear_authorized_keys" hcl:"ssh_clear_authorized_keys"`
-	SSHKEXAlgos               []string                   `mapstructure:"ssh_key_exchange_algorithms" cty:"ssh_key_exchange_algorithms" hcl:"ssh_key_exchange_algorithms"`
-	SSHPrivateKeyFile         *string                    `mapstructure:"ssh_private_key_file" undocumented:"true" cty:"ssh_private_key_file" hcl:"ssh_private_key_file"`
-	SSHCertificateFile        *string                    `mapstructure:"ssh_certificate_file" cty:"ssh_certificate_file" hcl:"ssh_certificate_file"`
-	SSHPty                    *bool                      `mapstructure:"ssh_pty" cty:"ssh_pty" hcl:"ssh_pty"`
-	SSHTimeout                *string                    `mapstructure:"ssh_timeout" cty:"ssh_timeout" hcl:"ssh_timeout"`
-	SSHWaitTimeout            *string                    `mapstructure:"ssh_wait_timeout" undocumented:"true" cty:"ssh_wait_timeout" hcl:"ssh_wait_timeout"`
-	SSHAgentAuth              *bool                      `mapstructure:"ssh_agent_auth" undocumented:"true" cty:"ssh_agent_auth" hcl:"ssh_agent_auth"`
-	SSHDisableAgentForwarding *bool                      `mapstructure:"ssh_disable_agent_forwarding" cty:"ssh_disable_agent_forwarding" hcl:"ssh_disable_agent_forwarding"`
-	SSHHandshakeAttempts      *int                       `mapstructure:"ssh_handshake_attempts" cty:"ssh_handshake_attempts" hcl:"ssh_handshake_attempts"`
-	SSHBastionHost            *string                    `mapstructure:"ssh_bastion_host" cty:"ssh_bastion_host" hcl:"ssh_bastion_host"`
-	SSHBastionPort            *int                       `mapstructure:"ssh_bastion_port" cty:"ssh_bastion_port" hcl:"ssh_bastion_port"`
-	SSHBastionAgentAuth       *bool                      `mapstructure:"ssh_bastion_agent_auth" cty:"ssh_bastion_agent_auth" hcl:"ssh_bastion_agent_auth"`
-	SSHBastionUsername        *string                    `mapstructure:"ssh_bastion_username" cty:"ssh_bastion_username" hcl:"ssh_bastion_username"`
-	SSHBastionPassword        *string                    `mapstructure:"ssh_bastion_password" cty:"ssh_bastion_password" hcl:"ssh_bastion_password"`
-	SSHBastionInteractive     *bool                      `mapstructure:"ssh_bastion_interactive" cty:"ssh_bastion_interactive" hcl:"ssh_bastion_interactive"`
-	SSHBastionPrivateKeyFile  *string                    `mapstructure:"ssh_bastion_private_key_file" cty:"ssh_bastion_private_key_file" hcl:"ssh_bastion_private_key_file"`
-	SSHBastionCertificateFile *string                    `mapstructure:"ssh_bastion_certificate_file" cty:"ssh_bastion_certificate_file" hcl:"ssh_bastion_certificate_file"`
-	SSHFileTransferMethod     *string                    `mapstructure:"ssh_file_transfer_method" cty:"ssh_file_transfer_method" hcl:"ssh_file_transfer_method"`
-	SSHProxyHost              *string                    `mapstructure:"ssh_proxy_host" cty:"ssh_proxy_host" hcl:"ssh_proxy_host"`
-	SSHProxyPort              *int                       `mapstructure:"ssh_proxy_port" cty:"ssh_proxy_port" hcl:"ssh_proxy_port"`
-	SSHProxyUsername          *string                    `mapstructure:"ssh_proxy_username" cty:"ssh_proxy_username" hcl:"ssh_proxy_username"`
-	SSHProxyPassword          *string                    `mapstructure:"ssh_proxy_password" cty:"ssh_proxy_password" hcl:"ssh_proxy_password"`
-	SSHKeepAliveInterval      *string                    `mapstructure:"ssh_keep_alive_interval" cty:"ssh_keep_alive_interval" hcl:"ssh_keep_alive_interval"`
-	SSHReadWriteTimeout       *string                    `mapstructure:"ssh_read_write_timeout" cty:"ssh_read_write_timeout" hcl:"ssh_read_write_timeout"`
-	SSHRemoteTunnels          []string                   `mapstructure:"ssh_remote_tunnels" cty:"ssh_remote_tunnels" hcl:"ssh_remote_tunnels"`
-	SSHLocalTunnels           []string                   `mapstructure:"ssh_local_tunnels" cty:"ssh_local_tunnels" hcl:"ssh_local_tunnels"`
-	SSHPublicKey              []byte                     `mapstructure:"ssh_public_key" undocumented:"true" cty:"ssh_public_key" hcl:"ssh_public_key"`
-	SSHPrivateKey             []byte                     `mapstructure:"ssh_private_key" undocumented:"true" cty:"ssh_private_key" hcl:"ssh_private_key"`
-	WinRMUser                 *string                    `mapstructure:"winrm_username" cty:"winrm_username" hcl:"winrm_username"`
-	WinRMPassword             *string                    `mapstructure:"winrm_password" cty:"winrm_password" hcl:"winrm_password"`
-	WinRMHost                 *string                    `mapstructure:"winrm_host" cty:"winrm_host" hcl:"winrm_host"`
-	WinRMNoProxy              *bool                      `mapstructure:"winrm_no_proxy" cty:"winrm_no_proxy" hcl:"winrm_no_proxy"`
-	WinRMPort                 *int                       `mapstructure:"winrm_port" cty:"winrm_port" hcl:"winrm_port"`
-	WinRMTimeout              *string                    `mapstructure:"winrm_timeout" cty:"winrm_timeout" hcl:"winrm_timeout"`
-	WinRMUseSSL               *bool                      `mapstructure:"winrm_use_ssl" cty:"winrm_use_ssl" hcl:"winrm_use_ssl"`
-	WinRMInsecure             *bool                      `mapstructure:"winrm_insecure" cty:"winrm_insecure" hcl:"winrm_insecure"`
-	WinRMUseNTLM              *bool                      `mapstructure:"winrm_use_ntlm" cty:"winrm_use_ntlm" hcl:"winrm_use_ntlm"`
-	SSHPrivateIp              *bool                      `mapstructure:"ssh_private_ip" cty:"ssh_private_ip" hcl:"ssh_private_ip"`
-	SkipCreateImage           *bool                      `mapstructure:"skip_create_image" required:"false" cty:"skip_create_image" hcl:"skip_create_image"`
-	DisableSecurityService    *bool                      `mapstructure:"disable_security_service" required:"false" cty:"disable_security_service" hcl:"disable_security_service"`
-	DisableMonitorService     *bool                      `mapstructure:"disable_monitor_service" required:"false" cty:"disable_monitor_service" hcl:"disable_monitor_service"`
-	DisableAutomationService  *bool                      `mapstructure:"disable_automation_service" required:"false" cty:"disable_automation_service" hcl:"disable_automation_service"`
-	PlacementGroupId          *string                    `mapstructure:"placement_group_id" required:"false" cty:"placement_group_id" hcl:"placement_group_id"`
-	SkipRegionValidation      *bool                      `mapstructure:"skip_region_validation" required:"false" cty:"skip_region_validation" hcl:"skip_region_validation"`
+	PackerBuildName           *string                         `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType         *string                         `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion         *string                         `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug               *bool                           `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce               *bool                           `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError             *string                         `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars            map[string]string               `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars       []string                        `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	SecretId                  *string                         `mapstructure:"secret_id" required:"true" cty:"secret_id" hcl:"secret_id"`
+	SecretKey                 *string                         `mapstructure:"secret_key" required:"true" cty:"secret_key" hcl:"secret_key"`
+	Region                    *string                         `mapstructure:"region" required:"true" cty:"region" hcl:"region"`
+	Zone                      *string                         `mapstructure:"zone" required:"true" cty:"zone" hcl:"zone"`
+	CvmEndpoint               *string                         `mapstructure:"cvm_endpoint" required:"false" cty:"cvm_endpoint" hcl:"cvm_endpoint"`
+	VpcEndpoint               *string                         `mapstructure:"vpc_endpoint" required:"false" cty:"vpc_endpoint" hcl:"vpc_endpoint"`
+	Endpoints                 map[string]string               `mapstructure:"endpoints" required:"false" cty:"endpoints" hcl:"endpoints"`
+	UseInternalEndpoint       *bool                           `mapstructure:"use_internal_endpoint" required:"false" cty:"use_internal_endpoint" hcl:"use_internal_endpoint"`
+	HttpProxy                 *string                         `mapstructure:"http_proxy" required:"false" cty:"http_proxy" hcl:"http_proxy"`
+	CACertFile                *string                         `mapstructure:"ca_cert_file" required:"false" cty:"ca_cert_file" hcl:"ca_cert_file"`
+	RequestTimeout            *string                         `mapstructure:"request_timeout" required:"false" cty:"request_timeout" hcl:"request_timeout"`
+	ApiRetry                  *FlatTencentCloudApiRetryConfig `mapstructure:"api_retry" required:"false" cty:"api_retry" hcl:"api_retry"`
+	ApiTraceFile              *string                         `mapstructure:"api_trace_file" required:"false" cty:"api_trace_file" hcl:"api_trace_file"`
+	ImageName                 *string                         `mapstructure:"image_name" required:"true" cty:"image_name" hcl:"image_name"`
+	ImageDescription          *string                         `mapstructure:"image_description" required:"false" cty:"image_description" hcl:"image_description"`
+	Reboot                    *bool                           `mapstructure:"reboot" required:"false" cty:"reboot" hcl:"reboot"`
+	ShutdownBehavior          *string                         `mapstructure:"shutdown_behavior" required:"false" cty:"shutdown_behavior" hcl:"shutdown_behavior"`
+	ShutdownCommand           *string                         `mapstructure:"shutdown_command" required:"false" cty:"shutdown_command" hcl:"shutdown_command"`
+	ShutdownTimeout           *string                         `mapstructure:"shutdown_timeout" required:"false" cty:"shutdown_timeout" hcl:"shutdown_timeout"`
+	StopType                  *string                         `mapstructure:"stop_type" required:"false" cty:"stop_type" hcl:"stop_type"`
+	ForcePoweroff             *bool                           `mapstructure:"force_poweroff" required:"false" cty:"force_poweroff" hcl:"force_poweroff"`
+	Sysprep                   *bool                           `mapstructure:"sysprep" required:"false" cty:"sysprep" hcl:"sysprep"`
+	ImageForceDelete          *bool                           `mapstructure:"image_force_delete" cty:"image_force_delete" hcl:"image_force_delete"`
+	ImageCopyRegions          []string                        `mapstructure:"image_copy_regions" required:"false" cty:"image_copy_regions" hcl:"image_copy_regions"`
+	ImageCopyEncrypted        *bool                           `mapstructure:"image_copy_encrypted" required:"false" cty:"image_copy_encrypted" hcl:"image_copy_encrypted"`
+	ImageCopyKmsKeyIds        map[string]string               `mapstructure:"image_copy_kms_key_ids" required:"false" cty:"image_copy_kms_key_ids" hcl:"image_copy_kms_key_ids"`
+	ImageShareAccounts        []string                        `mapstructure:"image_share_accounts" required:"false" cty:"image_share_accounts" hcl:"image_share_accounts"`
+	ImageTags                 map[string]string               `mapstructure:"image_tags" required:"false" cty:"image_tags" hcl:"image_tags"`
+	SkipIfExists              *bool                           `mapstructure:"skip_if_exists" required:"false" cty:"skip_if_exists" hcl:"skip_if_exists"`
+	AssociatePublicIpAddress  *bool                           `mapstructure:"associate_public_ip_address" required:"false" cty:"associate_public_ip_address" hcl:"associate_public_ip_address"`
+	AssociateEip              *bool                           `mapstructure:"associate_eip" required:"false" cty:"associate_eip" hcl:"associate_eip"`
+	EipIds                    []string                        `mapstructure:"eip_ids" required:"false" cty:"eip_ids" hcl:"eip_ids"`
+	EipAddresses              []string                        `mapstructure:"eip_addresses" required:"false" cty:"eip_addresses" hcl:"eip_addresses"`
+	SourceImageId             *string                         `mapstructure:"source_image_id" required:"false" cty:"source_image_id" hcl:"source_image_id"`
+	SourceImageName           *string                         `mapstructure:"source_image_name" required:"false" cty:"source_image_name" hcl:"source_image_name"`
+	InstanceChargeType        *string                         `mapstructure:"instance_charge_type" required:"false" cty:"instance_charge_type" hcl:"instance_charge_type"`
+	InstanceTypeCandidates    []string                        `mapstructure:"instance_type_candidates" required:"false" cty:"instance_type_candidates" hcl:"instance_type_candidates"`
+	InstanceType              *string                         `mapstructure:"instance_type" required:"false" cty:"instance_type" hcl:"instance_type"`
+	InstanceName              *string                         `mapstructure:"instance_name" required:"false" cty:"instance_name" hcl:"instance_name"`
+	DiskType                  *string                         `mapstructure:"disk_type" required:"false" cty:"disk_type" hcl:"disk_type"`
+	DiskSize                  *int64                          `mapstructure:"disk_size" required:"false" cty:"disk_size" hcl:"disk_size"`
+	DiskEncrypted             *bool                           `mapstructure:"disk_encrypted" required:"false" cty:"disk_encrypted" hcl:"disk_encrypted"`
+	DiskKmsKeyId              *string                         `mapstructure:"disk_kms_key_id" required:"false" cty:"disk_kms_key_id" hcl:"disk_kms_key_id"`
+	DataDisks                 []FlattencentCloudDataDisk      `mapstructure:"data_disks" cty:"data_disks" hcl:"data_disks"`
+	VpcId                     *string                         `mapstructure:"vpc_id" required:"false" cty:"vpc_id" hcl:"vpc_id"`
+	VpcName                   *string                         `mapstructure:"vpc_name" required:"false" cty:"vpc_name" hcl:"vpc_name"`
+	VpcIp                     *string                         `mapstructure:"vpc_ip" required:"false" cty:"vpc_ip" hcl:"vpc_ip"`
+	VpcIpCandidates           []string                        `mapstructure:"vpc_ip_candidates" required:"false" cty:"vpc_ip_candidates" hcl:"vpc_ip_candidates"`
+	SubnetId                  *string                         `mapstructure:"subnet_id" required:"false" cty:"subnet_id" hcl:"subnet_id"`
+	SubnetName                *string                         `mapstructure:"subnet_name" required:"false" cty:"subnet_name" hcl:"subnet_name"`
+	EnableIpv6                *bool                           `mapstructure:"enable_ipv6" required:"false" cty:"enable_ipv6" hcl:"enable_ipv6"`
+	CidrBlock                 *string                         `mapstructure:"cidr_block" required:"false" cty:"cidr_block" hcl:"cidr_block"`
+	SubnectCidrBlock          *string                         `mapstructure:"subnect_cidr_block" required:"false" cty:"subnect_cidr_block" hcl:"subnect_cidr_block"`
+	InternetChargeType        *string                         `mapstructure:"internet_charge_type" required:"false" cty:"internet_charge_type" hcl:"internet_charge_type"`
+	InternetMaxBandwidthOut   *int64                          `mapstructure:"internet_max_bandwidth_out" required:"false" cty:"internet_max_bandwidth_out" hcl:"internet_max_bandwidth_out"`
+	BandwidthPackageId        *string                         `mapstructure:"bandwidth_package_id" required:"false" cty:"bandwidth_package_id" hcl:"bandwidth_package_id"`
+	SecurityGroupId           *string                         `mapstructure:"security_group_id" required:"false" cty:"security_group_id" hcl:"security_group_id"`
+	SecurityGroupName         *string                         `mapstructure:"security_group_name" required:"false" cty:"security_group_name" hcl:"security_group_name"`
+	UserData                  *string                         `mapstructure:"user_data" required:"false" cty:"user_data" hcl:"user_data"`
+	UserDataFile              *string                         `mapstructure:"user_data_file" required:"false" cty:"user_data_file" hcl:"user_data_file"`
+	UserDataParts             []FlattencentCloudUserDataPart  `mapstructure:"user_data_parts" required:"false" cty:"user_data_parts" hcl:"user_data_parts"`
+	HostName                  *string                         `mapstructure:"host_name" required:"false" cty:"host_name" hcl:"host_name"`
+	CamRoleName               *string                         `mapstructure:"cam_role_name" required:"false" cty:"cam_role_name" hcl:"cam_role_name"`
+	DetachCamRole             *bool                           `mapstructure:"detach_cam_role" required:"false" cty:"detach_cam_role" hcl:"detach_cam_role"`
+	RunTags                   map[string]string               `mapstructure:"run_tags" required:"false" cty:"run_tags" hcl:"run_tags"`
+	DefaultTags               map[string]string               `mapstructure:"default_tags" required:"false" cty:"default_tags" hcl:"default_tags"`
+	RunTag                    []config.FlatKeyValue           `mapstructure:"run_tag" required:"false" cty:"run_tag" hcl:"run_tag"`
+	Type                      *string                         `mapstructure:"communicator" cty:"communicator" hcl:"communicator"`
+	PauseBeforeConnect        *string                         `mapstructure:"pause_before_connecting" cty:"pause_before_connecting" hcl:"pause_before_connecting"`
+	SSHHost                   *string                         `mapstructure:"ssh_host" cty:"ssh_host" hcl:"ssh_host"`
+	SSHPort                   *int                            `mapstructure:"ssh_port" cty:"ssh_port" hcl:"ssh_port"`
+	SSHUsername               *string                         `mapstructure:"ssh_username" cty:"ssh_username" hcl:"ssh_username"`
+	SSHPassword               *string                         `mapstructure:"ssh_password" cty:"ssh_password" hcl:"ssh_password"`
+	SSHKeyPairName            *string                         `mapstructure:"ssh_keypair_name" undocumented:"true" cty:"ssh_keypair_name" hcl:"ssh_keypair_name"`
+	SSHTemporaryKeyPairName   *string                         `mapstructure:"temporary_key_pair_name" undocumented:"true" cty:"temporary_key_pair_name" hcl:"temporary_key_pair_name"`
+	SSHTemporaryKeyPairType   *string                         `mapstructure:"temporary_key_pair_type" cty:"temporary_key_pair_type" hcl:"temporary_key_pair_type"`
+	SSHTemporaryKeyPairBits   *int                            `mapstructure:"temporary_key_pair_bits" cty:"temporary_key_pair_bits" hcl:"temporary_key_pair_bits"`
+	SSHCiphers                []string                        `mapstructure:"ssh_ciphers" cty:"ssh_ciphers" hcl:"ssh_ciphers"`
+	SSHClearAuthorizedKeys    *bool                           `mapstructure:"ssh_clear_authorized_keys" cty:"ssh_clear_authorized_keys" hcl:"ssh_clear_authorized_keys"`
+	SSHKEXAlgos               []string                        `mapstructure:"ssh_key_exchange_algorithms" cty:"ssh_key_exchange_algorithms" hcl:"ssh_key_exchange_algorithms"`
+	SSHPrivateKeyFile         *string                         `mapstructure:"ssh_private_key_file" undocumented:"true" cty:"ssh_private_key_file" hcl:"ssh_private_key_file"`
+	SSHCertificateFile        *string                         `mapstructure:"ssh_certificate_file" cty:"ssh_certificate_file" hcl:"ssh_certificate_file"`
+	SSHPty                    *bool                           `mapstructure:"ssh_pty" cty:"ssh_pty" hcl:"ssh_pty"`
+	SSHTimeout                *string                         `mapstructure:"ssh_timeout" cty:"ssh_timeout" hcl:"ssh_timeout"`
+	SSHWaitTimeout            *string                         `mapstructure:"ssh_wait_timeout" undocumented:"true" cty:"ssh_wait_timeout" hcl:"ssh_wait_timeout"`
+	SSHAgentAuth              *bool                           `mapstructure:"ssh_agent_auth" undocumented:"true" cty:"ssh_agent_auth" hcl:"ssh_agent_auth"`
+	SSHDisableAgentForwarding *bool                           `mapstructure:"ssh_disable_agent_forwarding" cty:"ssh_disable_agent_forwarding" hcl:"ssh_disable_agent_forwarding"`
+	SSHHandshakeAttempts      *int                            `mapstructure:"ssh_handshake_attempts" cty:"ssh_handshake_attempts" hcl:"ssh_handshake_attempts"`
+	SSHBastionHost            *string                         `mapstructure:"ssh_bastion_host" cty:"ssh_bastion_host" hcl:"ssh_bastion_host"`
+	SSHBastionPort            *int                            `mapstructure:"ssh_bastion_port" cty:"ssh_bastion_port" hcl:"ssh_bastion_port"`
+	SSHBastionAgentAuth       *bool                           `mapstructure:"ssh_bastion_agent_auth" cty:"ssh_bastion_agent_auth" hcl:"ssh_bastion_agent_auth"`
+	SSHBastionUsername        *string                         `mapstructure:"ssh_bastion_username" cty:"ssh_bastion_username" hcl:"ssh_bastion_username"`
+	SSHBastionPassword        *string                         `mapstructure:"ssh_bastion_password" cty:"ssh_bastion_password" hcl:"ssh_bastion_password"`
+	SSHBastionInteractive     *bool                           `mapstructure:"ssh_bastion_interactive" cty:"ssh_bastion_interactive" hcl:"ssh_bastion_interactive"`
+	SSHBastionPrivateKeyFile  *string                         `mapstructure:"ssh_bastion_private_key_file" cty:"ssh_bastion_private_key_file" hcl:"ssh_bastion_private_key_file"`
+	SSHBastionCertificateFile *string                         `mapstructure:"ssh_bastion_certificate_file" cty:"ssh_bastion_certificate_file" hcl:"ssh_bastion_certificate_file"`
+	SSHFileTransferMethod     *string                         `mapstructure:"ssh_file_transfer_method" cty:"ssh_file_transfer_method" hcl:"ssh_file_transfer_method"`
+	SSHProxyHost              *string                         `mapstructure:"ssh_proxy_host" cty:"ssh_proxy_host" hcl:"ssh_proxy_host"`
+	SSHProxyPort              *int                            `mapstructure:"ssh_proxy_port" cty:"ssh_proxy_port" hcl:"ssh_proxy_port"`
+	SSHProxyUsername          *string                         `mapstructure:"ssh_proxy_username" cty:"ssh_proxy_username" hcl:"ssh_proxy_username"`
+	SSHProxyPassword          *string                         `mapstructure:"ssh_proxy_password" cty:"ssh_proxy_password" hcl:"ssh_proxy_password"`
+	SSHKeepAliveInterval      *string                         `mapstructure:"ssh_keep_alive_interval" cty:"ssh_keep_alive_interval" hcl:"ssh_keep_alive_interval"`
+	SSHReadWriteTimeout       *string                         `mapstructure:"ssh_read_write_timeout" cty:"ssh_read_write_timeout" hcl:"ssh_read_write_timeout"`
+	SSHRemoteTunnels          []string                        `mapstructure:"ssh_remote_tunnels" cty:"ssh_remote_tunnels" hcl:"ssh_remote_tunnels"`
+	SSHLocalTunnels           []string                        `mapstructure:"ssh_local_tunnels" cty:"ssh_local_tunnels" hcl:"ssh_local_tunnels"`
+	SSHPublicKey              []byte                          `mapstructure:"ssh_public_key" undocumented:"true" cty:"ssh_public_key" hcl:"ssh_public_key"`
+	SSHPrivateKey             []byte                          `mapstructure:"ssh_private_key" undocumented:"true" cty:"ssh_private_key" hcl:"ssh_private_key"`
+	WinRMUser                 *string                         `mapstructure:"winrm_username" cty:"winrm_username" hcl:"winrm_username"`
+	WinRMPassword             *string                         `mapstructure:"winrm_password" cty:"winrm_password" hcl:"winrm_password"`
+	WinRMHost                 *string                         `mapstructure:"winrm_host" cty:"winrm_host" hcl:"winrm_host"`
+	WinRMNoProxy              *bool                           `mapstructure:"winrm_no_proxy" cty:"winrm_no_proxy" hcl:"winrm_no_proxy"`
+	WinRMPort                 *int                            `mapstructure:"winrm_port" cty:"winrm_port" hcl:"winrm_port"`
+	WinRMTimeout              *string                         `mapstructure:"winrm_timeout" cty:"winrm_timeout" hcl:"winrm_timeout"`
+	WinRMUseSSL               *bool                           `mapstructure:"winrm_use_ssl" cty:"winrm_use_ssl" hcl:"winrm_use_ssl"`
+	WinRMInsecure             *bool                           `mapstructure:"winrm_insecure" cty:"winrm_insecure" hcl:"winrm_insecure"`
+	WinRMUseNTLM              *bool                           `mapstructure:"winrm_use_ntlm" cty:"winrm_use_ntlm" hcl:"winrm_use_ntlm"`
+	SSHPrivateIp              *bool                           `mapstructure:"ssh_private_ip" required:"false" cty:"ssh_private_ip" hcl:"ssh_private_ip"`
+	SSHInterface              *string                         `mapstructure:"ssh_interface" required:"false" cty:"ssh_interface" hcl:"ssh_interface"`
+	SSHInterfaceTimeout       *string                         `mapstructure:"ssh_interface_timeout" required:"false" cty:"ssh_interface_timeout" hcl:"ssh_interface_timeout"`
+	SkipCreateImage           *bool                           `mapstructure:"skip_create_image" required:"false" cty:"skip_create_image" hcl:"skip_create_image"`
+	OsType                    *string                         `mapstructure:"os_type" required:"false" cty:"os_type" hcl:"os_type"`
+	DisableSecurityService    *bool                           `mapstructure:"disable_security_service" required:"false" cty:"disable_security_service" hcl:"disable_security_service"`
+	DisableMonitorService     *bool                           `mapstructure:"disable_monitor_service" required:"false" cty:"disable_monitor_service" hcl:"disable_monitor_service"`
+	DisableAutomationService  *bool                           `mapstructure:"disable_automation_service" required:"false" cty:"disable_automation_service" hcl:"disable_automation_service"`
+	PlacementGroupId          *string                         `mapstructure:"placement_group_id" required:"false" cty:"placement_group_id" hcl:"placement_group_id"`
+	Placement                 *FlattencentCloudPlacement      `mapstructure:"placement" required:"false" cty:"placement" hcl:"placement"`
+	LaunchTemplateId          *string                         `mapstructure:"launch_template_id" required:"false" cty:"launch_template_id" hcl:"launch_template_id"`
+	LaunchTemplateVersion     *uint64                         `mapstructure:"launch_template_version" required:"false" cty:"launch_template_version" hcl:"launch_template_version"`
+	SkipRegionValidation      *bool                           `mapstructure:"skip_region_validation" required:"false" cty:"skip_region_validation" hcl:"skip_region_validation"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -146,17 +176,33 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"zone":                         &hcldec.AttrSpec{Name: "zone", Type: cty.String, Required: false},
 		"cvm_endpoint":                 &hcldec.AttrSpec{Name: "cvm_endpoint", Type: cty.String, Required: false},
 		"vpc_endpoint":                 &hcldec.AttrSpec{Name: "vpc_endpoint", Type: cty.String, Required: false},
+		"endpoints":                    &hcldec.AttrSpec{Name: "endpoints", Type: cty.Map(cty.String), Required: false},
+		"use_internal_endpoint":        &hcldec.AttrSpec{Name: "use_internal_endpoint", Type: cty.Bool, Required: false},
+		"http_proxy":                   &hcldec.AttrSpec{Name: "http_proxy", Type: cty.String, Required: false},
+		"ca_cert_file":                 &hcldec.AttrSpec{Name: "ca_cert_file", Type: cty.String, Required: false},
+		"request_timeout":              &hcldec.AttrSpec{Name: "request_timeout", Type: cty.String, Required: false},
+		"api_retry":                    &hcldec.BlockSpec{TypeName: "api_retry", Nested: hcldec.ObjectSpec((*FlatTencentCloudApiRetryConfig)(nil).HCL2Spec())},
+		"api_trace_file":               &hcldec.AttrSpec{Name: "api_trace_file", Type: cty.String, Required: false},
 		"image_name":                   &hcldec.AttrSpec{Name: "image_name", Type: cty.String, Required: false},
 		"image_description":            &hcldec.AttrSpec{Name: "image_description", Type: cty.String, Required: false},
 		"reboot":                       &hcldec.AttrSpec{Name: "reboot", Type: cty.Bool, Required: false},
+		"shutdown_behavior":            &hcldec.AttrSpec{Name: "shutdown_behavior", Type: cty.String, Required: false},
+		"shutdown_command":             &hcldec.AttrSpec{Name: "shutdown_command", Type: cty.String, Required: false},
+		"shutdown_timeout":             &hcldec.AttrSpec{Name: "shutdown_timeout", Type: cty.String, Required: false},
+		"stop_type":                    &hcldec.AttrSpec{Name: "stop_type", Type: cty.String, Required: false},
 		"force_poweroff":               &hcldec.AttrSpec{Name: "force_poweroff", Type: cty.Bool, Required: false},
 		"sysprep":                      &hcldec.AttrSpec{Name: "sysprep", Type: cty.Bool, Required: false},
 		"image_force_delete":           &hcldec.AttrSpec{Name: "image_force_delete", Type: cty.Bool, Required: false},
 		"image_copy_regions":           &hcldec.AttrSpec{Name: "image_copy_regions", Type: cty.List(cty.String), Required: false},
+		"image_copy_encrypted":         &hcldec.AttrSpec{Name: "image_copy_encrypted", Type: cty.Bool, Required: false},
+		"image_copy_kms_key_ids":       &hcldec.AttrSpec{Name: "image_copy_kms_key_ids", Type: cty.Map(cty.String), Required: false},
 		"image_share_accounts":         &hcldec.AttrSpec{Name: "image_share_accounts", Type: cty.List(cty.String), Required: false},
 		"image_tags":                   &hcldec.AttrSpec{Name: "image_tags", Type: cty.Map(cty.String), Required: false},
 		"skip_if_exists":               &hcldec.AttrSpec{Name: "skip_if_exists", Type: cty.Bool, Required: false},
 		"associate_public_ip_address":  &hcldec.AttrSpec{Name: "associate_public_ip_address", Type: cty.Bool, Required: false},
+		"associate_eip":                &hcldec.AttrSpec{Name: "associate_eip", Type: cty.Bool, Required: false},
+		"eip_ids":                      &hcldec.AttrSpec{Name: "eip_ids", Type: cty.List(cty.String), Required: false},
+		"eip_addresses":                &hcldec.AttrSpec{Name: "eip_addresses", Type: cty.List(cty.String), Required: false},
 		"source_image_id":              &hcldec.AttrSpec{Name: "source_image_id", Type: cty.String, Required: false},
 		"source_image_name":            &hcldec.AttrSpec{Name: "source_image_name", Type: cty.String, Required: false},
 		"instance_charge_type":         &hcldec.AttrSpec{Name: "instance_charge_type", Type: cty.String, Required: false},
@@ -165,12 +211,16 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"instance_name":                &hcldec.AttrSpec{Name: "instance_name", Type: cty.String, Required: false},
 		"disk_type":                    &hcldec.AttrSpec{Name: "disk_type", Type: cty.String, Required: false},
 		"disk_size":                    &hcldec.AttrSpec{Name: "disk_size", Type: cty.Number, Required: false},
+		"disk_encrypted":               &hcldec.AttrSpec{Name: "disk_encrypted", Type: cty.Bool, Required: false},
+		"disk_kms_key_id":              &hcldec.AttrSpec{Name: "disk_kms_key_id", Type: cty.String, Required: false},
 		"data_disks":                   &hcldec.BlockListSpec{TypeName: "data_disks", Nested: hcldec.ObjectSpec((*FlattencentCloudDataDisk)(nil).HCL2Spec())},
 		"vpc_id":                       &hcldec.AttrSpec{Name: "vpc_id", Type: cty.String, Required: false},
 		"vpc_name":                     &hcldec.AttrSpec{Name: "vpc_name", Type: cty.String, Required: false},
 		"vpc_ip":                       &hcldec.AttrSpec{Name: "vpc_ip", Type: cty.String, Required: false},
+		"vpc_ip_candidates":            &hcldec.AttrSpec{Name: "vpc_ip_candidates", Type: cty.List(cty.String), Required: false},
 		"subnet_id":                    &hcldec.AttrSpec{Name: "subnet_id", Type: cty.String, Required: false},
 		"subnet_name":                  &hcldec.AttrSpec{Name: "subnet_name", Type: cty.String, Required: false},
+		"enable_ipv6":                  &hcldec.AttrSpec{Name: "enable_ipv6", Type: cty.Bool, Required: false},
 		"cidr_block":                   &hcldec.AttrSpec{Name: "cidr_block", Type: cty.String, Required: false},
 		"subnect_cidr_block":           &hcldec.AttrSpec{Name: "subnect_cidr_block", Type: cty.String, Required: false},
 		"internet_charge_type":         &hcldec.AttrSpec{Name: "internet_charge_type", Type: cty.String, Required: false},
@@ -180,8 +230,12 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"security_group_name":          &hcldec.AttrSpec{Name: "security_group_name", Type: cty.String, Required: false},
 		"user_data":                    &hcldec.AttrSpec{Name: "user_data", Type: cty.String, Required: false},
 		"user_data_file":               &hcldec.AttrSpec{Name: "user_data_file", Type: cty.String, Required: false},
+		"user_data_parts":              &hcldec.BlockListSpec{TypeName: "user_data_parts", Nested: hcldec.ObjectSpec((*FlattencentCloudUserDataPart)(nil).HCL2Spec())},
 		"host_name":                    &hcldec.AttrSpec{Name: "host_name", Type: cty.String, Required: false},
+		"cam_role_name":                &hcldec.AttrSpec{Name: "cam_role_name", Type: cty.String, Required: false},
+		"detach_cam_role":              &hcldec.AttrSpec{Name: "detach_cam_role", Type: cty.Bool, Required: false},
 		"run_tags":                     &hcldec.AttrSpec{Name: "run_tags", Type: cty.Map(cty.String), Required: false},
+		"default_tags":                 &hcldec.AttrSpec{Name: "default_tags", Type: cty.Map(cty.String), Required: false},
 		"run_tag":                      &hcldec.BlockListSpec{TypeName: "run_tag", Nested: hcldec.ObjectSpec((*config.FlatKeyValue)(nil).HCL2Spec())},
 		"communicator":                 &hcldec.AttrSpec{Name: "communicator", Type: cty.String, Required: false},
 		"pause_before_connecting":      &hcldec.AttrSpec{Name: "pause_before_connecting", Type: cty.String, Required: false},
@@ -233,11 +287,17 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 		"winrm_insecure":               &hcldec.AttrSpec{Name: "winrm_insecure", Type: cty.Bool, Required: false},
 		"winrm_use_ntlm":               &hcldec.AttrSpec{Name: "winrm_use_ntlm", Type: cty.Bool, Required: false},
 		"ssh_private_ip":               &hcldec.AttrSpec{Name: "ssh_private_ip", Type: cty.Bool, Required: false},
+		"ssh_interface":                &hcldec.AttrSpec{Name: "ssh_interface", Type: cty.String, Required: false},
+		"ssh_interface_timeout":        &hcldec.AttrSpec{Name: "ssh_interface_timeout", Type: cty.String, Required: false},
 		"skip_create_image":            &hcldec.AttrSpec{Name: "skip_create_image", Type: cty.Bool, Required: false},
+		"os_type":                      &hcldec.AttrSpec{Name: "os_type", Type: cty.String, Required: false},
 		"disable_security_service":     &hcldec.AttrSpec{Name: "disable_security_service", Type: cty.Bool, Required: false},
 		"disable_monitor_service":      &hcldec.AttrSpec{Name: "disable_monitor_service", Type: cty.Bool, Required: false},
 		"disable_automation_service":   &hcldec.AttrSpec{Name: "disable_automation_service", Type: cty.Bool, Required: false},
 		"placement_group_id":           &hcldec.AttrSpec{Name: "placement_group_id", Type: cty.String, Required: false},
+		"placement":                    &hcldec.BlockSpec{TypeName: "placement", Nested: hcldec.ObjectSpec((*FlattencentCloudPlacement)(nil).HCL2Spec())},
+		"launch_template_id":           &hcldec.AttrSpec{Name: "launch_template_id", Type: cty.String, Required: false},
+		"launch_template_version":      &hcldec.AttrSpec{Name: "launch_template_version", Type: cty.Number, Required: false},
 		"skip_region_validation":       &hcldec.AttrSpec{Name: "skip_region_validation", Type: cty.Bool, Required: false},
 	}
 	return s
@@ -0,0 +1,146 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"strings"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/mockapi"
+)
+
+// runBuild runs a build of the mock api server with the none communicator,
+// config overrides the default settings.
+func runBuild(t *testing.T, server *mockapi.Server, secretKey string, config map[string]interface{}) (packersdk.Artifact, error) {
+	settings := map[string]interface{}{
+		"secret_key":               secretKey,
+		"zone":                     "ap-guangzhou-3",
+		"image_name":               "packer-test",
+		"source_image_id":          "img-source01",
+		"instance_type_candidates": []string{"S5.MEDIUM2", "SA2.MEDIUM2"},
+		"communicator":             "none",
+		"image_copy_regions":       []string{"ap-guangzhou", "ap-shanghai"},
+	}
+	for k, v := range config {
+		settings[k] = v
+	}
+	return server.Build(t, &Builder{}, nil, settings)
+}
+
+func TestBuilder_Run(t *testing.T) {
+	cases := []struct {
+		name   string
+		faults []mockapi.Fault
+		config map[string]interface{}
+		// wrongKey signs requests with a wrong secret key
+		wrongKey bool
+		err      string
+		check    func(t *testing.T, server *mockapi.Server)
+	}{
+		{
+			name: "success",
+			check: func(t *testing.T, server *mockapi.Server) {
+				if actions := server.ActionsOf("StopInstances", "CreateImage"); actions != "StopInstances,CreateImage" {
+					t.Errorf("expected instance stopped before creating image, got %s", actions)
+				}
+			},
+		},
+		{
+			name:   "live",
+			config: map[string]interface{}{"shutdown_behavior": "live"},
+			check: func(t *testing.T, server *mockapi.Server) {
+				// the instance is stopped to detach the temporary keypair
+				if actions := server.ActionsOf("StopInstances", "CreateImage"); actions != "CreateImage,StopInstances" {
+					t.Errorf("expected image created from running instance, got %s", actions)
+				}
+			},
+		},
+		{
+			name:   "sold out",
+			faults: []mockapi.Fault{{Action: "RunInstances", Code: mockapi.CodeSoldOut, Times: 1}},
+			check: func(t *testing.T, server *mockapi.Server) {
+				if n := server.Calls("RunInstances"); n != 2 {
+					t.Errorf("expected next candidate tried, got %d RunInstances", n)
+				}
+			},
+		},
+		{
+			name:   "launch failed",
+			faults: []mockapi.Fault{{Action: "RunInstances", Code: mockapi.LaunchFailed, Times: 1}},
+			check: func(t *testing.T, server *mockapi.Server) {
+				if n := server.Calls("TerminateInstances"); n != 2 {
+					t.Errorf("expected failed instance terminated, got %d TerminateInstances", n)
+				}
+			},
+		},
+		{
+			name:   "rate limit",
+			faults: []mockapi.Fault{{Action: "DescribeInstances", Code: mockapi.CodeRateLimit, Times: 3}},
+			check: func(t *testing.T, server *mockapi.Server) {
+				if n := server.Failures("DescribeInstances", mockapi.CodeRateLimit); n != 3 {
+					t.Errorf("expected 3 rate limited requests, got %d", n)
+				}
+			},
+		},
+		{
+			name:   "quota",
+			faults: []mockapi.Fault{{Action: "RunInstances", Code: mockapi.CodeQuota}},
+			err:    "tried 2 configurations but no luck",
+		},
+		{
+			name:     "wrong secret key",
+			wrongKey: true,
+			err:      "AuthFailure.SignatureFailure",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := mockapi.NewServer("AKIDmock", "mock-secret")
+			defer server.Close()
+			server.AddImage("ap-guangzhou", "img-source01", "TencentOS Server 3.1")
+			for _, f := range tc.faults {
+				server.Inject(f)
+			}
+
+			secretKey := server.SecretKey
+			if tc.wrongKey {
+				secretKey = "wrong-secret"
+			}
+			artifact, err := runBuild(t, server, secretKey, tc.config)
+
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error %q, got %v", tc.err, err)
+				}
+				server.AssertNoLeaks(t, "ap-guangzhou", "image")
+				if n := len(server.Images("ap-guangzhou")); n != 1 {
+					t.Errorf("expected only source image left, got %d images", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+
+			images := artifact.(*Artifact).TencentCloudImages
+			for _, region := range []string{"ap-guangzhou", "ap-shanghai"} {
+				found := false
+				for _, img := range server.Images(region) {
+					if *img.ImageId == images[region] && *img.ImageName == "packer-test" && *img.ImageState == "NORMAL" {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("expected image %q of artifact in %s", images[region], region)
+				}
+				server.AssertNoLeaks(t, region, "image")
+			}
+			if tc.check != nil {
+				tc.check(t, server)
+			}
+		})
+	}
+}
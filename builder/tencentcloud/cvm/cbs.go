@@ -0,0 +1,347 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
+)
+
+// cbs is not vendored, so requests and responses are defined here.
+const (
+	cbsService = "cbs"
+	cbsVersion = "2017-03-12"
+)
+
+type cbsDiskConfig struct {
+	Available *bool   `json:"Available"`
+	DiskType  *string `json:"DiskType"`
+	DiskUsage *string `json:"DiskUsage"`
+	Zone      *string `json:"Zone"`
+}
+
+type describeDiskConfigQuotaResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		DiskConfigSet []*cbsDiskConfig `json:"DiskConfigSet"`
+		RequestId     *string          `json:"RequestId"`
+	} `json:"Response"`
+}
+
+// IsLocalDiskType returns whether diskType is a local disk, which is not
+// sold by cbs.
+func IsLocalDiskType(diskType string) bool {
+	return strings.HasPrefix(diskType, "LOCAL_")
+}
+
+// GetAvailableDiskTypes returns the cloud disk types available in zone for
+// instances of instanceChargeType, diskUsage can be `SYSTEM_DISK` or `DATA_DISK`.
+func GetAvailableDiskTypes(ctx context.Context, client APISender, zone string, instanceChargeType string, diskUsage string) (map[string]bool, error) {
+	// disks of instances are charged as the instances, except that disks of
+	// spot and dedicated host instances are postpaid
+	diskChargeType := "POSTPAID_BY_HOUR"
+	if instanceChargeType == "PREPAID" {
+		diskChargeType = "PREPAID"
+	}
+	params := map[string]interface{}{
+		"InquiryType":    "INQUIRY_CBS_CONFIG",
+		"Zones":          []string{zone},
+		"DiskChargeType": diskChargeType,
+		"DiskUsage":      diskUsage,
+	}
+	resp := &describeDiskConfigQuotaResponse{BaseResponse: &tchttp.BaseResponse{}}
+	if err := sendCbs(ctx, client, "DescribeDiskConfigQuota", params, resp); err != nil {
+		return nil, err
+	}
+
+	diskTypes := make(map[string]bool)
+	for _, diskConfig := range resp.Response.DiskConfigSet {
+		if diskConfig.Available != nil && *diskConfig.Available && diskConfig.DiskType != nil {
+			diskTypes[*diskConfig.DiskType] = true
+		}
+	}
+
+	return diskTypes, nil
+}
+
+// CbsDisk is a cloud disk got by DescribeDisks
+type CbsDisk struct {
+	DiskId     *string `json:"DiskId"`
+	DiskName   *string `json:"DiskName"`
+	DiskType   *string `json:"DiskType"`
+	DiskUsage  *string `json:"DiskUsage"`
+	DiskSize   *int64  `json:"DiskSize"`
+	DiskState  *string `json:"DiskState"`
+	Attached   *bool   `json:"Attached"`
+	InstanceId *string `json:"InstanceId"`
+	Placement  *struct {
+		Zone *string `json:"Zone"`
+	} `json:"Placement"`
+}
+
+// CbsSnapshot is a snapshot got by DescribeSnapshots
+type CbsSnapshot struct {
+	SnapshotId    *string `json:"SnapshotId"`
+	SnapshotName  *string `json:"SnapshotName"`
+	SnapshotState *string `json:"SnapshotState"`
+	DiskId        *string `json:"DiskId"`
+	DiskUsage     *string `json:"DiskUsage"`
+	DiskSize      *int64  `json:"DiskSize"`
+}
+
+// CbsDiskOptions are the options of a disk created by CreateDisk
+type CbsDiskOptions struct {
+	Zone       string
+	DiskType   string
+	DiskSize   int64
+	DiskName   string
+	SnapshotId string
+}
+
+type createDisksResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		DiskIdSet []*string `json:"DiskIdSet"`
+		RequestId *string   `json:"RequestId"`
+	} `json:"Response"`
+}
+
+type describeDisksResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		TotalCount *int64     `json:"TotalCount"`
+		DiskSet    []*CbsDisk `json:"DiskSet"`
+		RequestId  *string    `json:"RequestId"`
+	} `json:"Response"`
+}
+
+type createSnapshotResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		SnapshotId *string `json:"SnapshotId"`
+		RequestId  *string `json:"RequestId"`
+	} `json:"Response"`
+}
+
+type describeSnapshotsResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		TotalCount  *int64         `json:"TotalCount"`
+		SnapshotSet []*CbsSnapshot `json:"SnapshotSet"`
+		RequestId   *string        `json:"RequestId"`
+	} `json:"Response"`
+}
+
+type cbsResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		RequestId *string `json:"RequestId"`
+	} `json:"Response"`
+}
+
+// sendCbs sends the cbs action with params, and retries on the retryable
+// errors
+func sendCbs(ctx context.Context, client APISender, action string, params map[string]interface{}, response tchttp.Response) error {
+	req := tchttp.NewCommonRequest(cbsService, cbsVersion, action)
+	if err := req.SetActionParameters(params); err != nil {
+		return err
+	}
+	return Retry(ctx, func(ctx context.Context) error {
+		req.SetContext(ctx)
+		return client.Send(req, response)
+	})
+}
+
+// CreateDisk creates a pay-as-you-go disk, and returns its id. The client
+// token makes the retries idempotent.
+func CreateDisk(ctx context.Context, client APISender, opts *CbsDiskOptions) (string, error) {
+	params := map[string]interface{}{
+		"Placement":      map[string]interface{}{"Zone": opts.Zone},
+		"DiskChargeType": "POSTPAID_BY_HOUR",
+		"DiskType":       opts.DiskType,
+		"DiskSize":       opts.DiskSize,
+		"DiskCount":      1,
+		"ClientToken":    uuid.TimeOrderedUUID(),
+	}
+	if opts.DiskName != "" {
+		params["DiskName"] = opts.DiskName
+	}
+	if opts.SnapshotId != "" {
+		params["SnapshotId"] = opts.SnapshotId
+	}
+
+	resp := &createDisksResponse{BaseResponse: &tchttp.BaseResponse{}}
+	if err := sendCbs(ctx, client, "CreateDisks", params, resp); err != nil {
+		return "", err
+	}
+	if len(resp.Response.DiskIdSet) == 0 {
+		return "", fmt.Errorf("no disk created")
+	}
+	return *resp.Response.DiskIdSet[0], nil
+}
+
+// DescribeDisk returns the disk, or nil if it doesn't exist
+func DescribeDisk(ctx context.Context, client APISender, diskId string) (*CbsDisk, error) {
+	resp := &describeDisksResponse{BaseResponse: &tchttp.BaseResponse{}}
+	err := sendCbs(ctx, client, "DescribeDisks", map[string]interface{}{"DiskIds": []string{diskId}}, resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Response.DiskSet) == 0 {
+		return nil, nil
+	}
+	return resp.Response.DiskSet[0], nil
+}
+
+// WaitForDisk wait for disk reaches state
+func WaitForDisk(ctx context.Context, client APISender, diskId string, state string, timeout int) (*CbsDisk, error) {
+	for {
+		disk, err := DescribeDisk(ctx, client, diskId)
+		if err != nil {
+			return nil, err
+		}
+		if disk == nil {
+			return nil, fmt.Errorf("disk(%s) not exist", diskId)
+		}
+		if disk.DiskState != nil && *disk.DiskState == state {
+			return disk, nil
+		}
+		time.Sleep(DefaultWaitForInterval * time.Second)
+		timeout = timeout - DefaultWaitForInterval
+		if timeout <= 0 {
+			return nil, fmt.Errorf("wait disk(%s) state(%s) timeout", diskId, state)
+		}
+	}
+}
+
+// AttachDisk attaches disk to instance
+func AttachDisk(ctx context.Context, client APISender, diskId, instanceId string) error {
+	params := map[string]interface{}{
+		"DiskIds":    []string{diskId},
+		"InstanceId": instanceId,
+	}
+	return sendCbs(ctx, client, "AttachDisks", params, &cbsResponse{BaseResponse: &tchttp.BaseResponse{}})
+}
+
+// DetachDisk detaches disk from the instance it is attached to
+func DetachDisk(ctx context.Context, client APISender, diskId string) error {
+	params := map[string]interface{}{"DiskIds": []string{diskId}}
+	return sendCbs(ctx, client, "DetachDisks", params, &cbsResponse{BaseResponse: &tchttp.BaseResponse{}})
+}
+
+// TerminateDisk terminates the disk, which must be detached
+func TerminateDisk(ctx context.Context, client APISender, diskId string) error {
+	params := map[string]interface{}{"DiskIds": []string{diskId}}
+	return sendCbs(ctx, client, "TerminateDisks", params, &cbsResponse{BaseResponse: &tchttp.BaseResponse{}})
+}
+
+// CreateSnapshot creates a snapshot of disk, and returns its id
+func CreateSnapshot(ctx context.Context, client APISender, diskId, name string) (string, error) {
+	params := map[string]interface{}{"DiskId": diskId}
+	if name != "" {
+		params["SnapshotName"] = name
+	}
+	resp := &createSnapshotResponse{BaseResponse: &tchttp.BaseResponse{}}
+	if err := sendCbs(ctx, client, "CreateSnapshot", params, resp); err != nil {
+		return "", err
+	}
+	return *resp.Response.SnapshotId, nil
+}
+
+// DescribeSnapshot returns the snapshot, or nil if it doesn't exist
+func DescribeSnapshot(ctx context.Context, client APISender, snapshotId string) (*CbsSnapshot, error) {
+	resp := &describeSnapshotsResponse{BaseResponse: &tchttp.BaseResponse{}}
+	err := sendCbs(ctx, client, "DescribeSnapshots", map[string]interface{}{"SnapshotIds": []string{snapshotId}}, resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Response.SnapshotSet) == 0 {
+		return nil, nil
+	}
+	return resp.Response.SnapshotSet[0], nil
+}
+
+// WaitForSnapshot wait for snapshot reaches state
+func WaitForSnapshot(ctx context.Context, client APISender, snapshotId string, state string, timeout int) (*CbsSnapshot, error) {
+	for {
+		snapshot, err := DescribeSnapshot(ctx, client, snapshotId)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot == nil {
+			return nil, fmt.Errorf("snapshot(%s) not exist", snapshotId)
+		}
+		if snapshot.SnapshotState != nil && *snapshot.SnapshotState == state {
+			return snapshot, nil
+		}
+		time.Sleep(DefaultWaitForInterval * time.Second)
+		timeout = timeout - DefaultWaitForInterval
+		if timeout <= 0 {
+			return nil, fmt.Errorf("wait snapshot(%s) state(%s) timeout", snapshotId, state)
+		}
+	}
+}
+
+// DeleteSnapshot deletes the snapshot
+func DeleteSnapshot(ctx context.Context, client APISender, snapshotId string) error {
+	params := map[string]interface{}{"SnapshotIds": []string{snapshotId}}
+	return sendCbs(ctx, client, "DeleteSnapshots", params, &cbsResponse{BaseResponse: &tchttp.BaseResponse{}})
+}
+
+type copySnapshotCrossRegionsResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		SnapshotCopyResultSet []*struct {
+			SnapshotId        *string `json:"SnapshotId"`
+			Message           *string `json:"Message"`
+			Code              *string `json:"Code"`
+			DestinationRegion *string `json:"DestinationRegion"`
+		} `json:"SnapshotCopyResultSet"`
+		RequestId *string `json:"RequestId"`
+	} `json:"Response"`
+}
+
+// CopySnapshot copies the snapshot to regions with name, and returns the ids
+// of the copied snapshots keyed by region
+func CopySnapshot(ctx context.Context, client APISender, snapshotId string, regions []string, name string) (map[string]string, error) {
+	params := map[string]interface{}{
+		"SnapshotId":         snapshotId,
+		"DestinationRegions": regions,
+	}
+	if name != "" {
+		params["SnapshotName"] = name
+	}
+	resp := &copySnapshotCrossRegionsResponse{BaseResponse: &tchttp.BaseResponse{}}
+	if err := sendCbs(ctx, client, "CopySnapshotCrossRegions", params, resp); err != nil {
+		return nil, err
+	}
+
+	copied := make(map[string]string)
+	var errs []string
+	for _, result := range resp.Response.SnapshotCopyResultSet {
+		if result.DestinationRegion == nil {
+			continue
+		}
+		if result.Code != nil && *result.Code != "Success" {
+			message := *result.Code
+			if result.Message != nil {
+				message = *result.Message
+			}
+			errs = append(errs, fmt.Sprintf("%s: %s", *result.DestinationRegion, message))
+			continue
+		}
+		if result.SnapshotId != nil {
+			copied[*result.DestinationRegion] = *result.SnapshotId
+		}
+	}
+	if len(errs) > 0 {
+		return copied, fmt.Errorf("failed to copy snapshot(%s) to %s", snapshotId, strings.Join(errs, ", "))
+	}
+	return copied, nil
+}
@@ -0,0 +1,234 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
+)
+
+const (
+	// InternalRootDomain is the root domain of the api endpoints reachable
+	// from the vpcs without internet access
+	InternalRootDomain = "internal.tencentcloudapi.com"
+
+	// DefaultRequestTimeout is the timeout of an api request
+	DefaultRequestTimeout = 300 * time.Second
+)
+
+// The services called by the plugin, which are the keys of endpoints
+const (
+	cvmService = "cvm"
+	vpcService = "vpc"
+)
+
+// NewCvmClient returns a new cvm client of region
+func NewCvmClient(cf *TencentCloudAccessConfig, region string) (*cvm.Client, error) {
+	credential, cpf, transport, err := cf.clientOptions(cvmService)
+	if err != nil {
+		return nil, err
+	}
+	client, err := cvm.NewClient(credential, region, cpf)
+	if err != nil {
+		return nil, err
+	}
+	client.WithHttpTransport(transport)
+	return client, nil
+}
+
+// NewVpcClient returns a new vpc client of region
+func NewVpcClient(cf *TencentCloudAccessConfig, region string) (*vpc.Client, error) {
+	credential, cpf, transport, err := cf.clientOptions(vpcService)
+	if err != nil {
+		return nil, err
+	}
+	client, err := vpc.NewClient(credential, region, cpf)
+	if err != nil {
+		return nil, err
+	}
+	client.WithHttpTransport(transport)
+	return client, nil
+}
+
+// NewCommonClient returns a new client of region, which is used to call the
+// services not vendored, such as cbs and tag. Requests are sent with the
+// endpoint of their own service.
+func NewCommonClient(cf *TencentCloudAccessConfig, region string) APISender {
+	return &commonClient{
+		config:  cf,
+		region:  region,
+		clients: make(map[string]*common.Client),
+	}
+}
+
+type commonClient struct {
+	config *TencentCloudAccessConfig
+	region string
+
+	mu      sync.Mutex
+	clients map[string]*common.Client
+}
+
+func (c *commonClient) Send(request tchttp.Request, response tchttp.Response) error {
+	client, err := c.client(request.GetService())
+	if err != nil {
+		return err
+	}
+	return client.Send(request, response)
+}
+
+// client returns the client of service, which is created on first use
+func (c *commonClient) client(service string) (*common.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.clients[service]; ok {
+		return client, nil
+	}
+
+	credential, cpf, transport, err := c.config.clientOptions(service)
+	if err != nil {
+		return nil, err
+	}
+	client := common.NewCommonClient(credential, c.region, cpf)
+	client.WithHttpTransport(transport)
+	c.clients[service] = client
+	return client, nil
+}
+
+// clientOptions returns the credential, profile and transport of the
+// clients of service.
+func (cf *TencentCloudAccessConfig) clientOptions(service string) (*common.Credential, *profile.ClientProfile,
+	http.RoundTripper, error) {
+	cpf := profile.NewClientProfile()
+	cpf.HttpProfile.ReqMethod = "POST"
+	cpf.Language = "en-US"
+
+	timeout := cf.RequestTimeout
+	if timeout <= 0 {
+		timeout = DefaultRequestTimeout
+	}
+	cpf.HttpProfile.ReqTimeout = int(math.Ceil(timeout.Seconds()))
+
+	if cf.UseInternalEndpoint {
+		cpf.HttpProfile.RootDomain = InternalRootDomain
+	}
+	if endpoint := cf.endpoint(service); endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid %s endpoint %s: %s", service, endpoint, err)
+		}
+		if u.Scheme != "" {
+			cpf.HttpProfile.Scheme = u.Scheme
+			cpf.HttpProfile.Endpoint = u.Host
+		} else {
+			cpf.HttpProfile.Endpoint = endpoint
+		}
+	}
+
+	transport, err := cf.httpTransport()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return common.NewCredential(cf.SecretId, cf.SecretKey), cpf, newApiTransport(transport), nil
+}
+
+// endpoint returns the endpoint of service, cvm_endpoint and vpc_endpoint
+// take precedence over endpoints
+func (cf *TencentCloudAccessConfig) endpoint(service string) string {
+	switch {
+	case service == cvmService && cf.CvmEndpoint != "":
+		return cf.CvmEndpoint
+	case service == vpcService && cf.VpcEndpoint != "":
+		return cf.VpcEndpoint
+	}
+	return cf.Endpoints[service]
+}
+
+// httpTransport returns the transport with the proxy and ca certificates,
+// http.DefaultTransport is used if neither is set.
+func (cf *TencentCloudAccessConfig) httpTransport() (http.RoundTripper, error) {
+	if cf.HttpProxy == "" && cf.CACertFile == "" {
+		return http.DefaultTransport, nil
+	}
+
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("unexpected default transport %T", http.DefaultTransport)
+	}
+	transport := base.Clone()
+
+	if cf.HttpProxy != "" {
+		proxy, err := parseProxy(cf.HttpProxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if cf.CACertFile != "" {
+		pool, err := loadCACerts(cf.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	return transport, nil
+}
+
+// SensitiveValues returns the credential and the proxy password, which are
+// never logged
+func (cf *TencentCloudAccessConfig) SensitiveValues() []string {
+	values := []string{cf.SecretId, cf.SecretKey}
+	if proxy, err := parseProxy(cf.HttpProxy); err == nil && proxy.User != nil {
+		if password, ok := proxy.User.Password(); ok {
+			values = append(values, password)
+		}
+	}
+	return values
+}
+
+func parseProxy(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid http_proxy %s: %s", proxy, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid http_proxy %s: scheme and host are required", proxy)
+	}
+	return u, nil
+}
+
+// loadCACerts returns the system certificates with the ones in file added
+func loadCACerts(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ca_cert_file: %s", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in ca_cert_file %s", file)
+	}
+	return pool, nil
+}
@@ -0,0 +1,143 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/mockapi"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+func TestTencentCloudAccessConfig_clientOptions(t *testing.T) {
+	cf := &TencentCloudAccessConfig{
+		SecretId:            "secret-id",
+		SecretKey:           "secret-key",
+		CvmEndpoint:         "https://cvm.example.com",
+		Endpoints:           map[string]string{"vpc": "vpc.example.com", "cbs": "http://cbs.example.com:8080"},
+		UseInternalEndpoint: true,
+		RequestTimeout:      1500 * time.Millisecond,
+	}
+
+	cases := []struct {
+		service  string
+		scheme   string
+		endpoint string
+	}{
+		{service: "cvm", scheme: "https", endpoint: "cvm.example.com"},
+		{service: "vpc", scheme: "HTTPS", endpoint: "vpc.example.com"},
+		{service: "cbs", scheme: "http", endpoint: "cbs.example.com:8080"},
+		{service: "tag", scheme: "HTTPS", endpoint: ""},
+	}
+	for _, tc := range cases {
+		_, cpf, _, err := cf.clientOptions(tc.service)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.service, err)
+		}
+		if cpf.HttpProfile.Scheme != tc.scheme || cpf.HttpProfile.Endpoint != tc.endpoint {
+			t.Errorf("%s: expected endpoint %s://%s, got %s://%s", tc.service, tc.scheme, tc.endpoint,
+				cpf.HttpProfile.Scheme, cpf.HttpProfile.Endpoint)
+		}
+		if cpf.HttpProfile.RootDomain != InternalRootDomain {
+			t.Errorf("%s: expected root domain %s, got %s", tc.service, InternalRootDomain, cpf.HttpProfile.RootDomain)
+		}
+		if cpf.HttpProfile.ReqTimeout != 2 {
+			t.Errorf("%s: expected timeout rounded up to 2s, got %d", tc.service, cpf.HttpProfile.ReqTimeout)
+		}
+	}
+}
+
+func TestTencentCloudAccessConfig_Prepare_endpoints(t *testing.T) {
+	cf := TencentCloudAccessConfig{
+		SecretId:    "secret-id",
+		SecretKey:   "secret-key",
+		Region:      "ap-guangzhou",
+		CvmEndpoint: "cvm.example.com",
+		VpcEndpoint: "vpc.example.com",
+		Endpoints:   map[string]string{"cvm": "other.example.com"},
+	}
+	if errs := cf.Prepare(nil); len(errs) == 0 {
+		t.Fatal("should raise error: cvm_endpoint conflicts with endpoints.cvm")
+	}
+
+	cf.Endpoints = map[string]string{"cvm": "cvm.example.com"}
+	cf.HttpProxy = "proxy.example.com:3128"
+	if errs := cf.Prepare(nil); len(errs) == 0 {
+		t.Fatal("should raise error: http_proxy without scheme")
+	}
+
+	cf.HttpProxy = "http://proxy.example.com:3128"
+	cf.CACertFile = filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(cf.CACertFile, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if errs := cf.Prepare(nil); len(errs) == 0 {
+		t.Fatal("should raise error: no certificate in ca_cert_file")
+	}
+
+	cf.CACertFile = ""
+	if errs := cf.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("shouldn't raise error: %v", errs)
+	}
+	if cf.RequestTimeout != DefaultRequestTimeout {
+		t.Fatalf("expected default request timeout, got %s", cf.RequestTimeout)
+	}
+}
+
+func TestNewCvmClient_proxy(t *testing.T) {
+	server := mockapi.NewServer("AKIDmock", "mock-secret")
+	defer server.Close()
+
+	// the server serves the requests proxied to the plain http endpoint
+	cf := &TencentCloudAccessConfig{
+		SecretId:    server.SecretId,
+		SecretKey:   server.SecretKey,
+		CvmEndpoint: "http://cvm.tencentcloudapi.com",
+		HttpProxy:   server.URL,
+	}
+	client, err := NewCvmClient(cf, "ap-guangzhou")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DescribeZones(cvm.NewDescribeZonesRequest()); err != nil {
+		t.Fatalf("expected request sent through proxy, got %s", err)
+	}
+	if n := server.Calls("DescribeZones"); n != 1 {
+		t.Fatalf("expected 1 DescribeZones through proxy, got %d", n)
+	}
+}
+
+func TestNewCommonClient_caCertFile(t *testing.T) {
+	server := mockapi.NewTLSServer("AKIDmock", "mock-secret")
+	defer server.Close()
+
+	cf := &TencentCloudAccessConfig{
+		SecretId:  server.SecretId,
+		SecretKey: server.SecretKey,
+		Endpoints: map[string]string{"sts": server.URL},
+	}
+	if _, err := GetOwnerUin(context.Background(), NewCommonClient(cf, "ap-guangzhou")); err == nil ||
+		!strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("expected untrusted certificate error, got %v", err)
+	}
+
+	cf.CACertFile = filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(cf.CACertFile, certificate, 0644); err != nil {
+		t.Fatal(err)
+	}
+	uin, err := GetOwnerUin(context.Background(), NewCommonClient(cf, "ap-guangzhou"))
+	if err != nil {
+		t.Fatalf("expected certificate trusted, got %s", err)
+	}
+	if uin != server.AccountId {
+		t.Fatalf("expected uin %s, got %s", server.AccountId, uin)
+	}
+}
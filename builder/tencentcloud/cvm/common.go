@@ -5,8 +5,10 @@ package cvm
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"net/url"
+	"log"
+	"net"
 	"regexp"
 	"strings"
 	"time"
@@ -16,7 +18,7 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/retry"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
 	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
 	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
 )
@@ -25,7 +27,7 @@ import (
 const DefaultWaitForInterval = 5
 
 // WaitForInstance wait for instance reaches statue
-func WaitForInstance(ctx context.Context, client *cvm.Client, instanceId string, status string, timeout int) error {
+func WaitForInstance(ctx context.Context, client CVMAPI, instanceId string, status string, timeout int) error {
 	req := cvm.NewDescribeInstancesRequest()
 	req.InstanceIds = []*string{&instanceId}
 
@@ -33,7 +35,7 @@ func WaitForInstance(ctx context.Context, client *cvm.Client, instanceId string,
 		var resp *cvm.DescribeInstancesResponse
 		err := Retry(ctx, func(ctx context.Context) error {
 			var e error
-			resp, e = client.DescribeInstances(req)
+			resp, e = client.DescribeInstancesWithContext(ctx, req)
 			return e
 		})
 		if err != nil {
@@ -42,6 +44,10 @@ func WaitForInstance(ctx context.Context, client *cvm.Client, instanceId string,
 		if *resp.Response.TotalCount == 0 {
 			return fmt.Errorf("instance(%s) not exist", instanceId)
 		}
+		// the instance never becomes running once it failed to launch
+		if *resp.Response.InstanceSet[0].InstanceState == "LAUNCH_FAILED" && status != "LAUNCH_FAILED" {
+			return fmt.Errorf("instance(%s) launch failed", instanceId)
+		}
 		if *resp.Response.InstanceSet[0].InstanceState == status &&
 			(resp.Response.InstanceSet[0].LatestOperationState == nil ||
 				*resp.Response.InstanceSet[0].LatestOperationState != "OPERATING") {
@@ -57,8 +63,35 @@ func WaitForInstance(ctx context.Context, client *cvm.Client, instanceId string,
 	return nil
 }
 
+// WaitForInstanceTerminated wait for instance disappears after terminated,
+// resources like subnet and security group are in use until then
+func WaitForInstanceTerminated(ctx context.Context, client CVMAPI, instanceId string, timeout int) error {
+	req := cvm.NewDescribeInstancesRequest()
+	req.InstanceIds = []*string{&instanceId}
+
+	for {
+		var resp *cvm.DescribeInstancesResponse
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			resp, e = client.DescribeInstancesWithContext(ctx, req)
+			return e
+		})
+		if err != nil {
+			return err
+		}
+		if *resp.Response.TotalCount == 0 {
+			return nil
+		}
+		time.Sleep(DefaultWaitForInterval * time.Second)
+		timeout = timeout - DefaultWaitForInterval
+		if timeout <= 0 {
+			return fmt.Errorf("wait instance(%s) terminated timeout", instanceId)
+		}
+	}
+}
+
 // WaitForImageReady wait for image reaches statue
-func WaitForImageReady(ctx context.Context, client *cvm.Client, imageName string, status string, timeout int) error {
+func WaitForImageReady(ctx context.Context, client CVMAPI, imageName string, status string, timeout int) error {
 	for {
 		image, err := GetImageByName(ctx, client, imageName)
 		if err != nil {
@@ -77,8 +110,37 @@ func WaitForImageReady(ctx context.Context, client *cvm.Client, imageName string
 	}
 }
 
+// WaitForAddress wait for eip reaches statue
+func WaitForAddress(ctx context.Context, client VPCAPI, addressId string, status string, timeout int) (*vpc.Address, error) {
+	req := vpc.NewDescribeAddressesRequest()
+	req.AddressIds = []*string{&addressId}
+
+	for {
+		var resp *vpc.DescribeAddressesResponse
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			resp, e = client.DescribeAddressesWithContext(ctx, req)
+			return e
+		})
+		if err != nil {
+			return nil, err
+		}
+		if *resp.Response.TotalCount == 0 {
+			return nil, fmt.Errorf("eip(%s) not exist", addressId)
+		}
+		if *resp.Response.AddressSet[0].AddressStatus == status {
+			return resp.Response.AddressSet[0], nil
+		}
+		time.Sleep(DefaultWaitForInterval * time.Second)
+		timeout = timeout - DefaultWaitForInterval
+		if timeout <= 0 {
+			return nil, fmt.Errorf("wait eip(%s) status(%s) timeout", addressId, status)
+		}
+	}
+}
+
 // GetImageByName get image by image name
-func GetImageByName(ctx context.Context, client *cvm.Client, imageName string) (*cvm.Image, error) {
+func GetImageByName(ctx context.Context, client CVMAPI, imageName string) (*cvm.Image, error) {
 	req := cvm.NewDescribeImagesRequest()
 	req.Filters = []*cvm.Filter{
 		{
@@ -90,7 +152,7 @@ func GetImageByName(ctx context.Context, client *cvm.Client, imageName string) (
 	var resp *cvm.DescribeImagesResponse
 	err := Retry(ctx, func(ctx context.Context) error {
 		var e error
-		resp, e = client.DescribeImages(req)
+		resp, e = client.DescribeImagesWithContext(ctx, req)
 		return e
 	})
 	if err != nil {
@@ -109,7 +171,7 @@ func GetImageByName(ctx context.Context, client *cvm.Client, imageName string) (
 }
 
 // DeleteImageByName get image by image name
-func DeleteImageByID(ctx context.Context, client *cvm.Client, imageID string) error {
+func DeleteImageByID(ctx context.Context, client CVMAPI, imageID string) error {
 	req := cvm.NewDeleteImagesRequest()
 	req.ImageIds = []*string{&imageID}
 
@@ -125,56 +187,59 @@ func DeleteImageByID(ctx context.Context, client *cvm.Client, imageID string) er
 	return nil
 }
 
-// NewCvmClient returns a new cvm client
-func NewCvmClient(secretId, secretKey, region, endpoint string) (client *cvm.Client, err error) {
-	cpf := profile.NewClientProfile()
-	cpf.HttpProfile.ReqMethod = "POST"
-	cpf.HttpProfile.ReqTimeout = 300
-	cpf.Language = "en-US"
-	if endpoint != "" {
-		var u *url.URL
-		u, err = url.Parse(endpoint)
-		if err != nil {
-			return
-		}
-		if u.Scheme != "" {
-			cpf.HttpProfile.Scheme = u.Scheme
-			cpf.HttpProfile.Endpoint = u.Host
-		} else {
-			cpf.HttpProfile.Endpoint = endpoint
-		}
+// SendWithExtraParams sends request with extra parameters which are not
+// supported by the vendored sdk yet, params are merged into the request
+// recursively, and the result is parsed into response as usual.
+func SendWithExtraParams(ctx context.Context, client APISender, request tchttp.Request, params map[string]interface{}, response tchttp.Response) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
 	}
+	actionParams := make(map[string]interface{})
+	if err = json.Unmarshal(body, &actionParams); err != nil {
+		return err
+	}
+	mergeParams(actionParams, params)
 
-	credential := common.NewCredential(secretId, secretKey)
-	client, err = cvm.NewClient(credential, region, cpf)
+	req := tchttp.NewCommonRequest(request.GetService(), request.GetVersion(), request.GetAction())
+	req.SetContext(ctx)
+	if err = req.SetActionParameters(actionParams); err != nil {
+		return err
+	}
 
-	return
+	return client.Send(req, response)
 }
 
-// NewVpcClient returns a new vpc client
-func NewVpcClient(secretId, secretKey, region, endpoint string) (client *vpc.Client, err error) {
-	cpf := profile.NewClientProfile()
-	cpf.HttpProfile.ReqMethod = "POST"
-	cpf.HttpProfile.ReqTimeout = 300
-	cpf.Language = "en-US"
-	if endpoint != "" {
-		var u *url.URL
-		u, err = url.Parse(endpoint)
-		if err != nil {
-			return
-		}
-		if u.Scheme != "" {
-			cpf.HttpProfile.Scheme = u.Scheme
-			cpf.HttpProfile.Endpoint = u.Host
-		} else {
-			cpf.HttpProfile.Endpoint = endpoint
+// mergeParams merges src into dst, maps are merged by key and lists of maps
+// are merged by index.
+func mergeParams(dst, src map[string]interface{}) {
+	for k, v := range src {
+		switch srcValue := v.(type) {
+		case map[string]interface{}:
+			dstMap, ok := dst[k].(map[string]interface{})
+			if !ok {
+				dstMap = make(map[string]interface{})
+				dst[k] = dstMap
+			}
+			mergeParams(dstMap, srcValue)
+		case []map[string]interface{}:
+			dstList, _ := dst[k].([]interface{})
+			for i, item := range srcValue {
+				if i >= len(dstList) {
+					dstList = append(dstList, make(map[string]interface{}))
+				}
+				dstMap, ok := dstList[i].(map[string]interface{})
+				if !ok {
+					dstMap = make(map[string]interface{})
+					dstList[i] = dstMap
+				}
+				mergeParams(dstMap, item)
+			}
+			dst[k] = dstList
+		default:
+			dst[k] = v
 		}
 	}
-
-	credential := common.NewCredential(secretId, secretKey)
-	client, err = vpc.NewClient(credential, region, cpf)
-
-	return
 }
 
 // CheckResourceIdFormat check resource id format
@@ -183,42 +248,162 @@ func CheckResourceIdFormat(resource string, id string) bool {
 	return regex.MatchString(id)
 }
 
-// SSHHost returns a function that can be given to the SSH communicator
-func SSHHost(pubilcIp bool) func(multistep.StateBag) (string, error) {
+// CheckIpInCidr check whether ip is in cidr block
+func CheckIpInCidr(ip string, cidr string) bool {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+	return ipNet.Contains(net.ParseIP(ip))
+}
+
+// WaitForInstanceAddress wait for instance has address of the interface
+func WaitForInstanceAddress(ctx context.Context, client CVMAPI, instanceId string, sshInterface string, timeout int) (*cvm.Instance, error) {
+	req := cvm.NewDescribeInstancesRequest()
+	req.InstanceIds = []*string{&instanceId}
+
+	for {
+		var resp *cvm.DescribeInstancesResponse
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			resp, e = client.DescribeInstancesWithContext(ctx, req)
+			return e
+		})
+		if err != nil {
+			return nil, err
+		}
+		if *resp.Response.TotalCount == 0 {
+			return nil, fmt.Errorf("instance(%s) not exist", instanceId)
+		}
+		if len(instanceAddresses(resp.Response.InstanceSet[0], sshInterface)) > 0 {
+			return resp.Response.InstanceSet[0], nil
+		}
+		time.Sleep(DefaultWaitForInterval * time.Second)
+		timeout = timeout - DefaultWaitForInterval
+		if timeout <= 0 {
+			return nil, fmt.Errorf("wait instance(%s) %s address timeout", instanceId, sshInterface)
+		}
+	}
+}
+
+func instanceAddresses(instance *cvm.Instance, sshInterface string) []*string {
+	switch sshInterface {
+	case "public_ip":
+		return instance.PublicIpAddresses
+	case "ipv6":
+		return instance.IPv6Addresses
+	default:
+		return instance.PrivateIpAddresses
+	}
+}
+
+// SSHHost returns a function that can be given to the SSH and WinRM communicator
+func SSHHost(sshInterface string, timeout time.Duration, hostName string) func(multistep.StateBag) (string, error) {
 	return func(state multistep.StateBag) (string, error) {
+		switch sshInterface {
+		case "eip":
+			if address, ok := state.GetOk("eip_address"); ok {
+				return address.(string), nil
+			}
+			return "", fmt.Errorf("no eip associated")
+		case "private_dns":
+			return hostName, nil
+		}
+
 		instance := state.Get("instance").(*cvm.Instance)
-		if pubilcIp {
-			return *instance.PublicIpAddresses[0], nil
-		} else {
-			return *instance.PrivateIpAddresses[0], nil
+		if addresses := instanceAddresses(instance, sshInterface); len(addresses) > 0 {
+			return *addresses[0], nil
+		}
+
+		client := state.Get("cvm_client").(CVMAPI)
+		instance, err := WaitForInstanceAddress(context.TODO(), client, *instance.InstanceId, sshInterface, int(timeout.Seconds()))
+		if err != nil {
+			return "", err
 		}
+		state.Put("instance", instance)
+
+		return *instanceAddresses(instance, sshInterface)[0], nil
+	}
+}
+
+// retryAttemptKey is the context key of the attempt of the api request
+type retryAttemptKey struct{}
+
+// retryAttempt returns the attempt of the api request sent with ctx, which
+// starts from 1, the requests sent without Retry are the first attempts.
+func retryAttempt(ctx context.Context) int {
+	if attempt, ok := ctx.Value(retryAttemptKey{}).(int); ok {
+		return attempt
 	}
+	return 1
 }
 
-// Retry do retry on api request
+// Retry do retry on api request, as configured by api_retry. fn should send
+// the request with its ctx, which carries the attempt for the api trace.
 func Retry(ctx context.Context, fn func(context.Context) error) error {
+	policy := getApiRetry()
+	attempt := 1
+	send := func(ctx context.Context) error {
+		return fn(context.WithValue(ctx, retryAttemptKey{}, attempt))
+	}
 	return retry.Config{
-		Tries: 60,
+		Tries: policy.config.MaxAttempts,
 		ShouldRetry: func(err error) bool {
 			e, ok := err.(*errors.TencentCloudSDKError)
-			if !ok {
+			if !ok || !policy.isRetryable(e.Code) {
 				return false
 			}
-			if e.Code == "ClientError.NetworkError" || e.Code == "ClientError.HttpStatusCodeError" ||
-				e.Code == "InvalidKeyPair.NotSupported" || e.Code == "InvalidParameterValue.KeyPairNotSupported" ||
-				e.Code == "InvalidInstance.NotSupported" || e.Code == "OperationDenied.InstanceOperationInProgress" ||
-				strings.Contains(e.Code, "RequestLimitExceeded") || strings.Contains(e.Code, "InternalError") ||
-				strings.Contains(e.Code, "ResourceInUse") || strings.Contains(e.Code, "ResourceBusy") {
-				return true
+			action := requestAction(e.RequestId)
+			if attempt < policy.config.MaxAttempts {
+				log.Printf("[WARN] Retrying %s (attempt %d/%d) on %s, request id: %s",
+					action, attempt+1, policy.config.MaxAttempts, e.Code, e.RequestId)
 			}
-			return false
+			return true
+		},
+		RetryDelay: func() time.Duration {
+			d := policy.delay(attempt)
+			attempt++
+			return d
 		},
-		RetryDelay: (&retry.Backoff{
-			InitialBackoff: 1 * time.Second,
-			MaxBackoff:     5 * time.Second,
-			Multiplier:     2,
-		}).Linear,
-	}.Run(ctx, fn)
+	}.Run(ctx, send)
+}
+
+// leakedResource is a resource failed to be cleaned up
+type leakedResource struct {
+	Resource string
+	Id       string
+	Err      error
+}
+
+// Leak records a resource failed to be cleaned up, all of them are reported
+// together by ReportLeaks at the end of the build.
+func Leak(state multistep.StateBag, err error, resource, id string) {
+	log.Printf("[WARN] Failed to clean up %s(%s): %s", resource, id, err)
+
+	var leaks []*leakedResource
+	if v, ok := state.GetOk("leaked_resources"); ok {
+		leaks = v.([]*leakedResource)
+	}
+	leaks = append(leaks, &leakedResource{Resource: resource, Id: id, Err: err})
+	state.Put("leaked_resources", leaks)
+}
+
+// ReportLeaks prints the summary of resources failed to be cleaned up
+func ReportLeaks(state multistep.StateBag) {
+	v, ok := state.GetOk("leaked_resources")
+	if !ok {
+		return
+	}
+
+	leaks := v.([]*leakedResource)
+	lines := make([]string, 0, len(leaks)+1)
+	lines = append(lines, "Failed to clean up the following resources, please delete them manually:")
+	for _, leak := range leaks {
+		lines = append(lines, fmt.Sprintf("  %s(%s): %s", leak.Resource, leak.Id, leak.Err))
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+	ui.Error(strings.Join(lines, "\n"))
 }
 
 // SayClean tell you clean module message
@@ -234,6 +419,34 @@ func SayClean(state multistep.StateBag, module string) {
 	}
 }
 
+// LogSecrets registers secrets with the log filter of packer. The lines of a
+// pem private key are registered too, in case the key is logged partially,
+// other secrets such as user_data are only filtered as a whole.
+func LogSecrets(secrets ...string) {
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		packersdk.LogSecretFilter.Set(secret)
+		if !isPrivateKey(secret) {
+			continue
+		}
+		for _, line := range strings.Split(secret, "\n") {
+			line = strings.TrimSpace(line)
+			// the pem headers and short lines are not secret
+			if len(line) >= 16 && !strings.HasPrefix(line, "-----") {
+				packersdk.LogSecretFilter.Set(line)
+			}
+		}
+	}
+}
+
+// isPrivateKey returns whether secret is a pem encoded private key
+func isPrivateKey(secret string) bool {
+	secret = strings.TrimSpace(secret)
+	return strings.HasPrefix(secret, "-----BEGIN ") && strings.Contains(secret, "PRIVATE KEY-----")
+}
+
 // Say tell you a message
 func Say(state multistep.StateBag, message, prefix string) {
 	if prefix != "" {
@@ -0,0 +1,129 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+// dedicatedHost is a host candidate to launch cvm on, it is specified by
+// either id or ip.
+type dedicatedHost struct {
+	HostId string
+	HostIp string
+	Zone   string
+}
+
+func (h *dedicatedHost) String() string {
+	if h.HostIp != "" {
+		return h.HostIp
+	}
+	return h.HostId
+}
+
+// DescribeDedicatedHosts returns all dedicated hosts, or the hosts of
+// hostIds if it is not empty.
+func DescribeDedicatedHosts(ctx context.Context, client CVMAPI, hostIds []string) ([]*cvm.HostItem, error) {
+	var hosts []*cvm.HostItem
+	var offset uint64
+
+	for {
+		req := cvm.NewDescribeHostsRequest()
+		req.Offset = common.Uint64Ptr(offset)
+		req.Limit = common.Uint64Ptr(100)
+		if len(hostIds) != 0 {
+			req.Filters = []*cvm.Filter{
+				{
+					Name:   common.StringPtr("host-id"),
+					Values: common.StringPtrs(hostIds),
+				},
+			}
+		}
+
+		var resp *cvm.DescribeHostsResponse
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			resp, e = client.DescribeHostsWithContext(ctx, req)
+			return e
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		hosts = append(hosts, resp.Response.HostSet...)
+		if len(resp.Response.HostSet) < 100 || uint64(len(hosts)) >= *resp.Response.TotalCount {
+			break
+		}
+		offset += 100
+	}
+
+	return hosts, nil
+}
+
+// GetDedicatedHosts checks the dedicated hosts specified by ids and ips, and
+// returns them in order.
+func GetDedicatedHosts(ctx context.Context, client CVMAPI, hostIds []string, hostIps []string) ([]*dedicatedHost, error) {
+	if len(hostIds) == 0 && len(hostIps) == 0 {
+		return nil, nil
+	}
+
+	// hosts specified by ip can only be found by listing all hosts
+	filter := hostIds
+	if len(hostIps) != 0 {
+		filter = nil
+	}
+	items, err := DescribeDedicatedHosts(ctx, client, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[string]*cvm.HostItem)
+	byIp := make(map[string]*cvm.HostItem)
+	for _, item := range items {
+		if item.HostId != nil {
+			byId[*item.HostId] = item
+		}
+		if item.HostIp != nil {
+			byIp[*item.HostIp] = item
+		}
+	}
+
+	var hosts []*dedicatedHost
+	for _, id := range hostIds {
+		item, ok := byId[id]
+		if !ok {
+			return nil, fmt.Errorf("dedicated host(%s) not exist", id)
+		}
+		if err := checkDedicatedHost(item); err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, &dedicatedHost{HostId: id, Zone: *item.Placement.Zone})
+	}
+	for _, ip := range hostIps {
+		item, ok := byIp[ip]
+		if !ok {
+			return nil, fmt.Errorf("dedicated host with ip(%s) not exist", ip)
+		}
+		if err := checkDedicatedHost(item); err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, &dedicatedHost{HostIp: ip, Zone: *item.Placement.Zone})
+	}
+
+	return hosts, nil
+}
+
+func checkDedicatedHost(item *cvm.HostItem) error {
+	if item.HostState != nil && *item.HostState != "RUNNING" {
+		return fmt.Errorf("dedicated host(%s) is %s", *item.HostId, *item.HostState)
+	}
+	if item.Placement == nil || item.Placement.Zone == nil {
+		return fmt.Errorf("dedicated host(%s) has no zone", *item.HostId)
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ package cvm
 
 import (
 	"fmt"
+	"time"
 	"unicode/utf8"
 
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
@@ -20,24 +21,56 @@ type TencentCloudImageConfig struct {
 	// Image description.
 	ImageDescription string `mapstructure:"image_description" required:"false"`
 	// Whether shutdown cvm to create Image. Default value is
-	// false.
+	// false. It is deprecated, same as setting `shutdown_behavior` to `stop`.
 	Reboot bool `mapstructure:"reboot" required:"false"`
+	// What to do with cvm before creating image, values can be:
+	// -  `stop` - Shutdown cvm by `shutdown_command` and wait for it to be
+	//    stopped in `shutdown_timeout`, then fallback to stop cvm by api
+	//    with `stop_type`.
+	// -  `reboot` - Reboot cvm by api with `stop_type` and wait for it to be
+	//    running, the image is created from the running cvm.
+	// -  `live` - Create image from cvm as it is running, the temporary
+	//    keypair is detached after the image is created.
+	// Default value is `stop`.
+	ShutdownBehavior string `mapstructure:"shutdown_behavior" required:"false"`
+	// The command to shutdown cvm gracefully through communicator when
+	// `shutdown_behavior` is `stop`, e.g. `sudo shutdown -h now`. If not set,
+	// cvm is stopped by api directly.
+	ShutdownCommand string `mapstructure:"shutdown_command" required:"false"`
+	// The timeout waiting for cvm to be stopped after `shutdown_command`.
+	// Default value is `5m`.
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout" required:"false"`
+	// The way to stop or reboot cvm by api, values can be `SOFT` (default),
+	// `HARD` and `SOFT_FIRST`, which means soft shutdown first and hard
+	// shutdown when it fails.
+	StopType string `mapstructure:"stop_type" required:"false"`
 	// Whether to force power off cvm when create image.
 	// Default value is false.
 	ForcePoweroff bool `mapstructure:"force_poweroff" required:"false"`
-	// Whether enable Sysprep during creating windows image.
+	// Whether enable Sysprep during creating windows image. When `os_type`
+	// is `windows`, sysprep is run through WinRM before creating image.
 	Sysprep          bool `mapstructure:"sysprep" required:"false"`
 	ImageForceDelete bool `mapstructure:"image_force_delete"`
 	// regions that will be copied to after
 	// your image created.
 	ImageCopyRegions []string `mapstructure:"image_copy_regions" required:"false"`
+	// Whether to encrypt the images copied to `image_copy_regions`.
+	// Default value is false.
+	ImageCopyEncrypted bool `mapstructure:"image_copy_encrypted" required:"false"`
+	// The kms key ids used to encrypt the copied images, keyed by region,
+	// the default cbs key of the region is used if a region is not set.
+	// It requires `image_copy_encrypted` to be true.
+	ImageCopyKmsKeyIds map[string]string `mapstructure:"image_copy_kms_key_ids" required:"false"`
 	// accounts that will be shared to
 	// after your image created.
 	ImageShareAccounts []string `mapstructure:"image_share_accounts" required:"false"`
 	// Key/value pair tags that will be applied to the resulting image.
 	ImageTags      map[string]string `mapstructure:"image_tags" required:"false"`
 	skipValidation bool
-	SkipIfExists   bool `mapstructure:"skip_if_exists" required:"false"`
+	// regions are the known regions to validate image_copy_regions,
+	// ValidRegions is used if empty
+	regions      []string
+	SkipIfExists bool `mapstructure:"skip_if_exists" required:"false"`
 }
 
 func (cf *TencentCloudImageConfig) Prepare(ctx *interpolate.Context) []error {
@@ -65,7 +98,7 @@ func (cf *TencentCloudImageConfig) Prepare(ctx *interpolate.Context) []error {
 			regionSet[region] = struct{}{}
 
 			if !cf.skipValidation {
-				if err := validRegion(region); err != nil {
+				if err := validRegion(region, cf.knownRegions()); err != nil {
 					errs = append(errs, err)
 					continue
 				}
@@ -75,6 +108,51 @@ func (cf *TencentCloudImageConfig) Prepare(ctx *interpolate.Context) []error {
 		cf.ImageCopyRegions = regions
 	}
 
+	if len(cf.ImageCopyKmsKeyIds) > 0 {
+		if !cf.ImageCopyEncrypted {
+			errs = append(errs, fmt.Errorf("image_copy_kms_key_ids requires image_copy_encrypted to be true"))
+		}
+		for region := range cf.ImageCopyKmsKeyIds {
+			found := false
+			for _, copyRegion := range cf.ImageCopyRegions {
+				if region == copyRegion {
+					found = true
+					break
+				}
+			}
+			if !found {
+				errs = append(errs, fmt.Errorf("region(%s) in image_copy_kms_key_ids is not in image_copy_regions", region))
+			}
+		}
+	}
+
+	switch cf.ShutdownBehavior {
+	case "":
+		cf.ShutdownBehavior = "stop"
+	case "stop", "reboot", "live":
+		if cf.Reboot && cf.ShutdownBehavior != "stop" {
+			errs = append(errs, fmt.Errorf("reboot conflicts with shutdown_behavior(%s)", cf.ShutdownBehavior))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("specified shutdown_behavior(%s) is invalid", cf.ShutdownBehavior))
+	}
+
+	if cf.ShutdownCommand != "" && cf.ShutdownBehavior != "stop" {
+		errs = append(errs, fmt.Errorf("shutdown_command requires shutdown_behavior to be stop"))
+	}
+
+	if cf.ShutdownTimeout <= 0 {
+		cf.ShutdownTimeout = 5 * time.Minute
+	}
+
+	switch cf.StopType {
+	case "":
+		cf.StopType = "SOFT"
+	case "SOFT", "HARD", "SOFT_FIRST":
+	default:
+		errs = append(errs, fmt.Errorf("specified stop_type(%s) is invalid", cf.StopType))
+	}
+
 	if cf.ImageTags == nil {
 		cf.ImageTags = make(map[string]string)
 	}
@@ -85,3 +163,10 @@ func (cf *TencentCloudImageConfig) Prepare(ctx *interpolate.Context) []error {
 
 	return nil
 }
+
+func (cf *TencentCloudImageConfig) knownRegions() []string {
+	if len(cf.regions) > 0 {
+		return cf.regions
+	}
+	return staticRegions()
+}
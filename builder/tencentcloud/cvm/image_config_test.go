@@ -56,3 +56,76 @@ func TestSkipIfExists(t *testing.T) {
 		}
 	}
 }
+
+func TestTencentCloudImageConfigPrepare_ShutdownBehavior(t *testing.T) {
+	cf := &TencentCloudImageConfig{
+		ImageName: "foo",
+	}
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %v", err)
+	}
+
+	if cf.ShutdownBehavior != "stop" || cf.StopType != "SOFT" {
+		t.Fatalf("invalid shutdown value: %v, %v", cf.ShutdownBehavior, cf.StopType)
+	}
+
+	cf = &TencentCloudImageConfig{
+		ImageName: "foo",
+		Reboot:    true,
+	}
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %v", err)
+	}
+
+	if cf.ShutdownBehavior != "stop" {
+		t.Fatalf("invalid shutdown_behavior value: %v", cf.ShutdownBehavior)
+	}
+
+	cf.ShutdownBehavior = "live"
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have err")
+	}
+
+	cf.Reboot = false
+	cf.ShutdownCommand = "shutdown -h now"
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have err")
+	}
+
+	cf.ShutdownBehavior = "stop"
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %v", err)
+	}
+
+	cf.StopType = "unknown"
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have err")
+	}
+
+	cf.StopType = "HARD"
+	cf.ShutdownBehavior = "unknown"
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have err")
+	}
+}
+
+func TestTencentCloudImageConfigPrepare_ImageCopyEncrypted(t *testing.T) {
+	cf := &TencentCloudImageConfig{
+		ImageName:          "foo",
+		ImageCopyRegions:   []string{"ap-guangzhou", "ap-hongkong"},
+		ImageCopyKmsKeyIds: map[string]string{"ap-hongkong": "kms-key"},
+	}
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have err")
+	}
+
+	cf.ImageCopyEncrypted = true
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have err: %v", err)
+	}
+
+	cf.ImageCopyKmsKeyIds["ap-shanghai"] = "kms-key"
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have err")
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// LaunchSteps returns the steps to launch a cvm of run config in the region
+// and zone of access config, which are shared by the builders launching cvm.
+// The builders connect to the cvm and run the provisioners afterwards.
+func LaunchSteps(access *TencentCloudAccessConfig, config *TencentCloudRunConfig, packerConfig *common.PackerConfig) []multistep.Step {
+	userData := config.UserData
+	winrmPort := 0
+	if config.OsType == "windows" && config.Comm.Type == "winrm" {
+		userData = WinRMUserData(config.Comm.WinRMPort)
+		winrmPort = config.Comm.WinRMPort
+	}
+
+	return []multistep.Step{
+		&stepCheckSourceImage{
+			SourceImageId:   config.SourceImageId,
+			SourceImageName: config.SourceImageName,
+		},
+		&stepConfigKeyPair{
+			Debug:        packerConfig.PackerDebug,
+			Comm:         &config.Comm,
+			DebugKeyPath: fmt.Sprintf("cvm_%s.pem", packerConfig.PackerBuildName),
+		},
+		// 创建 VPC 或选择 VPC, 结果一定有且只有一个 VpcId
+		&stepConfigVPC{
+			VpcId:      config.VpcId,
+			CidrBlock:  config.CidrBlock,
+			VpcName:    config.VpcName,
+			EnableIpv6: config.EnableIpv6,
+		},
+		// 创建 subnet 或者选择 subnet 列表, 结果一定有 (subnet, zone) 列表
+		&stepConfigSubnet{
+			SubnetId:        config.SubnetId,
+			SubnetCidrBlock: config.SubnectCidrBlock,
+			SubnetName:      config.SubnetName,
+			Zone:            access.Zone,
+			EnableIpv6:      config.EnableIpv6,
+		},
+		&stepConfigSecurityGroup{
+			SecurityGroupId:   config.SecurityGroupId,
+			SecurityGroupName: config.SecurityGroupName,
+			Description:       "securitygroup for packer",
+			EnableIpv6:        config.EnableIpv6,
+			WinRMPort:         winrmPort,
+		},
+		// 遍历 subnet 列表, 尝试创建机器，直到创建成功或最终失败
+		&stepRunInstance{
+			InstanceTypeCandidates:   config.InstanceTypeCandidates,
+			InstanceChargeType:       config.InstanceChargeType,
+			UserData:                 userData,
+			UserDataFile:             config.UserDataFile,
+			UserDataParts:            config.UserDataParts,
+			Comm:                     &config.Comm,
+			InstanceName:             config.InstanceName,
+			DiskType:                 config.DiskType,
+			DiskSize:                 config.DiskSize,
+			DiskEncrypted:            config.DiskEncrypted,
+			DiskKmsKeyId:             config.DiskKmsKeyId,
+			DataDisks:                config.DataDisks,
+			HostName:                 config.HostName,
+			InternetChargeType:       config.InternetChargeType,
+			InternetMaxBandwidthOut:  config.InternetMaxBandwidthOut,
+			BandwidthPackageId:       config.BandwidthPackageId,
+			AssociatePublicIpAddress: config.AssociatePublicIpAddress,
+			Tags:                     config.RunTags,
+			PlacementGroupId:         config.PlacementGroupId,
+			VpcIpCandidates:          config.VpcIpCandidates,
+			EnableIpv6:               config.EnableIpv6,
+			Region:                   access.Region,
+			CamRoleName:              config.CamRoleName,
+			LaunchTemplateId:         config.LaunchTemplateId,
+			LaunchTemplateVersion:    config.LaunchTemplateVersion,
+			LaunchTemplate:           config.launchTemplate,
+			Placement:                config.Placement,
+			DisableSecurityService:   config.DisableSecurityService,
+			DisableMonitorService:    config.DisableMonitorService,
+			DisableAutomationService: config.DisableAutomationService,
+		},
+		&stepConfigEip{
+			AssociateEip:            config.AssociateEip,
+			EipIds:                  config.EipIds,
+			EipAddresses:            config.EipAddresses,
+			InternetChargeType:      config.InternetChargeType,
+			InternetMaxBandwidthOut: config.InternetMaxBandwidthOut,
+			BandwidthPackageId:      config.BandwidthPackageId,
+		},
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+// GetLaunchTemplateVersion returns the data of launch template version, the
+// default version is used if version is 0.
+func GetLaunchTemplateVersion(ctx context.Context, client CVMAPI, launchTemplateId string, version uint64) (*cvm.LaunchTemplateVersionData, error) {
+	req := cvm.NewDescribeLaunchTemplateVersionsRequest()
+	req.LaunchTemplateId = &launchTemplateId
+	if version > 0 {
+		req.LaunchTemplateVersions = []*uint64{common.Uint64Ptr(version)}
+	} else {
+		req.DefaultVersion = common.BoolPtr(true)
+	}
+
+	var resp *cvm.DescribeLaunchTemplateVersionsResponse
+	err := Retry(ctx, func(ctx context.Context) error {
+		var e error
+		resp, e = client.DescribeLaunchTemplateVersionsWithContext(ctx, req)
+		return e
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range resp.Response.LaunchTemplateVersionSet {
+		if info.LaunchTemplateVersionData != nil {
+			return info.LaunchTemplateVersionData, nil
+		}
+	}
+
+	if version > 0 {
+		return nil, fmt.Errorf("launch template(%s) version(%d) not exist", launchTemplateId, version)
+	}
+	return nil, fmt.Errorf("launch template(%s) not exist", launchTemplateId)
+}
+
+// LoadLaunchTemplate gets the data of launch template for run config, which
+// is required to check the conflicts with it.
+func (cf *TencentCloudRunConfig) LoadLaunchTemplate(access *TencentCloudAccessConfig) error {
+	client, err := NewCvmClient(access, access.Region)
+	if err != nil {
+		return err
+	}
+
+	data, err := GetLaunchTemplateVersion(context.TODO(), client, cf.LaunchTemplateId, cf.LaunchTemplateVersion)
+	if err != nil {
+		return fmt.Errorf("failed to get launch template: %w", err)
+	}
+	cf.launchTemplate = data
+
+	return nil
+}
+
+// launchTemplateVpc returns the vpc and subnet of launch template
+func launchTemplateVpc(data *cvm.LaunchTemplateVersionData) (string, string) {
+	if data == nil || data.VirtualPrivateCloud == nil {
+		return "", ""
+	}
+	var vpcId, subnetId string
+	if data.VirtualPrivateCloud.VpcId != nil {
+		vpcId = *data.VirtualPrivateCloud.VpcId
+	}
+	if data.VirtualPrivateCloud.SubnetId != nil {
+		subnetId = *data.VirtualPrivateCloud.SubnetId
+	}
+	return vpcId, subnetId
+}
+
+// launchTemplatePublicIp returns whether launch template assigns public ip
+func launchTemplatePublicIp(data *cvm.LaunchTemplateVersionData) bool {
+	return data != nil && data.InternetAccessible != nil &&
+		data.InternetAccessible.PublicIpAssigned != nil && *data.InternetAccessible.PublicIpAssigned
+}
+
+// launchTemplateTagSpecification returns the tag specification of the request
+// launching cvm from launch template, as the tag specification of the request
+// replaces that of the template. tags are merged into the instance tags of
+// the template, and the tags of other resources are kept.
+func launchTemplateTagSpecification(data *cvm.LaunchTemplateVersionData, tags map[string]string) []*cvm.TagSpecification {
+	instanceTags := make(map[string]string)
+	var specs []*cvm.TagSpecification
+	if data != nil {
+		for _, spec := range data.TagSpecification {
+			if spec.ResourceType == nil || *spec.ResourceType != "instance" {
+				specs = append(specs, spec)
+				continue
+			}
+			for _, tag := range spec.Tags {
+				if tag.Key != nil && tag.Value != nil {
+					instanceTags[*tag.Key] = *tag.Value
+				}
+			}
+		}
+	}
+	for k, v := range tags {
+		instanceTags[k] = v
+	}
+
+	return append(specs, &cvm.TagSpecification{
+		ResourceType: common.StringPtr("instance"),
+		Tags:         CvmTags(instanceTags),
+	})
+}
@@ -0,0 +1,200 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+const (
+	// RegionCacheTTL is how long the regions got from DescribeRegions are
+	// used before asking again
+	RegionCacheTTL = 24 * time.Hour
+
+	// describeRegionsTimeout bounds the region discovery, the static list is
+	// used when the api is unreachable
+	describeRegionsTimeout = 10 * time.Second
+)
+
+// regionCache is the regions saved on disk
+type regionCache struct {
+	Regions   []string  `json:"regions"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RegionCachePath returns the path of the region cache in packer cache dir
+func RegionCachePath() (string, error) {
+	return packersdk.CachePath("tencentcloud", "regions.json")
+}
+
+// GetRegions returns the available regions by DescribeRegions
+func GetRegions(ctx context.Context, client CVMAPI) ([]string, error) {
+	req := cvm.NewDescribeRegionsRequest()
+	var resp *cvm.DescribeRegionsResponse
+	err := Retry(ctx, func(ctx context.Context) error {
+		var e error
+		resp, e = client.DescribeRegionsWithContext(ctx, req)
+		return e
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []string
+	for _, region := range resp.Response.RegionSet {
+		if region.Region != nil && (region.RegionState == nil || *region.RegionState == "AVAILABLE") {
+			regions = append(regions, *region.Region)
+		}
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("no available region")
+	}
+	sort.Strings(regions)
+
+	return regions, nil
+}
+
+// LoadRegions returns the available regions, they are read from the cache at
+// cachePath if it is updated within RegionCacheTTL, or else got from client
+// and saved to the cache. When the api fails, the expired cache is used, and
+// ValidRegions at last.
+func LoadRegions(ctx context.Context, client CVMAPI, cachePath string) []string {
+	cache, err := readRegionCache(cachePath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("[WARN] Failed to read region cache %s: %s", cachePath, err)
+	}
+	if cache != nil && time.Since(cache.UpdatedAt) < RegionCacheTTL {
+		return cache.Regions
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, describeRegionsTimeout)
+	defer cancel()
+	regions, err := GetRegions(ctx, client)
+	if err == nil {
+		cache = &regionCache{Regions: regions, UpdatedAt: time.Now()}
+		if err := writeRegionCache(cachePath, cache); err != nil {
+			log.Printf("[WARN] Failed to write region cache %s: %s", cachePath, err)
+		}
+		return regions
+	}
+
+	if cache != nil {
+		log.Printf("[WARN] Failed to describe regions, using the cache updated at %s: %s",
+			cache.UpdatedAt.Format(time.RFC3339), err)
+		return cache.Regions
+	}
+	log.Printf("[WARN] Failed to describe regions, using the builtin list: %s", err)
+	return staticRegions()
+}
+
+// staticRegions returns ValidRegions as strings
+func staticRegions() []string {
+	regions := make([]string, 0, len(ValidRegions))
+	for _, region := range ValidRegions {
+		regions = append(regions, string(region))
+	}
+	return regions
+}
+
+func readRegionCache(path string) (*regionCache, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cache := &regionCache{}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, err
+	}
+	if len(cache.Regions) == 0 {
+		return nil, nil
+	}
+	return cache, nil
+}
+
+func writeRegionCache(path string, cache *regionCache) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first, builds running in parallel may read
+	// the cache at the same time
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// validRegion checks region is one of regions, the most similar one is
+// suggested if not.
+func validRegion(region string, regions []string) error {
+	for _, valid := range regions {
+		if region == valid {
+			return nil
+		}
+	}
+
+	if suggestion := suggest(region, regions); suggestion != "" {
+		return fmt.Errorf("unknown region: %s, did you mean %s?", region, suggestion)
+	}
+	return fmt.Errorf("unknown region: %s", region)
+}
+
+// suggest returns the candidate most similar to s, or empty if none is close
+// enough to be a typo.
+func suggest(s string, candidates []string) string {
+	best, bestDistance := "", len(s)/3+1
+	for _, candidate := range candidates {
+		if d := editDistance(s, candidate); d <= bestDistance && (best == "" || d < bestDistance) {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,133 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestMain points the packer cache dir to a fresh region cache, so config
+// validation does not describe regions over network.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "packer-tencentcloud-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("PACKER_CACHE_DIR", dir)
+	cachePath, err := RegionCachePath()
+	if err != nil {
+		panic(err)
+	}
+	if err := writeRegionCache(cachePath, &regionCache{Regions: staticRegions(), UpdatedAt: time.Now()}); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestLoadRegions(t *testing.T) {
+	describeErr := fakeError("AuthFailure.SignatureFailure")
+	cases := []struct {
+		name  string
+		cache *regionCache
+		err   error
+		want  []string
+		// describe is whether DescribeRegions is called
+		describe bool
+	}{
+		{
+			name:  "fresh cache",
+			cache: &regionCache{Regions: []string{"ap-cached"}, UpdatedAt: time.Now()},
+			want:  []string{"ap-cached"},
+		},
+		{
+			name:     "expired cache",
+			cache:    &regionCache{Regions: []string{"ap-cached"}, UpdatedAt: time.Now().Add(-RegionCacheTTL)},
+			want:     []string{"ap-guangzhou", "ap-shanghai"},
+			describe: true,
+		},
+		{
+			name:     "no cache",
+			want:     []string{"ap-guangzhou", "ap-shanghai"},
+			describe: true,
+		},
+		{
+			name:     "offline with expired cache",
+			cache:    &regionCache{Regions: []string{"ap-cached"}, UpdatedAt: time.Now().Add(-RegionCacheTTL)},
+			err:      describeErr,
+			want:     []string{"ap-cached"},
+			describe: true,
+		},
+		{
+			name:     "offline without cache",
+			err:      describeErr,
+			want:     staticRegions(),
+			describe: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cachePath := filepath.Join(t.TempDir(), "regions.json")
+			if tc.cache != nil {
+				if err := writeRegionCache(cachePath, tc.cache); err != nil {
+					t.Fatal(err)
+				}
+			}
+			client := newFakeCVM("ap-guangzhou")
+			client.Peers["ap-shanghai"] = newFakeCVM("ap-shanghai")
+			if tc.err != nil {
+				client.fail("DescribeRegions", tc.err)
+			}
+
+			got := LoadRegions(context.Background(), client, cachePath)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected regions %v, got %v", tc.want, got)
+			}
+			if called := client.called("DescribeRegions") > 0; called != tc.describe {
+				t.Fatalf("expected DescribeRegions called %v, got %v", tc.describe, called)
+			}
+
+			if tc.describe && tc.err == nil {
+				cache, err := readRegionCache(cachePath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(cache.Regions, tc.want) || time.Since(cache.UpdatedAt) > time.Minute {
+					t.Fatalf("expected cache updated, got %+v", cache)
+				}
+			}
+		})
+	}
+}
+
+func TestValidRegion(t *testing.T) {
+	regions := []string{"ap-guangzhou", "ap-shanghai", "ap-shanghai-fsi", "na-ashburn"}
+	cases := []struct {
+		region string
+		err    string
+	}{
+		{region: "ap-guangzhou"},
+		{region: "ap-guangzhuo", err: "unknown region: ap-guangzhuo, did you mean ap-guangzhou?"},
+		{region: "ap-shanghai-fs", err: "unknown region: ap-shanghai-fs, did you mean ap-shanghai-fsi?"},
+		{region: "eu-paris", err: "unknown region: eu-paris"},
+	}
+
+	for _, tc := range cases {
+		err := validRegion(tc.region, regions)
+		if tc.err == "" && err != nil {
+			t.Errorf("%s: expected no error, got %s", tc.region, err)
+		}
+		if tc.err != "" && (err == nil || err.Error() != tc.err) {
+			t.Errorf("%s: expected error %q, got %v", tc.region, tc.err, err)
+		}
+	}
+}
@@ -2,32 +2,66 @@
 // SPDX-License-Identifier: MPL-2.0
 
 //go:generate packer-sdc struct-markdown
-//go:generate packer-sdc mapstructure-to-hcl2 -type tencentCloudDataDisk
+//go:generate packer-sdc mapstructure-to-hcl2 -type tencentCloudDataDisk,tencentCloudPlacement,tencentCloudUserDataPart
 
 package cvm
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/hashicorp/packer-plugin-sdk/uuid"
 	"github.com/pkg/errors"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
 )
 
 type tencentCloudDataDisk struct {
-	DiskType   string `mapstructure:"disk_type"`
-	DiskSize   int64  `mapstructure:"disk_size"`
-	SnapshotId string `mapstructure:"disk_snapshot_id"`
+	DiskType              string            `mapstructure:"disk_type"`
+	DiskSize              int64             `mapstructure:"disk_size"`
+	SnapshotId            string            `mapstructure:"disk_snapshot_id"`
+	DiskEncrypted         bool              `mapstructure:"disk_encrypted"`
+	DiskKmsKeyId          string            `mapstructure:"disk_kms_key_id"`
+	DiskName              string            `mapstructure:"disk_name"`
+	ThroughputPerformance int64             `mapstructure:"throughput_performance"`
+	BurstPerformance      bool              `mapstructure:"burst_performance"`
+	DeleteWithInstance    config.Trilean    `mapstructure:"delete_with_instance"`
+	Tags                  map[string]string `mapstructure:"tags"`
+}
+
+type tencentCloudPlacement struct {
+	DedicatedHostIds []string `mapstructure:"dedicated_host_ids"`
+	ProjectId        int64    `mapstructure:"project_id"`
+	HostIps          []string `mapstructure:"host_ips"`
+}
+
+type tencentCloudUserDataPart struct {
+	ContentType string `mapstructure:"content_type"`
+	Content     string `mapstructure:"content"`
+	File        string `mapstructure:"file"`
+	Interpolate bool   `mapstructure:"interpolate"`
 }
 
 type TencentCloudRunConfig struct {
 	// Whether allocate public ip to your cvm.
 	// Default value is false.
 	AssociatePublicIpAddress bool `mapstructure:"associate_public_ip_address" required:"false"`
+	// Whether associate an elastic ip to your cvm, conflict with
+	// `associate_public_ip_address`. A temporary eip will be allocated and
+	// released after the build, unless `eip_ids` or `eip_addresses` is set.
+	// Default value is false.
+	AssociateEip bool `mapstructure:"associate_eip" required:"false"`
+	// The existing eip id list your cvm will be associated with. The first
+	// eip not bound to any resource is used, and it is only disassociated
+	// after the build.
+	EipIds []string `mapstructure:"eip_ids" required:"false"`
+	// Same as `eip_ids` but specified by eip addresses. Conflict with `eip_ids`.
+	EipAddresses []string `mapstructure:"eip_addresses" required:"false"`
 	// The base image id of Image you want to create
 	// your customized image from.
 	SourceImageId string `mapstructure:"source_image_id" required:"false"`
@@ -35,6 +69,7 @@ type TencentCloudRunConfig struct {
 	// customized image from.Conflict with SourceImageId.
 	SourceImageName string `mapstructure:"source_image_name" required:"false"`
 	// Charge type of cvm, values can be `POSTPAID_BY_HOUR` (default) `SPOTPAID`
+	// and `CDHPAID` (default when `placement` has dedicated hosts).
 	InstanceChargeType string `mapstructure:"instance_charge_type" required:"false"`
 	// The instance type candidate list your cvm will be launched by.
 	// Will try to launch instance type from this list in order.
@@ -53,6 +88,12 @@ type TencentCloudRunConfig struct {
 	DiskType string `mapstructure:"disk_type" required:"false"`
 	// Root disk size your cvm will be launched by. values range(in GB):
 	DiskSize int64 `mapstructure:"disk_size" required:"false"`
+	// Whether to encrypt the root disk your cvm will be launched by.
+	// Default value is false.
+	DiskEncrypted bool `mapstructure:"disk_encrypted" required:"false"`
+	// The kms key id used to encrypt the root disk, the default cbs key of
+	// the region is used if not set. It requires `disk_encrypted` to be true.
+	DiskKmsKeyId string `mapstructure:"disk_kms_key_id" required:"false"`
 	// Add one or more data disks to the instance before creating the image.
 	// Note that if the source image has data disk snapshots, this argument
 	// will be ignored, and the running instance will use source image data
@@ -60,21 +101,47 @@ type TencentCloudRunConfig struct {
 	// type for all data disks, and each data disk size will use the origin
 	// value in source image.
 	// The data disks allow for the following argument:
-	// -  `disk_type` - Type of the data disk. Valid choices: `CLOUD_BASIC`, `CLOUD_PREMIUM` and `CLOUD_SSD`.
+	// -  `disk_type` - Type of the data disk, e.g. `CLOUD_PREMIUM`, `CLOUD_SSD`,
+	//    `CLOUD_HSSD` and `CLOUD_TSSD`. Types not sold in the zone are skipped
+	//    when trying subnets.
 	// -  `disk_size` - Size of the data disk.
 	// -  `disk_snapshot_id` - Id of the snapshot for a data disk.
+	// -  `disk_encrypted` - Whether to encrypt the data disk.
+	// -  `disk_kms_key_id` - The kms key id used to encrypt the data disk,
+	//    requires `disk_encrypted` to be true.
+	// -  `disk_name` - Name of the data disk.
+	// -  `throughput_performance` - Extra throughput of the data disk in MB/s,
+	//    only `CLOUD_HSSD` and `CLOUD_TSSD` support it.
+	// -  `burst_performance` - Whether to enable burst performance of the data disk.
+	// -  `delete_with_instance` - Whether to delete the data disk with cvm.
+	//    Default value is true. Note that the data disk is left after the
+	//    build if false.
+	// -  `tags` - Key/value pair tags that will be applied to the data disk.
+	//    Data disks are told apart by type and size, the disks of the same
+	//    type and size should have the same tags.
 	DataDisks []tencentCloudDataDisk `mapstructure:"data_disks"`
 	// Specify vpc your cvm will be launched by.
 	VpcId string `mapstructure:"vpc_id" required:"false"`
 	// Specify vpc name you will create. if vpc_id is not set, packer will
 	// create a vpc for you named this parameter.
 	VpcName string `mapstructure:"vpc_name" required:"false"`
-	VpcIp   string `mapstructure:"vpc_ip"`
+	// Specify the private ip your cvm will be launched with, it must be
+	// in the cidr block of the subnet. Conflict with `vpc_ip_candidates`.
+	VpcIp string `mapstructure:"vpc_ip" required:"false"`
+	// The private ip candidate list your cvm will be launched with.
+	// Will try to launch instance with the ips in this list in order,
+	// ips outside the cidr block of a subnet are skipped for that subnet.
+	VpcIpCandidates []string `mapstructure:"vpc_ip_candidates" required:"false"`
 	// Specify subnet your cvm will be launched by.
 	SubnetId string `mapstructure:"subnet_id" required:"false"`
 	// Specify subnet name you will create. if subnet_id is not set, packer will
 	// create a subnet for you named this parameter.
 	SubnetName string `mapstructure:"subnet_name" required:"false"`
+	// Whether enable ipv6 for your cvm. The vpc and subnet created by packer
+	// will be assigned ipv6 cidr blocks, and the temporary security group
+	// will allow ipv6 traffic. If you specify existing subnets, only those
+	// with ipv6 cidr blocks are used. Default value is false.
+	EnableIpv6 bool `mapstructure:"enable_ipv6" required:"false"`
 	// Specify cider block of the vpc you will create if vpc_id not set
 	CidrBlock string `mapstructure:"cidr_block" required:"false"` // 10.0.0.0/16(default), 172.16.0.0/12, 192.168.0.0/16
 	// Specify cider block of the subnet you will create if
@@ -95,11 +162,32 @@ type TencentCloudRunConfig struct {
 	UserData string `mapstructure:"user_data" required:"false"`
 	// userdata file.
 	UserDataFile string `mapstructure:"user_data_file" required:"false"`
+	// The cloud-init parts assembled into a MIME multipart user data, conflict
+	// with `user_data` and `user_data_file`. Each part has a `content_type` of
+	// `cloud-config`, `x-shellscript` or `jinja2`, and either `content` or a
+	// `file` to read it from. The content is rendered as a template with the
+	// build info if `interpolate` is true. Jinja2 templates should be read from
+	// `file`, as the templates in options are validated by packer. The user
+	// data encoded in base64 is limited to 16 KB.
+	UserDataParts []tencentCloudUserDataPart `mapstructure:"user_data_parts" required:"false"`
 	// host name.
 	HostName string `mapstructure:"host_name" required:"false"`
+	// The cam role your cvm will be bound to, so that provisioners can get
+	// temporary credentials of the role from metadata.
+	CamRoleName string `mapstructure:"cam_role_name" required:"false"`
+	// Whether to unbind `cam_role_name` from cvm before creating image.
+	// Default value is false.
+	DetachCamRole bool `mapstructure:"detach_cam_role" required:"false"`
 	// Key/value pair tags to apply to the instance that is *launched* to
 	// create the image. These tags are *not* applied to the resulting image.
 	RunTags map[string]string `mapstructure:"run_tags" required:"false"`
+	// Key/value pair tags that will be applied to every resource the builder
+	// creates, including the instance, vpc, subnet, security group, keypair,
+	// eip, disks, image and snapshots. `packer-build-id` and
+	// `packer-build-name` tags are always added. Build info such as
+	// `{{ .BuildRegion }}`, `{{ .SourceImageId }}` and `{{ .SourceImageName }}`
+	// can be used in the tags as template.
+	DefaultTags map[string]string `mapstructure:"default_tags" required:"false"`
 	// Same as [`run_tags`](#run_tags) but defined as a singular repeatable
 	// block containing a `key` and a `value` field. In HCL2 mode the
 	// [`dynamic_block`](/packer/docs/templates/hcl_templates/expressions#dynamic-blocks)
@@ -107,31 +195,181 @@ type TencentCloudRunConfig struct {
 	RunTag config.KeyValues `mapstructure:"run_tag" required:"false"`
 
 	// Communicator settings
-	Comm         communicator.Config `mapstructure:",squash"`
-	SSHPrivateIp bool                `mapstructure:"ssh_private_ip"`
+	Comm communicator.Config `mapstructure:",squash"`
+	// Whether to connect to the private ip of your cvm, same as setting
+	// `ssh_interface` to `private_ip`.
+	SSHPrivateIp bool `mapstructure:"ssh_private_ip" required:"false"`
+	// The address of your cvm the communicator (SSH or WinRM) connects to,
+	// values can be:
+	// -  `public_ip` - The public ip of cvm.
+	// -  `private_ip` - The private ip of cvm.
+	// -  `eip` - The eip associated to cvm, requires `associate_eip`.
+	// -  `ipv6` - The ipv6 address of cvm, requires `enable_ipv6`.
+	// -  `private_dns` - The `host_name` of cvm, it should be resolvable
+	//    where packer runs, e.g. by a private dns zone.
+	// Default value is `eip` when `associate_eip` is true, `public_ip` when
+	// `associate_public_ip_address` is true, otherwise `private_ip`.
+	SSHInterface string `mapstructure:"ssh_interface" required:"false"`
+	// The timeout waiting for cvm to have an address of `ssh_interface`.
+	// Default value is `5m`.
+	SSHInterfaceTimeout time.Duration `mapstructure:"ssh_interface_timeout" required:"false"`
 	// If true, Packer will not create a final image. Defaults to `false`.
 	SkipCreateImage bool `mapstructure:"skip_create_image" required:"false"`
+	// The os type of source image, values can be `linux` (default) and `windows`.
+	// When it is `windows`, Packer will:
+	// -  use `winrm` communicator with `Administrator` as `winrm_username` by default.
+	// -  generate a random password for cvm if `winrm_password` is not set.
+	// -  enable WinRM over HTTPS with a self-signed certificate by user_data,
+	//    so `user_data` and `user_data_file` can not be set.
+	// -  set `winrm_use_ssl` and `winrm_insecure` to true, unless they are
+	//    set explicitly, e.g. for the images with a trusted certificate.
+	// -  allow WinRM port in the temporary security group.
+	// -  run sysprep through WinRM and wait for cvm to shut down before
+	//    creating image if `sysprep` is true.
+	OsType string `mapstructure:"os_type" required:"false"`
 
 	DisableSecurityService   bool `mapstructure:"disable_security_service" required:"false"`
 	DisableMonitorService    bool `mapstructure:"disable_monitor_service" required:"false"`
 	DisableAutomationService bool `mapstructure:"disable_automation_service" required:"false"`
 
 	PlacementGroupId string `mapstructure:"placement_group_id" required:"false"`
+	// The placement of your cvm, which allows for the following argument:
+	// -  `dedicated_host_ids` - The dedicated host (CDH) candidate list your
+	//    cvm will be launched on. Will try to launch on the hosts in the zone
+	//    of each subnet in order. `instance_charge_type` should be `CDHPAID`.
+	// -  `project_id` - The project your cvm belongs to. Default value is 0.
+	// -  `host_ips` - Same as `dedicated_host_ids` but specified by the ip
+	//    addresses of hosts.
+	Placement tencentCloudPlacement `mapstructure:"placement" required:"false"`
+
+	// The launch template your cvm will be launched by, the settings of
+	// the template are used as the base, and the settings specified
+	// explicitly override them. `source_image_id`, `instance_type`, vpc,
+	// subnet, security groups and root disk are taken from the template if
+	// not specified. The instance tags of the template are kept, and the
+	// tags of the builder override them.
+	LaunchTemplateId string `mapstructure:"launch_template_id" required:"false"`
+	// The version of `launch_template_id`, the default version of the
+	// template is used if not set.
+	LaunchTemplateVersion uint64 `mapstructure:"launch_template_version" required:"false"`
+
+	launchTemplate *cvm.LaunchTemplateVersionData
+	// explicitKeys are the options set in the configuration, which are not
+	// overridden by the defaults of os_type
+	explicitKeys map[string]bool
 }
 
-var ValidCBSType = []string{
-	"LOCAL_BASIC", "LOCAL_SSD", "CLOUD_BASIC", "CLOUD_SSD", "CLOUD_PREMIUM", "CLOUD_BSSD",
+// SetExplicitKeys records the options set in the configuration, such as the
+// keys of the metadata decoded.
+func (cf *TencentCloudRunConfig) SetExplicitKeys(keys []string) {
+	cf.explicitKeys = make(map[string]bool)
+	for _, key := range keys {
+		cf.explicitKeys[key] = true
+	}
+}
+
+// prepareLaunchTemplate takes the settings not specified explicitly from
+// launch template, and checks the conflicts between them.
+func (cf *TencentCloudRunConfig) prepareLaunchTemplate() []error {
+	data := cf.launchTemplate
+	if data == nil {
+		return nil
+	}
+
+	var errs []error
+
+	if cf.SourceImageId == "" && cf.SourceImageName == "" && data.ImageId != nil {
+		cf.SourceImageId = *data.ImageId
+	}
+
+	if cf.InstanceType == "" && len(cf.InstanceTypeCandidates) == 0 && data.InstanceType != nil {
+		cf.InstanceType = *data.InstanceType
+	}
+
+	// the vpc and subnet of launch template are used only if no network is specified
+	vpcId, subnetId := launchTemplateVpc(data)
+	if cf.VpcId == "" && cf.VpcName == "" && cf.CidrBlock == "" && vpcId != "" {
+		cf.VpcId = vpcId
+		if cf.SubnetId == "" && cf.SubnetName == "" && cf.SubnectCidrBlock == "" {
+			cf.SubnetId = subnetId
+		}
+	}
+
+	// the system disk is overridden as a whole, so fill it with launch template
+	if data.SystemDisk != nil {
+		if cf.DiskType == "" && data.SystemDisk.DiskType != nil {
+			cf.DiskType = *data.SystemDisk.DiskType
+		}
+		if cf.DiskSize <= 0 && data.SystemDisk.DiskSize != nil {
+			cf.DiskSize = *data.SystemDisk.DiskSize
+		}
+	}
+
+	if cf.AssociateEip && launchTemplatePublicIp(data) {
+		errs = append(errs, errors.New("associate_eip conflicts with the public ip assigned by launch template"))
+	}
+
+	if data.InstanceMarketOptions != nil && cf.InstanceChargeType != "" && cf.InstanceChargeType != "SPOTPAID" {
+		errs = append(errs, fmt.Errorf("instance_charge_type(%s) conflicts with the spot options of launch template", cf.InstanceChargeType))
+	}
+
+	if data.InstanceCount != nil && *data.InstanceCount > 1 {
+		errs = append(errs, fmt.Errorf("launch template launches %d instances, only 1 is supported", *data.InstanceCount))
+	}
+
+	return errs
 }
 
 func (cf *TencentCloudRunConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
 	packerId := fmt.Sprintf("packer_%s", uuid.TimeOrderedUUID()[:8])
+
+	switch cf.OsType {
+	case "":
+		cf.OsType = "linux"
+	case "linux":
+	case "windows":
+		if cf.Comm.Type == "" {
+			cf.Comm.Type = "winrm"
+		}
+		if cf.Comm.Type == "winrm" {
+			if cf.Comm.WinRMUser == "" {
+				cf.Comm.WinRMUser = "Administrator"
+			}
+			if cf.Comm.WinRMPassword == "" {
+				password, err := GeneratePassword(16)
+				if err != nil {
+					errs = append(errs, err)
+				}
+				cf.Comm.WinRMPassword = password
+			}
+			// WinRM over HTTPS is enabled with a self-signed certificate
+			if !cf.explicitKeys["winrm_use_ssl"] {
+				cf.Comm.WinRMUseSSL = true
+			}
+			if !cf.explicitKeys["winrm_insecure"] {
+				cf.Comm.WinRMInsecure = true
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("specified os_type(%s) is invalid", cf.OsType))
+	}
+
 	if cf.Comm.SSHKeyPairName == "" && cf.Comm.SSHTemporaryKeyPairName == "" &&
 		cf.Comm.SSHPrivateKeyFile == "" && cf.Comm.SSHPassword == "" && cf.Comm.WinRMPassword == "" {
 		//tencentcloud support key pair name length max to 25
 		cf.Comm.SSHTemporaryKeyPairName = packerId
 	}
 
-	errs := cf.Comm.Prepare(ctx)
+	errs = append(errs, cf.Comm.Prepare(ctx)...)
+
+	if cf.LaunchTemplateId != "" && !CheckResourceIdFormat("lt", cf.LaunchTemplateId) {
+		errs = append(errs, errors.New("launch_template_id wrong format"))
+	} else if cf.LaunchTemplateId == "" && cf.LaunchTemplateVersion > 0 {
+		errs = append(errs, errors.New("launch_template_version requires launch_template_id to be set"))
+	}
+	errs = append(errs, cf.prepareLaunchTemplate()...)
+
 	if cf.SourceImageId == "" && cf.SourceImageName == "" {
 		errs = append(errs, errors.New("source_image_id or source_image_name must be specified"))
 	}
@@ -157,6 +395,21 @@ func (cf *TencentCloudRunConfig) Prepare(ctx *interpolate.Context) []error {
 		}
 	}
 
+	if len(cf.UserDataParts) > 0 && (cf.UserData != "" || cf.UserDataFile != "") {
+		errs = append(errs, errors.New("user_data_parts conflicts with user_data and user_data_file"))
+	}
+	for i, part := range cf.UserDataParts {
+		errs = append(errs, part.prepare(i)...)
+	}
+
+	if cf.OsType == "windows" && (cf.UserData != "" || cf.UserDataFile != "" || len(cf.UserDataParts) > 0) {
+		errs = append(errs, errors.New("user_data, user_data_file or user_data_parts can't be specified when os_type is windows"))
+	}
+
+	if err := cf.checkUserDataSize(); err != nil {
+		errs = append(errs, err)
+	}
+
 	// 添加SubnetName的判断，指定了SubnetName会自动搜索SubnetId
 	if (cf.VpcId != "" || cf.CidrBlock != "") && cf.SubnetId == "" && cf.SubnetName == "" && cf.SubnectCidrBlock == "" {
 		errs = append(errs, errors.New("if vpc cidr_block is specified, then "+
@@ -181,13 +434,57 @@ func (cf *TencentCloudRunConfig) Prepare(ctx *interpolate.Context) []error {
 		}
 	}
 
-	if cf.SecurityGroupId == "" && cf.SecurityGroupName == "" {
+	if cf.VpcIp != "" && len(cf.VpcIpCandidates) != 0 {
+		errs = append(errs, errors.New("only one of vpc_ip or vpc_ip_candidates can be specified"))
+	} else if cf.VpcIp != "" {
+		// normalize
+		cf.VpcIpCandidates = []string{cf.VpcIp}
+	}
+
+	for _, ip := range cf.VpcIpCandidates {
+		if net.ParseIP(ip).To4() == nil {
+			errs = append(errs, fmt.Errorf("specified vpc ip(%s) is invalid", ip))
+			continue
+		}
+		// subnet will be created with subnect_cidr_block, so we can check it now
+		if cf.SubnetId == "" && cf.SubnetName == "" && !CheckIpInCidr(ip, cf.SubnectCidrBlock) {
+			errs = append(errs, fmt.Errorf("specified vpc ip(%s) is not in subnect_cidr_block(%s)", ip, cf.SubnectCidrBlock))
+		}
+	}
+
+	for _, id := range cf.Placement.DedicatedHostIds {
+		if !CheckResourceIdFormat("host", id) {
+			errs = append(errs, fmt.Errorf("specified dedicated host id(%s) is invalid", id))
+		}
+	}
+
+	for _, ip := range cf.Placement.HostIps {
+		if net.ParseIP(ip) == nil {
+			errs = append(errs, fmt.Errorf("specified host ip(%s) is invalid", ip))
+		}
+	}
+
+	if cf.Placement.ProjectId < 0 {
+		errs = append(errs, fmt.Errorf("specified project_id(%d) is invalid", cf.Placement.ProjectId))
+	}
+
+	// instances on dedicated hosts are charged as CDHPAID
+	if len(cf.Placement.DedicatedHostIds) != 0 || len(cf.Placement.HostIps) != 0 {
+		if cf.InstanceChargeType == "" {
+			cf.InstanceChargeType = "CDHPAID"
+		} else if cf.InstanceChargeType != "CDHPAID" {
+			errs = append(errs, fmt.Errorf("instance_charge_type(%s) conflicts with dedicated hosts, it should be CDHPAID", cf.InstanceChargeType))
+		}
+	}
+
+	// security groups of launch template are used if not specified
+	if cf.SecurityGroupId == "" && cf.SecurityGroupName == "" &&
+		(cf.launchTemplate == nil || len(cf.launchTemplate.SecurityGroupIds) == 0) {
 		cf.SecurityGroupName = packerId
 	}
 
-	if cf.DiskType != "" && !checkDiskType(cf.DiskType) {
-		errs = append(errs, errors.New(fmt.Sprintf("specified disk_type(%s) is invalid", cf.DiskType)))
-	} else if cf.DiskType == "" {
+	// disk types are checked against the zone when running instance
+	if cf.DiskType == "" {
 		cf.DiskType = "CLOUD_PREMIUM"
 	}
 
@@ -195,6 +492,21 @@ func (cf *TencentCloudRunConfig) Prepare(ctx *interpolate.Context) []error {
 		cf.DiskSize = 50
 	}
 
+	if cf.DiskKmsKeyId != "" && !cf.DiskEncrypted {
+		errs = append(errs, errors.New("disk_kms_key_id requires disk_encrypted to be true"))
+	}
+
+	for i, disk := range cf.DataDisks {
+		if disk.DiskKmsKeyId != "" && !disk.DiskEncrypted {
+			errs = append(errs, fmt.Errorf("data_disks[%d]: disk_kms_key_id requires disk_encrypted to be true", i))
+		}
+		if disk.ThroughputPerformance < 0 {
+			errs = append(errs, fmt.Errorf("data_disks[%d]: throughput_performance should not be negative", i))
+		} else if disk.ThroughputPerformance > 0 && disk.DiskType != "CLOUD_HSSD" && disk.DiskType != "CLOUD_TSSD" {
+			errs = append(errs, fmt.Errorf("data_disks[%d]: throughput_performance is only supported by CLOUD_HSSD and CLOUD_TSSD", i))
+		}
+	}
+
 	validChargeTypes := map[string]int{
 		"TRAFFIC_POSTPAID_BY_HOUR":   0,
 		"BANDWIDTH_POSTPAID_BY_HOUR": 0,
@@ -214,14 +526,75 @@ func (cf *TencentCloudRunConfig) Prepare(ctx *interpolate.Context) []error {
 		}
 	}
 
-	if cf.AssociatePublicIpAddress && cf.InternetMaxBandwidthOut <= 0 {
+	if cf.AssociatePublicIpAddress && cf.AssociateEip {
+		errs = append(errs, errors.New("only one of associate_public_ip_address or associate_eip can be specified"))
+	}
+
+	if len(cf.EipIds) != 0 && len(cf.EipAddresses) != 0 {
+		errs = append(errs, errors.New("only one of eip_ids or eip_addresses can be specified"))
+	} else if (len(cf.EipIds) != 0 || len(cf.EipAddresses) != 0) && !cf.AssociateEip {
+		errs = append(errs, errors.New("eip_ids or eip_addresses requires associate_eip to be true"))
+	}
+
+	for _, id := range cf.EipIds {
+		if !CheckResourceIdFormat("eip", id) {
+			errs = append(errs, fmt.Errorf("specified eip id(%s) is invalid", id))
+		}
+	}
+
+	for _, address := range cf.EipAddresses {
+		if net.ParseIP(address).To4() == nil {
+			errs = append(errs, fmt.Errorf("specified eip address(%s) is invalid", address))
+		}
+	}
+
+	if (cf.AssociatePublicIpAddress || cf.AssociateEip) && cf.InternetMaxBandwidthOut <= 0 {
 		cf.InternetMaxBandwidthOut = 1
 	}
 
+	if cf.SSHPrivateIp {
+		if cf.SSHInterface == "" {
+			cf.SSHInterface = "private_ip"
+		} else if cf.SSHInterface != "private_ip" {
+			errs = append(errs, errors.New("ssh_private_ip conflicts with ssh_interface"))
+		}
+	}
+
+	switch cf.SSHInterface {
+	case "":
+		if cf.AssociateEip {
+			cf.SSHInterface = "eip"
+		} else if cf.AssociatePublicIpAddress || launchTemplatePublicIp(cf.launchTemplate) {
+			cf.SSHInterface = "public_ip"
+		} else {
+			cf.SSHInterface = "private_ip"
+		}
+	case "public_ip", "private_ip", "private_dns":
+	case "eip":
+		if !cf.AssociateEip {
+			errs = append(errs, errors.New("ssh_interface eip requires associate_eip to be true"))
+		}
+	case "ipv6":
+		if !cf.EnableIpv6 {
+			errs = append(errs, errors.New("ssh_interface ipv6 requires enable_ipv6 to be true"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("specified ssh_interface(%s) is invalid", cf.SSHInterface))
+	}
+
+	if cf.SSHInterfaceTimeout <= 0 {
+		cf.SSHInterfaceTimeout = 5 * time.Minute
+	}
+
 	if cf.InstanceName == "" {
 		cf.InstanceName = packerId
 	}
 
+	if cf.DetachCamRole && cf.CamRoleName == "" &&
+		(cf.launchTemplate == nil || cf.launchTemplate.CamRoleName == nil || *cf.launchTemplate.CamRoleName == "") {
+		errs = append(errs, errors.New("detach_cam_role requires cam_role_name to be set"))
+	}
+
 	if cf.HostName == "" {
 		cf.HostName = cf.InstanceName
 	}
@@ -240,12 +613,14 @@ func (cf *TencentCloudRunConfig) Prepare(ctx *interpolate.Context) []error {
 	return errs
 }
 
-func checkDiskType(diskType string) bool {
-	for _, valid := range ValidCBSType {
-		if valid == diskType {
-			return true
-		}
+// SensitiveValues returns the user data and the credentials of communicator,
+// which are never logged
+func (cf *TencentCloudRunConfig) SensitiveValues() []string {
+	return []string{
+		cf.UserData,
+		cf.Comm.SSHPassword,
+		cf.Comm.SSHBastionPassword,
+		cf.Comm.WinRMPassword,
+		string(cf.Comm.SSHPrivateKey),
 	}
-
-	return false
 }
@@ -10,9 +10,16 @@ import (
 // FlattencentCloudDataDisk is an auto-generated flat version of tencentCloudDataDisk.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlattencentCloudDataDisk struct {
-	DiskType   *string `mapstructure:"disk_type" cty:"disk_type" hcl:"disk_type"`
-	DiskSize   *int64  `mapstructure:"disk_size" cty:"disk_size" hcl:"disk_size"`
-	SnapshotId *string `mapstructure:"disk_snapshot_id" cty:"disk_snapshot_id" hcl:"disk_snapshot_id"`
+	DiskType              *string           `mapstructure:"disk_type" cty:"disk_type" hcl:"disk_type"`
+	DiskSize              *int64            `mapstructure:"disk_size" cty:"disk_size" hcl:"disk_size"`
+	SnapshotId            *string           `mapstructure:"disk_snapshot_id" cty:"disk_snapshot_id" hcl:"disk_snapshot_id"`
+	DiskEncrypted         *bool             `mapstructure:"disk_encrypted" cty:"disk_encrypted" hcl:"disk_encrypted"`
+	DiskKmsKeyId          *string           `mapstructure:"disk_kms_key_id" cty:"disk_kms_key_id" hcl:"disk_kms_key_id"`
+	DiskName              *string           `mapstructure:"disk_name" cty:"disk_name" hcl:"disk_name"`
+	ThroughputPerformance *int64            `mapstructure:"throughput_performance" cty:"throughput_performance" hcl:"throughput_performance"`
+	BurstPerformance      *bool             `mapstructure:"burst_performance" cty:"burst_performance" hcl:"burst_performance"`
+	DeleteWithInstance    *bool             `mapstructure:"delete_with_instance" cty:"delete_with_instance" hcl:"delete_with_instance"`
+	Tags                  map[string]string `mapstructure:"tags" cty:"tags" hcl:"tags"`
 }
 
 // FlatMapstructure returns a new FlattencentCloudDataDisk.
@@ -27,9 +34,72 @@ func (*tencentCloudDataDisk) FlatMapstructure() interface{ HCL2Spec() map[string
 // The decoded values from this spec will then be applied to a FlattencentCloudDataDisk.
 func (*FlattencentCloudDataDisk) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"disk_type":        &hcldec.AttrSpec{Name: "disk_type", Type: cty.String, Required: false},
-		"disk_size":        &hcldec.AttrSpec{Name: "disk_size", Type: cty.Number, Required: false},
-		"disk_snapshot_id": &hcldec.AttrSpec{Name: "disk_snapshot_id", Type: cty.String, Required: false},
+		"disk_type":              &hcldec.AttrSpec{Name: "disk_type", Type: cty.String, Required: false},
+		"disk_size":              &hcldec.AttrSpec{Name: "disk_size", Type: cty.Number, Required: false},
+		"disk_snapshot_id":       &hcldec.AttrSpec{Name: "disk_snapshot_id", Type: cty.String, Required: false},
+		"disk_encrypted":         &hcldec.AttrSpec{Name: "disk_encrypted", Type: cty.Bool, Required: false},
+		"disk_kms_key_id":        &hcldec.AttrSpec{Name: "disk_kms_key_id", Type: cty.String, Required: false},
+		"disk_name":              &hcldec.AttrSpec{Name: "disk_name", Type: cty.String, Required: false},
+		"throughput_performance": &hcldec.AttrSpec{Name: "throughput_performance", Type: cty.Number, Required: false},
+		"burst_performance":      &hcldec.AttrSpec{Name: "burst_performance", Type: cty.Bool, Required: false},
+		"delete_with_instance":   &hcldec.AttrSpec{Name: "delete_with_instance", Type: cty.Bool, Required: false},
+		"tags":                   &hcldec.AttrSpec{Name: "tags", Type: cty.Map(cty.String), Required: false},
+	}
+	return s
+}
+
+// FlattencentCloudPlacement is an auto-generated flat version of tencentCloudPlacement.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlattencentCloudPlacement struct {
+	DedicatedHostIds []string `mapstructure:"dedicated_host_ids" cty:"dedicated_host_ids" hcl:"dedicated_host_ids"`
+	ProjectId        *int64   `mapstructure:"project_id" cty:"project_id" hcl:"project_id"`
+	HostIps          []string `mapstructure:"host_ips" cty:"host_ips" hcl:"host_ips"`
+}
+
+// FlatMapstructure returns a new FlattencentCloudPlacement.
+// FlattencentCloudPlacement is an auto-generated flat version of tencentCloudPlacement.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*tencentCloudPlacement) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlattencentCloudPlacement)
+}
+
+// HCL2Spec returns the hcl spec of a tencentCloudPlacement.
+// This spec is used by HCL to read the fields of tencentCloudPlacement.
+// The decoded values from this spec will then be applied to a FlattencentCloudPlacement.
+func (*FlattencentCloudPlacement) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"dedicated_host_ids": &hcldec.AttrSpec{Name: "dedicated_host_ids", Type: cty.List(cty.String), Required: false},
+		"project_id":         &hcldec.AttrSpec{Name: "project_id", Type: cty.Number, Required: false},
+		"host_ips":           &hcldec.AttrSpec{Name: "host_ips", Type: cty.List(cty.String), Required: false},
+	}
+	return s
+}
+
+// FlattencentCloudUserDataPart is an auto-generated flat version of tencentCloudUserDataPart.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlattencentCloudUserDataPart struct {
+	ContentType *string `mapstructure:"content_type" cty:"content_type" hcl:"content_type"`
+	Content     *string `mapstructure:"content" cty:"content" hcl:"content"`
+	File        *string `mapstructure:"file" cty:"file" hcl:"file"`
+	Interpolate *bool   `mapstructure:"interpolate" cty:"interpolate" hcl:"interpolate"`
+}
+
+// FlatMapstructure returns a new FlattencentCloudUserDataPart.
+// FlattencentCloudUserDataPart is an auto-generated flat version of tencentCloudUserDataPart.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*tencentCloudUserDataPart) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlattencentCloudUserDataPart)
+}
+
+// HCL2Spec returns the hcl spec of a tencentCloudUserDataPart.
+// This spec is used by HCL to read the fields of tencentCloudUserDataPart.
+// The decoded values from this spec will then be applied to a FlattencentCloudUserDataPart.
+func (*FlattencentCloudUserDataPart) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"content_type": &hcldec.AttrSpec{Name: "content_type", Type: cty.String, Required: false},
+		"content":      &hcldec.AttrSpec{Name: "content", Type: cty.String, Required: false},
+		"file":         &hcldec.AttrSpec{Name: "file", Type: cty.String, Required: false},
+		"interpolate":  &hcldec.AttrSpec{Name: "interpolate", Type: cty.Bool, Required: false},
 	}
 	return s
 }
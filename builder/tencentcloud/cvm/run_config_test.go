@@ -9,6 +9,8 @@ import (
 	"testing"
 
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
 )
 
 func testConfig() *TencentCloudRunConfig {
@@ -105,6 +107,30 @@ func TestTencentCloudRunConfigPrepare_UserDataFile(t *testing.T) {
 	}
 }
 
+func TestTencentCloudRunConfigPrepare_UserDataParts(t *testing.T) {
+	cf := testConfig()
+	cf.UserDataParts = []tencentCloudUserDataPart{
+		{ContentType: "cloud-config", Content: "packages: [nginx]"},
+		{ContentType: "x-shellscript", Content: "#!/bin/sh", File: "script.sh"},
+		{ContentType: "jinja2"},
+		{ContentType: "x-shellscript", File: "not-exist-file"},
+		{ContentType: "text/plain", Content: "text"},
+	}
+	if errs := cf.Prepare(nil); len(errs) != 4 {
+		t.Fatalf("should have 4 errors of invalid parts: %v", errs)
+	}
+
+	cf.UserDataParts = cf.UserDataParts[:1]
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+
+	cf.UserData = "text user_data"
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error: user_data_parts conflicts with user_data")
+	}
+}
+
 func TestTencentCloudRunConfigPrepare_TemporaryKeyPairName(t *testing.T) {
 	cf := testConfig()
 	cf.Comm.SSHTemporaryKeyPairName = ""
@@ -142,3 +168,339 @@ func TestTencentCloudRunConfigPrepare_SSHPrivateIp(t *testing.T) {
 		t.Fatalf("invalud ssh_private_ip value: %v", cf.SSHPrivateIp)
 	}
 }
+
+func TestTencentCloudRunConfigPrepare_VpcIp(t *testing.T) {
+	cf := testConfig()
+	cf.VpcIp = "10.0.8.10"
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+
+	if len(cf.VpcIpCandidates) != 1 || cf.VpcIpCandidates[0] != "10.0.8.10" {
+		t.Fatalf("invalid vpc_ip_candidates value: %v", cf.VpcIpCandidates)
+	}
+
+	cf = testConfig()
+	cf.VpcIp = "10.0.8.10"
+	cf.VpcIpCandidates = []string{"10.0.8.11"}
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	cf = testConfig()
+	cf.VpcIpCandidates = []string{"10.0.8.11", "10.0.9.11"}
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error: vpc ip not in subnet cidr block")
+	}
+
+	cf = testConfig()
+	cf.VpcIpCandidates = []string{"not-an-ip"}
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error: invalid vpc ip")
+	}
+}
+
+func TestTencentCloudRunConfigPrepare_Eip(t *testing.T) {
+	cf := testConfig()
+	cf.AssociateEip = true
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+
+	if cf.InternetMaxBandwidthOut != 1 {
+		t.Fatalf("invalid internet_max_bandwidth_out value: %v", cf.InternetMaxBandwidthOut)
+	}
+
+	cf.AssociatePublicIpAddress = true
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	cf = testConfig()
+	cf.EipIds = []string{"eip-qwer1234"}
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error: associate_eip not set")
+	}
+
+	cf.AssociateEip = true
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+
+	cf.EipAddresses = []string{"1.1.1.1"}
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	cf.EipIds = []string{"eip-qwer"}
+	cf.EipAddresses = nil
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error: invalid eip id")
+	}
+}
+
+func TestTencentCloudRunConfigPrepare_SSHInterface(t *testing.T) {
+	cf := testConfig()
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+
+	if cf.SSHInterface != "private_ip" {
+		t.Fatalf("invalid ssh_interface value: %v", cf.SSHInterface)
+	}
+
+	cf = testConfig()
+	cf.AssociatePublicIpAddress = true
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+
+	if cf.SSHInterface != "public_ip" {
+		t.Fatalf("invalid ssh_interface value: %v", cf.SSHInterface)
+	}
+
+	cf = testConfig()
+	cf.SSHInterface = "ipv6"
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error: enable_ipv6 not set")
+	}
+
+	cf.EnableIpv6 = true
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+
+	cf.SSHInterface = "unknown"
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	cf = testConfig()
+	cf.AssociateEip = true
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+
+	if cf.SSHInterface != "eip" {
+		t.Fatalf("invalid ssh_interface value: %v", cf.SSHInterface)
+	}
+
+	cf = testConfig()
+	cf.SSHInterface = "eip"
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error: associate_eip not set")
+	}
+
+	cf = testConfig()
+	cf.SSHPrivateIp = true
+	cf.SSHInterface = "public_ip"
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	cf.SSHInterface = ""
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+
+	if cf.SSHInterface != "private_ip" {
+		t.Fatalf("invalid ssh_interface value: %v", cf.SSHInterface)
+	}
+}
+
+func TestTencentCloudRunConfigPrepare_Windows(t *testing.T) {
+	cf := testConfig()
+	cf.Comm = communicator.Config{}
+	cf.OsType = "windows"
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+
+	if cf.Comm.Type != "winrm" || cf.Comm.WinRMUser != "Administrator" {
+		t.Fatalf("invalid communicator value: %v, %v", cf.Comm.Type, cf.Comm.WinRMUser)
+	}
+
+	if len(cf.Comm.WinRMPassword) != 16 {
+		t.Fatalf("invalid winrm_password value: %v", cf.Comm.WinRMPassword)
+	}
+
+	if !cf.Comm.WinRMUseSSL || cf.Comm.WinRMPort != 5986 {
+		t.Fatalf("invalid winrm ssl value: %v, %v", cf.Comm.WinRMUseSSL, cf.Comm.WinRMPort)
+	}
+
+	if cf.Comm.SSHTemporaryKeyPairName != "" {
+		t.Fatalf("invalid ssh key pair value: %v", cf.Comm.SSHTemporaryKeyPairName)
+	}
+
+	cf.UserData = "text user_data"
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	cf = testConfig()
+	cf.Comm = communicator.Config{}
+	cf.OsType = "windows"
+	cf.SetExplicitKeys([]string{"winrm_insecure"})
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+
+	if !cf.Comm.WinRMUseSSL || cf.Comm.WinRMInsecure {
+		t.Fatalf("invalid winrm ssl value: %v, %v", cf.Comm.WinRMUseSSL, cf.Comm.WinRMInsecure)
+	}
+
+	cf = testConfig()
+	cf.OsType = "unknown"
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestTencentCloudRunConfigPrepare_DiskEncrypted(t *testing.T) {
+	cf := testConfig()
+	cf.DiskKmsKeyId = "kms-key"
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	cf.DiskEncrypted = true
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+
+	cf.DataDisks = []tencentCloudDataDisk{
+		{
+			DiskType:     "CLOUD_PREMIUM",
+			DiskSize:     50,
+			DiskKmsKeyId: "kms-key",
+		},
+	}
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	cf.DataDisks[0].DiskEncrypted = true
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+}
+
+func TestTencentCloudRunConfigPrepare_DataDisks(t *testing.T) {
+	cf := testConfig()
+	cf.DataDisks = []tencentCloudDataDisk{
+		{
+			DiskType:              "CLOUD_HSSD",
+			DiskSize:              100,
+			DiskName:              "data",
+			ThroughputPerformance: 100,
+			Tags:                  map[string]string{"foo": "bar"},
+		},
+	}
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+
+	cf.DataDisks[0].DiskType = "CLOUD_PREMIUM"
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	cf.DataDisks[0].DiskType = "CLOUD_TSSD"
+	cf.DataDisks[0].ThroughputPerformance = -1
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestTencentCloudRunConfigPrepare_CamRole(t *testing.T) {
+	cf := testConfig()
+	cf.DetachCamRole = true
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	cf.CamRoleName = "packer-build"
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+}
+
+func TestTencentCloudRunConfigPrepare_LaunchTemplate(t *testing.T) {
+	cf := testConfig()
+	cf.LaunchTemplateVersion = 2
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	cf = testConfig()
+	cf.LaunchTemplateId = "lt-qwer1234"
+	cf.SourceImageId = ""
+	cf.InstanceTypeCandidates = nil
+	cf.launchTemplate = &cvm.LaunchTemplateVersionData{
+		ImageId:      common.StringPtr("img-asdf1234"),
+		InstanceType: common.StringPtr("S5.MEDIUM4"),
+		SystemDisk: &cvm.SystemDisk{
+			DiskType: common.StringPtr("CLOUD_SSD"),
+			DiskSize: common.Int64Ptr(100),
+		},
+		VirtualPrivateCloud: &cvm.VirtualPrivateCloud{
+			VpcId:    common.StringPtr("vpc-qwer1234"),
+			SubnetId: common.StringPtr("subnet-qwer1234"),
+		},
+		SecurityGroupIds: common.StringPtrs([]string{"sg-qwer1234"}),
+		InternetAccessible: &cvm.InternetAccessible{
+			PublicIpAssigned: common.BoolPtr(true),
+		},
+	}
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+
+	if cf.SourceImageId != "img-asdf1234" || cf.InstanceTypeCandidates[0] != "S5.MEDIUM4" {
+		t.Fatalf("invalid launch template value: %v, %v", cf.SourceImageId, cf.InstanceTypeCandidates)
+	}
+
+	if cf.VpcId != "vpc-qwer1234" || cf.SubnetId != "subnet-qwer1234" || cf.SecurityGroupName != "" {
+		t.Fatalf("invalid network value: %v, %v, %v", cf.VpcId, cf.SubnetId, cf.SecurityGroupName)
+	}
+
+	if cf.DiskType != "CLOUD_SSD" || cf.DiskSize != 100 || cf.SSHInterface != "public_ip" {
+		t.Fatalf("invalid value: %v, %v, %v", cf.DiskType, cf.DiskSize, cf.SSHInterface)
+	}
+
+	cf.SSHInterface = ""
+	cf.AssociateEip = true
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestTencentCloudRunConfigPrepare_Placement(t *testing.T) {
+	cf := testConfig()
+	cf.Placement.DedicatedHostIds = []string{"host-qwer1234"}
+	cf.Placement.HostIps = []string{"10.0.0.10"}
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+
+	if cf.InstanceChargeType != "CDHPAID" {
+		t.Fatalf("invalid instance_charge_type value: %v", cf.InstanceChargeType)
+	}
+
+	cf.InstanceChargeType = "POSTPAID_BY_HOUR"
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	cf = testConfig()
+	cf.Placement.DedicatedHostIds = []string{"qwer1234"}
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+
+	cf = testConfig()
+	cf.Placement.HostIps = []string{"10.0.0"}
+	if err := cf.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+}
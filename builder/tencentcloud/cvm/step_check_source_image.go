@@ -13,7 +13,8 @@ import (
 )
 
 type stepCheckSourceImage struct {
-	sourceImageId string
+	SourceImageId   string
+	SourceImageName string
 }
 
 func (s *stepCheckSourceImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -21,16 +22,15 @@ func (s *stepCheckSourceImage) Run(ctx context.Context, state multistep.StateBag
 		imageNameRegex *regexp.Regexp
 		err            error
 	)
-	config := state.Get("config").(*Config)
-	client := state.Get("cvm_client").(*cvm.Client)
+	client := state.Get("cvm_client").(CVMAPI)
 
-	Say(state, config.SourceImageId, "Trying to check source image")
+	Say(state, s.SourceImageId, "Trying to check source image")
 
 	req := cvm.NewDescribeImagesRequest()
-	if config.SourceImageId != "" {
-		req.ImageIds = []*string{&config.SourceImageId}
+	if s.SourceImageId != "" {
+		req.ImageIds = []*string{&s.SourceImageId}
 	} else {
-		imageNameRegex, err = regexp.Compile(config.SourceImageName)
+		imageNameRegex, err = regexp.Compile(s.SourceImageName)
 		if err != nil {
 			return Halt(state, fmt.Errorf("regex compilation error"), "Bad input")
 		}
@@ -38,7 +38,7 @@ func (s *stepCheckSourceImage) Run(ctx context.Context, state multistep.StateBag
 	var resp *cvm.DescribeImagesResponse
 	err = Retry(ctx, func(ctx context.Context) error {
 		var err error
-		resp, err = client.DescribeImages(req)
+		resp, err = client.DescribeImagesWithContext(ctx, req)
 		return err
 	})
 	if err != nil {
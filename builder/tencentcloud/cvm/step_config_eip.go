@@ -0,0 +1,211 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
+)
+
+type stepConfigEip struct {
+	AssociateEip            bool
+	EipIds                  []string
+	EipAddresses            []string
+	InternetChargeType      string
+	InternetMaxBandwidthOut int64
+	BandwidthPackageId      string
+	addressId               string
+	isCreate                bool
+	isAssociated            bool
+}
+
+func (s *stepConfigEip) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if !s.AssociateEip {
+		return multistep.ActionContinue
+	}
+
+	vpcClient := state.Get("vpc_client").(VPCAPI)
+	instanceId := state.Get("instance_id").(string)
+
+	if len(s.EipIds) != 0 || len(s.EipAddresses) != 0 {
+		Say(state, "Trying to use existing eip", "")
+		address, err := s.getUnbindAddress(ctx, vpcClient)
+		if err != nil {
+			return Halt(state, err, "Failed to get eip info")
+		}
+		if address == nil {
+			return Halt(state, fmt.Errorf("no unbind eip in the specified eips"), "")
+		}
+		s.addressId = *address.AddressId
+		Message(state, fmt.Sprintf("%s(%s)", s.addressId, *address.AddressIp), "Eip found")
+	} else {
+		Say(state, "Trying to allocate a new eip", "")
+		tags, err := ResourceTags(state, nil)
+		if err != nil {
+			return Halt(state, err, "Failed to get tags")
+		}
+		req := vpc.NewAllocateAddressesRequest()
+		req.AddressCount = common.Int64Ptr(1)
+		req.Tags = vpcTags(tags)
+		if s.InternetChargeType != "" {
+			req.InternetChargeType = &s.InternetChargeType
+		}
+		if s.InternetMaxBandwidthOut > 0 {
+			req.InternetMaxBandwidthOut = &s.InternetMaxBandwidthOut
+		}
+		if s.BandwidthPackageId != "" {
+			req.BandwidthPackageId = &s.BandwidthPackageId
+		}
+		var resp *vpc.AllocateAddressesResponse
+		err = Retry(ctx, func(ctx context.Context) error {
+			var e error
+			resp, e = vpcClient.AllocateAddressesWithContext(ctx, req)
+			return e
+		})
+		if err != nil {
+			return Halt(state, err, "Failed to allocate eip")
+		}
+		if len(resp.Response.AddressSet) != 1 {
+			return Halt(state, fmt.Errorf("expect 1 eip id, got %d", len(resp.Response.AddressSet)), "Failed to allocate eip")
+		}
+
+		s.isCreate = true
+		s.addressId = *resp.Response.AddressSet[0]
+		Message(state, s.addressId, "Eip allocated")
+
+		if _, err = WaitForAddress(ctx, vpcClient, s.addressId, "UNBIND", 300); err != nil {
+			return Halt(state, err, "Failed to wait for eip ready")
+		}
+	}
+
+	Say(state, fmt.Sprintf("%s to instance(%s)", s.addressId, instanceId), "Trying to associate eip")
+	req := vpc.NewAssociateAddressRequest()
+	req.AddressId = &s.addressId
+	req.InstanceId = &instanceId
+	err := Retry(ctx, func(ctx context.Context) error {
+		_, e := vpcClient.AssociateAddressWithContext(ctx, req)
+		return e
+	})
+	if err != nil {
+		return Halt(state, err, "Failed to associate eip")
+	}
+	s.isAssociated = true
+
+	Message(state, "Waiting for eip associated", "")
+	address, err := WaitForAddress(ctx, vpcClient, s.addressId, "BIND", 300)
+	if err != nil {
+		return Halt(state, err, "Failed to wait for eip associated")
+	}
+
+	// refresh instance so that the eip shows up in its public ip addresses
+	client := state.Get("cvm_client").(CVMAPI)
+	describeReq := cvm.NewDescribeInstancesRequest()
+	describeReq.InstanceIds = []*string{&instanceId}
+	var describeResp *cvm.DescribeInstancesResponse
+	err = Retry(ctx, func(ctx context.Context) error {
+		var e error
+		describeResp, e = client.DescribeInstancesWithContext(ctx, describeReq)
+		return e
+	})
+	if err != nil {
+		return Halt(state, err, "Failed to get instance info")
+	}
+	if len(describeResp.Response.InstanceSet) == 0 {
+		return Halt(state, fmt.Errorf("instance(%s) not exist", instanceId), "Failed to get instance info")
+	}
+
+	state.Put("instance", describeResp.Response.InstanceSet[0])
+	state.Put("eip_address", *address.AddressIp)
+	Message(state, *address.AddressIp, "Eip associated")
+
+	return multistep.ActionContinue
+}
+
+// getUnbindAddress returns the first unbind eip in the specified eip pool
+func (s *stepConfigEip) getUnbindAddress(ctx context.Context, vpcClient VPCAPI) (*vpc.Address, error) {
+	req := vpc.NewDescribeAddressesRequest()
+	if len(s.EipIds) != 0 {
+		req.AddressIds = common.StringPtrs(s.EipIds)
+	} else {
+		req.Filters = []*vpc.Filter{
+			{
+				Name:   common.StringPtr("address-ip"),
+				Values: common.StringPtrs(s.EipAddresses),
+			},
+		}
+	}
+	var resp *vpc.DescribeAddressesResponse
+	err := Retry(ctx, func(ctx context.Context) error {
+		var e error
+		resp, e = vpcClient.DescribeAddressesWithContext(ctx, req)
+		return e
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// keep the order of the pool
+	addresses := make(map[string]*vpc.Address)
+	for _, address := range resp.Response.AddressSet {
+		if *address.AddressStatus == "UNBIND" {
+			addresses[*address.AddressId] = address
+			addresses[*address.AddressIp] = address
+		}
+	}
+	for _, key := range append(s.EipIds, s.EipAddresses...) {
+		if address, ok := addresses[key]; ok {
+			return address, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *stepConfigEip) Cleanup(state multistep.StateBag) {
+	if s.addressId == "" {
+		return
+	}
+
+	ctx := context.TODO()
+	vpcClient := state.Get("vpc_client").(VPCAPI)
+
+	SayClean(state, "eip")
+
+	if s.isAssociated {
+		req := vpc.NewDisassociateAddressRequest()
+		req.AddressId = &s.addressId
+		err := Retry(ctx, func(ctx context.Context) error {
+			_, e := vpcClient.DisassociateAddressWithContext(ctx, req)
+			return e
+		})
+		if err != nil {
+			Leak(state, err, "eip", s.addressId)
+			return
+		}
+	}
+
+	if !s.isCreate {
+		return
+	}
+
+	if _, err := WaitForAddress(ctx, vpcClient, s.addressId, "UNBIND", 300); err != nil {
+		Leak(state, err, "eip", s.addressId)
+		return
+	}
+
+	req := vpc.NewReleaseAddressesRequest()
+	req.AddressIds = []*string{&s.addressId}
+	err := Retry(ctx, func(ctx context.Context) error {
+		_, e := vpcClient.ReleaseAddressesWithContext(ctx, req)
+		return e
+	})
+	if err != nil {
+		Leak(state, err, "eip", s.addressId)
+	}
+}
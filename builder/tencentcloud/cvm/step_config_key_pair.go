@@ -23,7 +23,7 @@ type stepConfigKeyPair struct {
 }
 
 func (s *stepConfigKeyPair) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
-	client := state.Get("cvm_client").(*cvm.Client)
+	client := state.Get("cvm_client").(CVMAPI)
 
 	if s.Comm.SSHPrivateKeyFile != "" {
 		Say(state, "Using existing SSH private key", "")
@@ -32,6 +32,7 @@ func (s *stepConfigKeyPair) Run(ctx context.Context, state multistep.StateBag) m
 			return Halt(state, err, fmt.Sprintf("Failed to load configured private key(%s)", s.Comm.SSHPrivateKeyFile))
 		}
 		s.Comm.SSHPrivateKey = privateKeyBytes
+		LogSecrets(string(privateKeyBytes))
 		Message(state, fmt.Sprintf("Loaded %d bytes private key data", len(s.Comm.SSHPrivateKey)), "")
 		return multistep.ActionContinue
 	}
@@ -53,15 +54,28 @@ func (s *stepConfigKeyPair) Run(ctx context.Context, state multistep.StateBag) m
 
 	Say(state, s.Comm.SSHTemporaryKeyPairName, "Trying to create a new keypair")
 
+	tags, err := ResourceTags(state, nil)
+	if err != nil {
+		return Halt(state, err, "Failed to get tags")
+	}
+
 	req := cvm.NewCreateKeyPairRequest()
 	req.KeyName = &s.Comm.SSHTemporaryKeyPairName
 	defaultProjectId := int64(0)
 	req.ProjectId = &defaultProjectId
+	// TagSpecification of CreateKeyPair is not supported by the vendored sdk yet
+	params := map[string]interface{}{
+		"TagSpecification": []map[string]interface{}{
+			{
+				"ResourceType": "keypair",
+				"Tags":         CvmTags(tags),
+			},
+		},
+	}
 	var resp *cvm.CreateKeyPairResponse
-	err := Retry(ctx, func(ctx context.Context) error {
-		var e error
-		resp, e = client.CreateKeyPair(req)
-		return e
+	err = Retry(ctx, func(ctx context.Context) error {
+		resp = cvm.NewCreateKeyPairResponse()
+		return SendWithExtraParams(ctx, client, req, params, resp)
 	})
 	if err != nil {
 		return Halt(state, err, "Failed to create keypair")
@@ -74,6 +88,7 @@ func (s *stepConfigKeyPair) Run(ctx context.Context, state multistep.StateBag) m
 
 	s.Comm.SSHKeyPairName = *resp.Response.KeyPair.KeyId
 	s.Comm.SSHPrivateKey = []byte(*resp.Response.KeyPair.PrivateKey)
+	LogSecrets(*resp.Response.KeyPair.PrivateKey)
 
 	if s.Debug {
 		Message(state, fmt.Sprintf("Saving temporary key to %s for debug purposes", s.DebugKeyPath), "")
@@ -101,23 +116,23 @@ func (s *stepConfigKeyPair) Cleanup(state multistep.StateBag) {
 	}
 
 	ctx := context.TODO()
-	client := state.Get("cvm_client").(*cvm.Client)
+	client := state.Get("cvm_client").(CVMAPI)
 
 	SayClean(state, "keypair")
 
 	req := cvm.NewDeleteKeyPairsRequest()
 	req.KeyIds = []*string{&s.keyID}
 	err := Retry(ctx, func(ctx context.Context) error {
-		_, e := client.DeleteKeyPairs(req)
+		_, e := client.DeleteKeyPairsWithContext(ctx, req)
 		return e
 	})
 	if err != nil {
-		Error(state, err, fmt.Sprintf("Failed to delete keypair(%s), please delete it manually", s.keyID))
+		Leak(state, err, "keypair", s.keyID)
 	}
 
 	if s.Debug {
 		if err := os.Remove(s.DebugKeyPath); err != nil {
-			Error(state, err, fmt.Sprintf("Failed to delete debug key file(%s), please delete it manually", s.DebugKeyPath))
+			Leak(state, err, "debug key file", s.DebugKeyPath)
 		}
 	}
 }
@@ -6,8 +6,10 @@ package cvm
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
 	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
 )
 
@@ -15,11 +17,13 @@ type stepConfigSecurityGroup struct {
 	SecurityGroupId   string
 	SecurityGroupName string
 	Description       string
+	EnableIpv6        bool
+	WinRMPort         int
 	isCreate          bool
 }
 
 func (s *stepConfigSecurityGroup) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
-	vpcClient := state.Get("vpc_client").(*vpc.Client)
+	vpcClient := state.Get("vpc_client").(VPCAPI)
 
 	if len(s.SecurityGroupId) != 0 {
 		Say(state, s.SecurityGroupId, "Trying to use existing securitygroup")
@@ -28,7 +32,7 @@ func (s *stepConfigSecurityGroup) Run(ctx context.Context, state multistep.State
 		var resp *vpc.DescribeSecurityGroupsResponse
 		err := Retry(ctx, func(ctx context.Context) error {
 			var e error
-			resp, e = vpcClient.DescribeSecurityGroups(req)
+			resp, e = vpcClient.DescribeSecurityGroupsWithContext(ctx, req)
 			return e
 		})
 		if err != nil {
@@ -43,15 +47,28 @@ func (s *stepConfigSecurityGroup) Run(ctx context.Context, state multistep.State
 		return Halt(state, fmt.Errorf("The specified securitygroup(%s) does not exists", s.SecurityGroupId), "")
 	}
 
+	if s.SecurityGroupName == "" {
+		// security groups of launch template are used
+		state.Put("security_group_id", "")
+		Message(state, "Use security groups of launch template", "")
+		return multistep.ActionContinue
+	}
+
 	Say(state, "Trying to create a new securitygroup", "")
 
+	tags, err := ResourceTags(state, nil)
+	if err != nil {
+		return Halt(state, err, "Failed to get tags")
+	}
+
 	req := vpc.NewCreateSecurityGroupRequest()
 	req.GroupName = &s.SecurityGroupName
 	req.GroupDescription = &s.Description
+	req.Tags = vpcTags(tags)
 	var resp *vpc.CreateSecurityGroupResponse
-	err := Retry(ctx, func(ctx context.Context) error {
+	err = Retry(ctx, func(ctx context.Context) error {
 		var e error
-		resp, e = vpcClient.CreateSecurityGroup(req)
+		resp, e = vpcClient.CreateSecurityGroupWithContext(ctx, req)
 		return e
 	})
 	if err != nil {
@@ -68,6 +85,7 @@ func (s *stepConfigSecurityGroup) Run(ctx context.Context, state multistep.State
 	pReq := vpc.NewCreateSecurityGroupPoliciesRequest()
 	ACCEPT := "ACCEPT"
 	DEFAULT_CIDR := "0.0.0.0/0"
+	DEFAULT_IPV6_CIDR := "::/0"
 	pReq.SecurityGroupId = &s.SecurityGroupId
 	pReq.SecurityGroupPolicySet = &vpc.SecurityGroupPolicySet{
 		Ingress: []*vpc.SecurityGroupPolicy{
@@ -77,8 +95,27 @@ func (s *stepConfigSecurityGroup) Run(ctx context.Context, state multistep.State
 			},
 		},
 	}
+	if s.EnableIpv6 {
+		pReq.SecurityGroupPolicySet.Ingress = append(pReq.SecurityGroupPolicySet.Ingress, &vpc.SecurityGroupPolicy{
+			Ipv6CidrBlock: &DEFAULT_IPV6_CIDR,
+			Action:        &ACCEPT,
+		})
+	}
+	if s.WinRMPort != 0 {
+		TCP := "TCP"
+		winrmPort := strconv.Itoa(s.WinRMPort)
+		pReq.SecurityGroupPolicySet.Ingress = append([]*vpc.SecurityGroupPolicy{
+			{
+				Protocol:          &TCP,
+				Port:              &winrmPort,
+				CidrBlock:         &DEFAULT_CIDR,
+				Action:            &ACCEPT,
+				PolicyDescription: common.StringPtr("winrm for packer"),
+			},
+		}, pReq.SecurityGroupPolicySet.Ingress...)
+	}
 	err = Retry(ctx, func(ctx context.Context) error {
-		_, e := vpcClient.CreateSecurityGroupPolicies(pReq)
+		_, e := vpcClient.CreateSecurityGroupPoliciesWithContext(ctx, pReq)
 		return e
 	})
 	if err != nil {
@@ -96,8 +133,14 @@ func (s *stepConfigSecurityGroup) Run(ctx context.Context, state multistep.State
 			},
 		},
 	}
+	if s.EnableIpv6 {
+		pReq.SecurityGroupPolicySet.Egress = append(pReq.SecurityGroupPolicySet.Egress, &vpc.SecurityGroupPolicy{
+			Ipv6CidrBlock: &DEFAULT_IPV6_CIDR,
+			Action:        &ACCEPT,
+		})
+	}
 	err = Retry(ctx, func(ctx context.Context) error {
-		_, e := vpcClient.CreateSecurityGroupPolicies(pReq)
+		_, e := vpcClient.CreateSecurityGroupPoliciesWithContext(ctx, pReq)
 		return e
 	})
 	if err != nil {
@@ -115,17 +158,17 @@ func (s *stepConfigSecurityGroup) Cleanup(state multistep.StateBag) {
 	}
 
 	ctx := context.TODO()
-	vpcClient := state.Get("vpc_client").(*vpc.Client)
+	vpcClient := state.Get("vpc_client").(VPCAPI)
 
 	SayClean(state, "securitygroup")
 
 	req := vpc.NewDeleteSecurityGroupRequest()
 	req.SecurityGroupId = &s.SecurityGroupId
 	err := Retry(ctx, func(ctx context.Context) error {
-		_, e := vpcClient.DeleteSecurityGroup(req)
+		_, e := vpcClient.DeleteSecurityGroupWithContext(ctx, req)
 		return e
 	})
 	if err != nil {
-		Error(state, err, fmt.Sprintf("Failed to delete securitygroup(%s), please delete it manually", s.SecurityGroupId))
+		Leak(state, err, "securitygroup", s.SecurityGroupId)
 	}
 }
@@ -6,6 +6,7 @@ package cvm
 import (
 	"context"
 	"fmt"
+	"net"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/uuid"
@@ -19,11 +20,12 @@ type stepConfigSubnet struct {
 	SubnetCidrBlock string
 	SubnetName      string
 	Zone            string // 用户指定的子网可用区
+	EnableIpv6      bool
 	createdSubnet   *vpc.Subnet
 }
 
 func (s *stepConfigSubnet) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
-	vpcClient := state.Get("vpc_client").(*vpc.Client)
+	vpcClient := state.Get("vpc_client").(VPCAPI)
 	vpcId := state.Get("vpc_id").(string)
 
 	// 如果指定了子网ID或子网名称，则尝试使用已有子网
@@ -57,17 +59,30 @@ func (s *stepConfigSubnet) Run(ctx context.Context, state multistep.StateBag) mu
 		var resp *vpc.DescribeSubnetsResponse
 		err := Retry(ctx, func(ctx context.Context) error {
 			var e error
-			resp, e = vpcClient.DescribeSubnets(req)
+			resp, e = vpcClient.DescribeSubnetsWithContext(ctx, req)
 			return e
 		})
 		if err != nil {
 			return Halt(state, err, "Failed to get subnet info")
 		}
-		if *resp.Response.TotalCount > 0 {
-			state.Put("subnets", resp.Response.SubnetSet)
-			Message(state, fmt.Sprintf("%d subnets in total.", *resp.Response.TotalCount), "Subnet found")
+		subnets := resp.Response.SubnetSet
+		// 开启ipv6时只使用已分配ipv6网段的subnet
+		if s.EnableIpv6 {
+			subnets = nil
+			for _, subnet := range resp.Response.SubnetSet {
+				if subnet.Ipv6CidrBlock != nil && *subnet.Ipv6CidrBlock != "" {
+					subnets = append(subnets, subnet)
+				}
+			}
+		}
+		if len(subnets) > 0 {
+			state.Put("subnets", subnets)
+			Message(state, fmt.Sprintf("%d subnets in total.", len(subnets)), "Subnet found")
 			return multistep.ActionContinue
 		}
+		if s.EnableIpv6 && *resp.Response.TotalCount > 0 {
+			return Halt(state, fmt.Errorf("the specified subnet has no ipv6 cidr block"), "")
+		}
 		return Halt(state, fmt.Errorf("the specified subnet does not exist"), "")
 	}
 
@@ -75,15 +90,21 @@ func (s *stepConfigSubnet) Run(ctx context.Context, state multistep.StateBag) mu
 	// 此时subnetname一定为空，使用随机生成的名称
 	s.SubnetName = fmt.Sprintf("packer_%s", uuid.TimeOrderedUUID()[:8])
 	Say(state, s.SubnetName, "Trying to create a new subnet")
+	tags, err := ResourceTags(state, nil)
+	if err != nil {
+		return Halt(state, err, "Failed to get tags")
+	}
+
 	req := vpc.NewCreateSubnetRequest()
 	req.VpcId = &vpcId
 	req.SubnetName = &s.SubnetName
 	req.CidrBlock = &s.SubnetCidrBlock
 	req.Zone = &s.Zone
+	req.Tags = vpcTags(tags)
 	var resp *vpc.CreateSubnetResponse
-	err := Retry(ctx, func(ctx context.Context) error {
+	err = Retry(ctx, func(ctx context.Context) error {
 		var e error
-		resp, e = vpcClient.CreateSubnet(req)
+		resp, e = vpcClient.CreateSubnetWithContext(ctx, req)
 		return e
 	})
 	if err != nil {
@@ -94,6 +115,35 @@ func (s *stepConfigSubnet) Run(ctx context.Context, state multistep.StateBag) mu
 	s.createdSubnet = resp.Response.Subnet
 	Message(state, fmt.Sprintf("subnet created: %s in zone: %s", *s.createdSubnet.SubnetId, *s.createdSubnet.Zone), "Subnet created")
 
+	if s.EnableIpv6 {
+		vpcIpv6CidrBlock, ok := state.GetOk("vpc_ipv6_cidr_block")
+		if !ok {
+			return Halt(state, fmt.Errorf("vpc(%s) has no ipv6 cidr block", vpcId), "Failed to assign ipv6 cidr block to subnet")
+		}
+		ipv6CidrBlock, err := ipv6SubnetCidrBlock(vpcIpv6CidrBlock.(string))
+		if err != nil {
+			return Halt(state, err, "Failed to assign ipv6 cidr block to subnet")
+		}
+		Say(state, *s.createdSubnet.SubnetId, "Trying to assign ipv6 cidr block to subnet")
+		ipv6Req := vpc.NewAssignIpv6SubnetCidrBlockRequest()
+		ipv6Req.VpcId = &vpcId
+		ipv6Req.Ipv6SubnetCidrBlocks = []*vpc.Ipv6SubnetCidrBlock{
+			{
+				SubnetId:      s.createdSubnet.SubnetId,
+				Ipv6CidrBlock: &ipv6CidrBlock,
+			},
+		}
+		err = Retry(ctx, func(ctx context.Context) error {
+			_, e := vpcClient.AssignIpv6SubnetCidrBlockWithContext(ctx, ipv6Req)
+			return e
+		})
+		if err != nil {
+			return Halt(state, err, "Failed to assign ipv6 cidr block to subnet")
+		}
+		s.createdSubnet.Ipv6CidrBlock = &ipv6CidrBlock
+		Message(state, ipv6CidrBlock, "Subnet ipv6 cidr block assigned")
+	}
+
 	// 由于cidr冲突，不能用同一个cidr创建多个subnet，所以创建成功后直接继续
 	state.Put("subnets", []*vpc.Subnet{s.createdSubnet})
 	return multistep.ActionContinue
@@ -105,17 +155,30 @@ func (s *stepConfigSubnet) Cleanup(state multistep.StateBag) {
 		return
 	}
 	ctx := context.TODO()
-	vpcClient := state.Get("vpc_client").(*vpc.Client)
+	vpcClient := state.Get("vpc_client").(VPCAPI)
 
 	SayClean(state, "subnet")
 	req := vpc.NewDeleteSubnetRequest()
 	req.SubnetId = s.createdSubnet.SubnetId
 	err := Retry(ctx, func(ctx context.Context) error {
-		_, e := vpcClient.DeleteSubnet(req)
+		_, e := vpcClient.DeleteSubnetWithContext(ctx, req)
 		return e
 	})
 	if err != nil {
-		Error(state, err, fmt.Sprintf("Failed to delete subnet(%s), please delete it manually", *s.createdSubnet.SubnetId))
+		Leak(state, err, "subnet", *s.createdSubnet.SubnetId)
 	}
 
 }
+
+// ipv6SubnetCidrBlock returns the first /64 ipv6 cidr block in vpc ipv6 cidr block
+func ipv6SubnetCidrBlock(vpcCidrBlock string) (string, error) {
+	ip, _, err := net.ParseCIDR(vpcCidrBlock)
+	if err != nil {
+		return "", err
+	}
+	if ip.To4() != nil {
+		return "", fmt.Errorf("invalid ipv6 cidr block: %s", vpcCidrBlock)
+	}
+	subnet := net.IPNet{IP: ip.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}
+	return subnet.String(), nil
+}
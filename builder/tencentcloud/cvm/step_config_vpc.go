@@ -12,14 +12,15 @@ import (
 )
 
 type stepConfigVPC struct {
-	VpcId     string
-	CidrBlock string
-	VpcName   string
-	isCreate  bool
+	VpcId      string
+	CidrBlock  string
+	VpcName    string
+	EnableIpv6 bool
+	isCreate   bool
 }
 
 func (s *stepConfigVPC) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
-	vpcClient := state.Get("vpc_client").(*vpc.Client)
+	vpcClient := state.Get("vpc_client").(VPCAPI)
 
 	if len(s.VpcId) != 0 {
 		Say(state, s.VpcId, "Trying to use existing vpc")
@@ -28,7 +29,7 @@ func (s *stepConfigVPC) Run(ctx context.Context, state multistep.StateBag) multi
 		var resp *vpc.DescribeVpcsResponse
 		err := Retry(ctx, func(ctx context.Context) error {
 			var e error
-			resp, e = vpcClient.DescribeVpcs(req)
+			resp, e = vpcClient.DescribeVpcsWithContext(ctx, req)
 			return e
 		})
 		if err != nil {
@@ -37,6 +38,9 @@ func (s *stepConfigVPC) Run(ctx context.Context, state multistep.StateBag) multi
 		if *resp.Response.TotalCount > 0 {
 			s.isCreate = false
 			state.Put("vpc_id", *resp.Response.VpcSet[0].VpcId)
+			if resp.Response.VpcSet[0].Ipv6CidrBlock != nil && *resp.Response.VpcSet[0].Ipv6CidrBlock != "" {
+				state.Put("vpc_ipv6_cidr_block", *resp.Response.VpcSet[0].Ipv6CidrBlock)
+			}
 			Message(state, *resp.Response.VpcSet[0].VpcName, "Vpc found")
 			return multistep.ActionContinue
 		}
@@ -45,13 +49,19 @@ func (s *stepConfigVPC) Run(ctx context.Context, state multistep.StateBag) multi
 
 	Say(state, "Trying to create a new vpc", "")
 
+	tags, err := ResourceTags(state, nil)
+	if err != nil {
+		return Halt(state, err, "Failed to get tags")
+	}
+
 	req := vpc.NewCreateVpcRequest()
 	req.VpcName = &s.VpcName
 	req.CidrBlock = &s.CidrBlock
+	req.Tags = vpcTags(tags)
 	var resp *vpc.CreateVpcResponse
-	err := Retry(ctx, func(ctx context.Context) error {
+	err = Retry(ctx, func(ctx context.Context) error {
 		var e error
-		resp, e = vpcClient.CreateVpc(req)
+		resp, e = vpcClient.CreateVpcWithContext(ctx, req)
 		return e
 	})
 	if err != nil {
@@ -63,6 +73,23 @@ func (s *stepConfigVPC) Run(ctx context.Context, state multistep.StateBag) multi
 	state.Put("vpc_id", s.VpcId)
 	Message(state, s.VpcId, "Vpc created")
 
+	if s.EnableIpv6 {
+		Say(state, s.VpcId, "Trying to assign ipv6 cidr block to vpc")
+		ipv6Req := vpc.NewAssignIpv6CidrBlockRequest()
+		ipv6Req.VpcId = &s.VpcId
+		var ipv6Resp *vpc.AssignIpv6CidrBlockResponse
+		err = Retry(ctx, func(ctx context.Context) error {
+			var e error
+			ipv6Resp, e = vpcClient.AssignIpv6CidrBlockWithContext(ctx, ipv6Req)
+			return e
+		})
+		if err != nil {
+			return Halt(state, err, "Failed to assign ipv6 cidr block to vpc")
+		}
+		state.Put("vpc_ipv6_cidr_block", *ipv6Resp.Response.Ipv6CidrBlock)
+		Message(state, *ipv6Resp.Response.Ipv6CidrBlock, "Vpc ipv6 cidr block assigned")
+	}
+
 	return multistep.ActionContinue
 }
 
@@ -72,17 +99,17 @@ func (s *stepConfigVPC) Cleanup(state multistep.StateBag) {
 	}
 
 	ctx := context.TODO()
-	vpcClient := state.Get("vpc_client").(*vpc.Client)
+	vpcClient := state.Get("vpc_client").(VPCAPI)
 
 	SayClean(state, "vpc")
 
 	req := vpc.NewDeleteVpcRequest()
 	req.VpcId = &s.VpcId
 	err := Retry(ctx, func(ctx context.Context) error {
-		_, e := vpcClient.DeleteVpc(req)
+		_, e := vpcClient.DeleteVpcWithContext(ctx, req)
 		return e
 	})
 	if err != nil {
-		Error(state, err, fmt.Sprintf("Failed to delete vpc(%s), please delete it manually", s.VpcId))
+		Leak(state, err, "vpc", s.VpcId)
 	}
 }
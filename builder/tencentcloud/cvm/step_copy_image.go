@@ -13,18 +13,21 @@ import (
 	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
 )
 
-type stepCopyImage struct {
+type StepCopyImage struct {
 	DesinationRegions []string
 	SourceRegion      string
+	Encrypted         bool
+	KmsKeyIds         map[string]string
 }
 
-func (s *stepCopyImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+func (s *StepCopyImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	if len(s.DesinationRegions) == 0 || (len(s.DesinationRegions) == 1 && s.DesinationRegions[0] == s.SourceRegion) {
 		return multistep.ActionContinue
 	}
 
-	config := state.Get("config").(*Config)
-	client := state.Get("cvm_client").(*cvm.Client)
+	info := state.Get("config").(BuildConfig).BuildInfo()
+	client := state.Get("cvm_client").(CVMAPI)
+	regionClient := state.Get("cvm_region_client").(RegionCVMClientFunc)
 
 	imageId := state.Get("image").(*cvm.Image).ImageId
 
@@ -40,10 +43,15 @@ func (s *stepCopyImage) Run(ctx context.Context, state multistep.StateBag) multi
 	}
 	req.DestinationRegions = copyRegions
 
-	err := Retry(ctx, func(ctx context.Context) error {
-		_, e := client.SyncImages(req)
-		return e
-	})
+	var err error
+	if s.Encrypted {
+		err = s.syncEncryptedImages(ctx, client, req)
+	} else {
+		err = Retry(ctx, func(ctx context.Context) error {
+			_, e := client.SyncImagesWithContext(ctx, req)
+			return e
+		})
+	}
 	if err != nil {
 		return Halt(state, err, "Failed to copy image")
 	}
@@ -52,17 +60,17 @@ func (s *stepCopyImage) Run(ctx context.Context, state multistep.StateBag) multi
 	tencentCloudImages := state.Get("tencentcloudimages").(map[string]string)
 
 	for _, region := range req.DestinationRegions {
-		rc, err := NewCvmClient(config.SecretId, config.SecretKey, *region, config.CvmEndpoint)
+		rc, err := regionClient(*region)
 		if err != nil {
 			return Halt(state, err, "Failed to init client")
 		}
 
-		err = WaitForImageReady(ctx, rc, config.ImageName, "NORMAL", 1800)
+		err = WaitForImageReady(ctx, rc, info.ImageName, "NORMAL", 1800)
 		if err != nil {
 			return Halt(state, err, "Failed to wait for image ready")
 		}
 
-		image, err := GetImageByName(ctx, rc, config.ImageName)
+		image, err := GetImageByName(ctx, rc, info.ImageName)
 		if err != nil {
 			return Halt(state, err, "Failed to get image")
 		}
@@ -71,6 +79,19 @@ func (s *stepCopyImage) Run(ctx context.Context, state multistep.StateBag) multi
 			return Halt(state, err, "Failed to wait for image ready")
 		}
 
+		// tags of copied image are applied explicitly
+		tags, err := ResourceTags(state, info.ImageTags)
+		if err != nil {
+			return Halt(state, err, "Failed to get tags")
+		}
+		// tagging for cost allocation doesn't fail the build
+		if err := TagCvmResources(ctx, state, *region, "image", []string{*image.ImageId}, tags); err != nil {
+			Message(state, fmt.Sprintf("%s, skip tagging copied image", err), "Failed to tag copied image")
+		}
+		if err := TagCvmResources(ctx, state, *region, "snapshot", imageSnapshotIds(image), tags); err != nil {
+			Message(state, fmt.Sprintf("%s, skip tagging copied image snapshots", err), "Failed to tag copied image snapshots")
+		}
+
 		tencentCloudImages[*region] = *image.ImageId
 		Message(state, fmt.Sprintf("Copy image from %s(%s) to %s(%s)", s.SourceRegion, *imageId, *region, *image.ImageId), "")
 	}
@@ -81,4 +102,30 @@ func (s *stepCopyImage) Run(ctx context.Context, state multistep.StateBag) multi
 	return multistep.ActionContinue
 }
 
-func (s *stepCopyImage) Cleanup(state multistep.StateBag) {}
+// syncEncryptedImages copies image to each region with the kms key of that
+// region, encryption parameters are not supported by the vendored sdk yet.
+func (s *StepCopyImage) syncEncryptedImages(ctx context.Context, client CVMAPI, req *cvm.SyncImagesRequest) error {
+	for _, region := range req.DestinationRegions {
+		regionReq := cvm.NewSyncImagesRequest()
+		regionReq.ImageIds = req.ImageIds
+		regionReq.DestinationRegions = []*string{region}
+
+		params := map[string]interface{}{
+			"Encrypt": true,
+		}
+		if kmsKeyId := s.KmsKeyIds[*region]; kmsKeyId != "" {
+			params["KmsKeyId"] = kmsKeyId
+		}
+
+		err := Retry(ctx, func(ctx context.Context) error {
+			return SendWithExtraParams(ctx, client, regionReq, params, cvm.NewSyncImagesResponse())
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *StepCopyImage) Cleanup(state multistep.StateBag) {}
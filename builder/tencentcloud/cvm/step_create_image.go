@@ -16,7 +16,7 @@ type stepCreateImage struct {
 }
 
 func (s *stepCreateImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
-	client := state.Get("cvm_client").(*cvm.Client)
+	client := state.Get("cvm_client").(CVMAPI)
 
 	config := state.Get("config").(*Config)
 	instance := state.Get("instance").(*cvm.Instance)
@@ -46,34 +46,29 @@ func (s *stepCreateImage) Run(ctx context.Context, state multistep.StateBag) mul
 		req.ForcePoweroff = &False
 	}
 
-	if config.Sysprep {
+	// no need to sysprep again if it has been done through communicator
+	_, sysprepped := state.GetOk("instance_sysprepped")
+	if config.Sysprep && !sysprepped {
 		req.Sysprep = &True
 	} else {
 		req.Sysprep = &False
 	}
 
-	var tags []*cvm.Tag
-	for k, v := range config.ImageTags {
-		k := k
-		v := v
-		tags = append(tags, &cvm.Tag{
-			Key:   &k,
-			Value: &v,
-		})
+	tags, err := ResourceTags(state, config.ImageTags)
+	if err != nil {
+		return Halt(state, err, "Failed to get tags")
 	}
 
 	resourceType := "image"
-	if len(tags) > 0 {
-		req.TagSpecification = []*cvm.TagSpecification{
-			{
-				ResourceType: &resourceType,
-				Tags:         tags,
-			},
-		}
+	req.TagSpecification = []*cvm.TagSpecification{
+		{
+			ResourceType: &resourceType,
+			Tags:         CvmTags(tags),
+		},
 	}
 
-	err := Retry(ctx, func(ctx context.Context) error {
-		_, e := client.CreateImage(req)
+	err = Retry(ctx, func(ctx context.Context) error {
+		_, e := client.CreateImageWithContext(ctx, req)
 		return e
 	})
 	if err != nil {
@@ -99,6 +94,12 @@ func (s *stepCreateImage) Run(ctx context.Context, state multistep.StateBag) mul
 	state.Put("image", image)
 	Message(state, s.imageId, "Image created")
 
+	// snapshots are created with image, they can only be tagged afterwards,
+	// tagging for cost allocation doesn't fail the build
+	if err := TagCvmResources(ctx, state, config.Region, "snapshot", imageSnapshotIds(image), tags); err != nil {
+		Message(state, fmt.Sprintf("%s, skip tagging image snapshots", err), "Failed to tag image snapshots")
+	}
+
 	tencentCloudImages := make(map[string]string)
 	tencentCloudImages[config.Region] = s.imageId
 	state.Put("tencentcloudimages", tencentCloudImages)
@@ -118,17 +119,17 @@ func (s *stepCreateImage) Cleanup(state multistep.StateBag) {
 	}
 
 	ctx := context.TODO()
-	client := state.Get("cvm_client").(*cvm.Client)
+	client := state.Get("cvm_client").(CVMAPI)
 
 	SayClean(state, "image")
 
 	req := cvm.NewDeleteImagesRequest()
 	req.ImageIds = []*string{&s.imageId}
 	err := Retry(ctx, func(ctx context.Context) error {
-		_, e := client.DeleteImages(req)
+		_, e := client.DeleteImagesWithContext(ctx, req)
 		return e
 	})
 	if err != nil {
-		Error(state, err, fmt.Sprintf("Failed to delete image(%s), please delete it manually", s.imageId))
+		Leak(state, err, "image", s.imageId)
 	}
 }
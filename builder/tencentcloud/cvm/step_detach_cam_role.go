@@ -0,0 +1,46 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+type stepDetachCamRole struct {
+	DetachCamRole bool
+}
+
+func (s *stepDetachCamRole) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if !s.DetachCamRole {
+		return multistep.ActionContinue
+	}
+
+	client := state.Get("cvm_client").(CVMAPI)
+	instance := state.Get("instance").(*cvm.Instance)
+
+	Say(state, *instance.InstanceId, "Trying to detach cam role from instance")
+
+	// CamRoleName of ModifyInstancesAttribute is not supported by the vendored
+	// sdk yet, an empty role name unbinds the role.
+	req := cvm.NewModifyInstancesAttributeRequest()
+	req.InstanceIds = []*string{instance.InstanceId}
+	params := map[string]interface{}{
+		"CamRoleName": "",
+	}
+	err := Retry(ctx, func(ctx context.Context) error {
+		return SendWithExtraParams(ctx, client, req, params, cvm.NewModifyInstancesAttributeResponse())
+	})
+	if err != nil {
+		return Halt(state, err, "Failed to detach cam role")
+	}
+
+	Message(state, "Cam role detached", "")
+
+	return multistep.ActionContinue
+}
+
+func (s *stepDetachCamRole) Cleanup(state multistep.StateBag) {}
@@ -11,11 +11,14 @@ import (
 	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
 )
 
-type stepDetachTempKeyPair struct {
+type StepDetachTempKeyPair struct {
+	// StopType is the way to stop instance by api if it is not stopped
+	// by StepShutdownInstance
+	StopType string
 }
 
-func (s *stepDetachTempKeyPair) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
-	client := state.Get("cvm_client").(*cvm.Client)
+func (s *StepDetachTempKeyPair) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("cvm_client").(CVMAPI)
 
 	if _, ok := state.GetOk("temporary_key_pair_id"); !ok {
 		return multistep.ActionContinue
@@ -24,22 +27,24 @@ func (s *stepDetachTempKeyPair) Run(ctx context.Context, state multistep.StateBa
 	keyId := state.Get("temporary_key_pair_id").(string)
 	instance := state.Get("instance").(*cvm.Instance)
 
-	// 生成镜像前先关机，避免创建镜像过程中发生重启导致服务意外启动
-	Say(state, *instance.InstanceName, "Trying to stop instance")
-
-	stopReq := cvm.NewStopInstancesRequest()
-	stopReq.InstanceIds = []*string{instance.InstanceId}
+	describeReq := cvm.NewDescribeInstancesRequest()
+	describeReq.InstanceIds = []*string{instance.InstanceId}
+	var describeResp *cvm.DescribeInstancesResponse
 	err := Retry(ctx, func(ctx context.Context) error {
-		_, e := client.StopInstances(stopReq)
+		var e error
+		describeResp, e = client.DescribeInstancesWithContext(ctx, describeReq)
 		return e
 	})
 	if err != nil {
-		return Halt(state, err, "Failed to stop instance")
+		return Halt(state, err, "Failed to get instance info")
 	}
-	Message(state, "Waiting for instance stop", "")
-	err = WaitForInstance(ctx, client, *instance.InstanceId, "STOPPED", 1800)
-	if err != nil {
-		return Halt(state, err, "Failed to wait for instance to be stopped")
+
+	// 已经由StepShutdownInstance关机时不需要再次关机
+	if *describeResp.Response.TotalCount == 0 || *describeResp.Response.InstanceSet[0].InstanceState != "STOPPED" {
+		// 解绑密钥对需要先关机
+		if action := stopInstance(ctx, state, instance, s.StopType); action != multistep.ActionContinue {
+			return action
+		}
 	}
 
 	Say(state, keyId, "Trying to detach keypair")
@@ -49,7 +54,7 @@ func (s *stepDetachTempKeyPair) Run(ctx context.Context, state multistep.StateBa
 	req.InstanceIds = []*string{instance.InstanceId}
 	req.ForceStop = common.BoolPtr(false)
 	err = Retry(ctx, func(ctx context.Context) error {
-		_, e := client.DisassociateInstancesKeyPairs(req)
+		_, e := client.DisassociateInstancesKeyPairsWithContext(ctx, req)
 		return e
 	})
 	if err != nil {
@@ -67,4 +72,4 @@ func (s *stepDetachTempKeyPair) Run(ctx context.Context, state multistep.StateBa
 	return multistep.ActionContinue
 }
 
-func (s *stepDetachTempKeyPair) Cleanup(state multistep.StateBag) {}
+func (s *StepDetachTempKeyPair) Cleanup(state multistep.StateBag) {}
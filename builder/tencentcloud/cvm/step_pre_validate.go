@@ -8,23 +8,22 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
-	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
 )
 
-type stepPreValidate struct {
+type StepPreValidate struct {
 	ForceDelete  bool
 	SkipIfExists bool
 }
 
 var ImageExistsError = fmt.Errorf("Image name has exists")
 
-func (s *stepPreValidate) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
-	config := state.Get("config").(*Config)
-	client := state.Get("cvm_client").(*cvm.Client)
+func (s *StepPreValidate) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	imageName := state.Get("config").(BuildConfig).BuildInfo().ImageName
+	client := state.Get("cvm_client").(CVMAPI)
 
-	Say(state, config.ImageName, "Trying to check image name")
+	Say(state, imageName, "Trying to check image name")
 
-	image, err := GetImageByName(ctx, client, config.ImageName)
+	image, err := GetImageByName(ctx, client, imageName)
 	if err != nil {
 		return Halt(state, err, "Failed to get images info")
 	}
@@ -45,4 +44,4 @@ func (s *stepPreValidate) Run(ctx context.Context, state multistep.StateBag) mul
 	return multistep.ActionContinue
 }
 
-func (s *stepPreValidate) Cleanup(multistep.StateBag) {}
+func (s *StepPreValidate) Cleanup(multistep.StateBag) {}
@@ -0,0 +1,82 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+// stepRebootInstance reboots instance before creating image, or starts it
+// if it has been stopped for detaching temporary keypair.
+type stepRebootInstance struct {
+	ShutdownBehavior string
+	StopType         string
+}
+
+func (s *stepRebootInstance) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if s.ShutdownBehavior != "reboot" {
+		return multistep.ActionContinue
+	}
+
+	// instance has been shut down by sysprep, it should not boot again
+	if _, ok := state.GetOk("instance_sysprepped"); ok {
+		return multistep.ActionContinue
+	}
+
+	client := state.Get("cvm_client").(CVMAPI)
+	instance := state.Get("instance").(*cvm.Instance)
+
+	req := cvm.NewDescribeInstancesRequest()
+	req.InstanceIds = []*string{instance.InstanceId}
+	var resp *cvm.DescribeInstancesResponse
+	err := Retry(ctx, func(ctx context.Context) error {
+		var e error
+		resp, e = client.DescribeInstancesWithContext(ctx, req)
+		return e
+	})
+	if err != nil {
+		return Halt(state, err, "Failed to get instance info")
+	}
+	if *resp.Response.TotalCount == 0 {
+		return Halt(state, fmt.Errorf("instance(%s) not exist", *instance.InstanceId), "Failed to get instance info")
+	}
+
+	if *resp.Response.InstanceSet[0].InstanceState == "STOPPED" {
+		Say(state, *instance.InstanceName, "Trying to start instance")
+		startReq := cvm.NewStartInstancesRequest()
+		startReq.InstanceIds = []*string{instance.InstanceId}
+		err = Retry(ctx, func(ctx context.Context) error {
+			_, e := client.StartInstancesWithContext(ctx, startReq)
+			return e
+		})
+	} else {
+		Say(state, fmt.Sprintf("%s with stop type %s", *instance.InstanceName, s.StopType), "Trying to reboot instance")
+		rebootReq := cvm.NewRebootInstancesRequest()
+		rebootReq.InstanceIds = []*string{instance.InstanceId}
+		rebootReq.StopType = &s.StopType
+		err = Retry(ctx, func(ctx context.Context) error {
+			_, e := client.RebootInstancesWithContext(ctx, rebootReq)
+			return e
+		})
+	}
+	if err != nil {
+		return Halt(state, err, "Failed to reboot instance")
+	}
+
+	Message(state, "Waiting for instance running", "")
+	err = WaitForInstance(ctx, client, *instance.InstanceId, "RUNNING", 1800)
+	if err != nil {
+		return Halt(state, err, "Failed to wait for instance to be running")
+	}
+
+	Message(state, "Instance rebooted", "")
+
+	return multistep.ActionContinue
+}
+
+func (s *stepRebootInstance) Cleanup(state multistep.StateBag) {}
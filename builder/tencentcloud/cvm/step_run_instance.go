@@ -5,29 +5,43 @@ package cvm
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"log"
 	"os"
+	"reflect"
 	"strings"
 
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/uuid"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
 	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
 	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
 )
 
+// launchTarget is the dedicated host and vpc ip to launch cvm on in a subnet
+type launchTarget struct {
+	host      *dedicatedHost
+	privateIp string
+}
+
 // 移除了zoneid，由subnet step生成的subnet信息提供
 type stepRunInstance struct {
 	InstanceTypeCandidates   []string
 	InstanceChargeType       string
 	UserData                 string
 	UserDataFile             string
+	UserDataParts            []tencentCloudUserDataPart
+	Comm                     *communicator.Config
 	instanceId               string
 	InstanceName             string
 	DiskType                 string
 	DiskSize                 int64
+	DiskEncrypted            bool
+	DiskKmsKeyId             string
 	HostName                 string
 	InternetChargeType       string
 	InternetMaxBandwidthOut  int64
@@ -36,19 +50,34 @@ type stepRunInstance struct {
 	Tags                     map[string]string
 	DataDisks                []tencentCloudDataDisk
 	PlacementGroupId         string
+	VpcIpCandidates          []string
+	EnableIpv6               bool
+	Region                   string
+	CamRoleName              string
+	LaunchTemplateId         string
+	LaunchTemplateVersion    uint64
+	LaunchTemplate           *cvm.LaunchTemplateVersionData
+	Placement                tencentCloudPlacement
+	DisableSecurityService   bool
+	DisableMonitorService    bool
+	DisableAutomationService bool
+	dedicatedHosts           []*dedicatedHost
+	dataDiskParams           []map[string]interface{}
+	zoneDiskTypes            map[string]bool
+	unsoldDiskType           string
 }
 
 func (s *stepRunInstance) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
-	client := state.Get("cvm_client").(*cvm.Client)
+	client := state.Get("cvm_client").(CVMAPI)
 
-	config := state.Get("config").(*Config)
 	source_image := state.Get("source_image").(*cvm.Image)
 	security_group_id := state.Get("security_group_id").(string)
 
-	password := config.Comm.SSHPassword
-	if password == "" && config.Comm.WinRMPassword != "" {
-		password = config.Comm.WinRMPassword
+	password := s.Comm.SSHPassword
+	if password == "" && s.Comm.WinRMPassword != "" {
+		password = s.Comm.WinRMPassword
 	}
+	LogSecrets(password)
 
 	userData, err := s.getUserData(state)
 	if err != nil {
@@ -59,30 +88,48 @@ func (s *stepRunInstance) Run(ctx context.Context, state multistep.StateBag) mul
 
 	// config RunInstances parameters
 	req := cvm.NewRunInstancesRequest()
-	securityEnabled := !config.DisableSecurityService
-	monitorEnabled := !config.DisableMonitorService
-	automationEnabled := !config.DisableAutomationService
-	req.EnhancedService = &cvm.EnhancedService{
-		SecurityService: &cvm.RunSecurityServiceEnabled{
-			Enabled: &securityEnabled,
-		},
-		MonitorService: &cvm.RunMonitorServiceEnabled{
-			Enabled: &monitorEnabled,
-		},
-		AutomationService: &cvm.RunAutomationServiceEnabled{
-			Enabled: &automationEnabled,
-		},
+	if s.LaunchTemplateId != "" {
+		// settings specified below override the ones of launch template
+		req.LaunchTemplate = &cvm.LaunchTemplate{
+			LaunchTemplateId: &s.LaunchTemplateId,
+		}
+		if s.LaunchTemplateVersion > 0 {
+			req.LaunchTemplate.LaunchTemplateVersion = &s.LaunchTemplateVersion
+		}
+	}
+	// enhanced services of launch template are kept
+	if s.LaunchTemplate == nil || s.LaunchTemplate.EnhancedService == nil {
+		securityEnabled := !s.DisableSecurityService
+		monitorEnabled := !s.DisableMonitorService
+		automationEnabled := !s.DisableAutomationService
+		req.EnhancedService = &cvm.EnhancedService{
+			SecurityService: &cvm.RunSecurityServiceEnabled{
+				Enabled: &securityEnabled,
+			},
+			MonitorService: &cvm.RunMonitorServiceEnabled{
+				Enabled: &monitorEnabled,
+			},
+			AutomationService: &cvm.RunAutomationServiceEnabled{
+				Enabled: &automationEnabled,
+			},
+		}
 	}
 
 	if s.PlacementGroupId != "" {
 		req.DisasterRecoverGroupIds = []*string{&s.PlacementGroupId}
 	}
 
+	if s.CamRoleName != "" {
+		req.CamRoleName = &s.CamRoleName
+	}
+
 	instanceChargeType := s.InstanceChargeType
-	if instanceChargeType == "" {
+	if instanceChargeType == "" && s.LaunchTemplateId == "" {
 		instanceChargeType = "POSTPAID_BY_HOUR"
 	}
-	req.InstanceChargeType = &instanceChargeType
+	if instanceChargeType != "" {
+		req.InstanceChargeType = &instanceChargeType
+	}
 	req.ImageId = source_image.ImageId
 	// Instance type will be set later
 	// TODO: Add check for system disk size, it should be larger than image system disk size.
@@ -115,13 +162,34 @@ func (s *stepRunInstance) Run(ctx context.Context, state multistep.StateBag) mul
 	} else {
 		var dataDisks []*cvm.DataDisk
 		for _, disk := range s.DataDisks {
+			disk := disk
 			var dataDisk cvm.DataDisk
 			dataDisk.DiskType = &disk.DiskType
 			dataDisk.DiskSize = &disk.DiskSize
 			if disk.SnapshotId != "" {
 				dataDisk.SnapshotId = &disk.SnapshotId
 			}
+			if disk.DiskEncrypted {
+				dataDisk.Encrypt = common.BoolPtr(true)
+				if disk.DiskKmsKeyId != "" {
+					dataDisk.KmsKeyId = common.StringPtr(disk.DiskKmsKeyId)
+				}
+			}
+			if disk.ThroughputPerformance > 0 {
+				dataDisk.ThroughputPerformance = &disk.ThroughputPerformance
+			}
+			dataDisk.DeleteWithInstance = disk.DeleteWithInstance.ToBoolPointer()
 			dataDisks = append(dataDisks, &dataDisk)
+
+			// disk name and burst performance are not supported by the vendored sdk yet
+			params := make(map[string]interface{})
+			if disk.DiskName != "" {
+				params["DiskName"] = disk.DiskName
+			}
+			if disk.BurstPerformance {
+				params["BurstPerformance"] = true
+			}
+			s.dataDiskParams = append(s.dataDiskParams, params)
 		}
 		req.DataDisks = dataDisks
 	}
@@ -142,80 +210,90 @@ func (s *stepRunInstance) Run(ctx context.Context, state multistep.StateBag) mul
 	if password != "" {
 		loginSettings.Password = &password
 	}
-	if config.Comm.SSHKeyPairName != "" {
-		loginSettings.KeyIds = []*string{&config.Comm.SSHKeyPairName}
+	if s.Comm.SSHKeyPairName != "" {
+		loginSettings.KeyIds = []*string{&s.Comm.SSHKeyPairName}
 	}
 	req.LoginSettings = &loginSettings
-	req.SecurityGroupIds = []*string{&security_group_id}
+	// security groups of launch template are used if it is empty
+	if security_group_id != "" {
+		req.SecurityGroupIds = []*string{&security_group_id}
+	}
 	// client token 在 loop 中生成，避免重复使用
 	req.HostName = &s.HostName
-	req.UserData = &userData
-	var tags []*cvm.Tag
-	for k, v := range s.Tags {
-		k := k
-		v := v
-		tags = append(tags, &cvm.Tag{
-			Key:   &k,
-			Value: &v,
-		})
-	}
-	resourceType := "instance"
-	if len(tags) > 0 {
-		req.TagSpecification = []*cvm.TagSpecification{
-			{
-				ResourceType: &resourceType,
-				Tags:         tags,
-			},
-		}
+	if userData != "" {
+		req.UserData = &userData
+	}
+	tags, err := ResourceTags(state, s.Tags)
+	if err != nil {
+		return Halt(state, err, "Failed to get tags")
 	}
+	// the tags of launch template are kept under the resource tags
+	req.TagSpecification = launchTemplateTagSpecification(s.LaunchTemplate, tags)
 	// 遍历subnet列表，依次尝试建立instance
 	subnets, ok := state.GetOk("subnets")
 	if !ok {
 		return Halt(state, fmt.Errorf("no subnets in state"), "Cannot get subnets info when starting instance")
 	}
+	s.dedicatedHosts, err = GetDedicatedHosts(ctx, client, s.Placement.DedicatedHostIds, s.Placement.HostIps)
+	if err != nil {
+		return Halt(state, err, "Failed to get dedicated hosts")
+	}
 	err = fmt.Errorf("No subnet found")
+	tried := 0
 	// 根据instance_type_candidates顺序尝试创建instance
 loop:
 	for _, instanceType := range s.InstanceTypeCandidates {
 		req.InstanceType = &instanceType
 		// 腾讯云开机时返回instanceid后还需要等待实例状态为running才可认为开机成功。
 		for _, subnet := range subnets.([]*vpc.Subnet) {
-			var instanceIds []*string
-			token := uuid.TimeOrderedUUID()
-			req.ClientToken = &token
-			instanceIds, err = s.CreateCvmInstance(ctx, state, subnet, req)
-			if err == nil {
-				// 此时 WaitForInstance 已经确认了instance状态为RUNNING，可以认为开机成功，且id不可能为空
-				s.instanceId = *instanceIds[0]
-				break loop
+			// 跳过不支持所需云硬盘类型的可用区
+			if !s.checkZoneDiskTypes(ctx, state, *subnet.Zone, req) {
+				continue
 			}
-			// InstanceIdSet不为空，代表已经创建了instance，但是开机不成功，此时需要删除instance
-			if len(instanceIds) > 0 {
-				// 尝试删除已有的instanceId，避免资源泄露
-				terminateReq := cvm.NewTerminateInstancesRequest()
-				terminateReq.InstanceIds = instanceIds
-				terminateErr := Retry(ctx, func(ctx context.Context) error {
-					_, e := client.TerminateInstances(terminateReq)
-					return e
-				})
-				// 如果删除失败，且不是因为instanceId不存在，则报错
-				// instanceId不存在代表之前开机不成功，此处不需要再次删除。若是LAUNCH_FAILED会预到Code=InvalidInstanceId.NotFound，跳过尝试下一个subnet继续尝试开机即可
-				if terminateErr != nil && terminateErr.(*errors.TencentCloudSDKError).Code != "InvalidInstanceId.NotFound" {
-					// undefined behavior, just halt
-					// halt use put to store error in state, it cannot append
-					var builder strings.Builder
-					for _, instanceId := range instanceIds {
-						builder.WriteString(*instanceId)
-						builder.WriteString(",")
+			// 指定了vpc_ip时，只尝试在subnet网段内的ip，ip冲突时继续尝试下一个ip
+			// 指定了专用宿主机时，只尝试subnet可用区内的宿主机，资源不足时继续尝试下一个宿主机
+			for _, target := range s.getLaunchTargets(state, subnet) {
+				var instanceIds []*string
+				token := uuid.TimeOrderedUUID()
+				req.ClientToken = &token
+				tried++
+				instanceIds, err = s.CreateCvmInstance(ctx, state, subnet, target, req)
+				if err == nil {
+					// 此时 WaitForInstance 已经确认了instance状态为RUNNING，可以认为开机成功，且id不可能为空
+					s.instanceId = *instanceIds[0]
+					break loop
+				}
+				// InstanceIdSet不为空，代表已经创建了instance，但是开机不成功，此时需要删除instance
+				if len(instanceIds) > 0 {
+					// 尝试删除已有的instanceId，避免资源泄露
+					terminateReq := cvm.NewTerminateInstancesRequest()
+					terminateReq.InstanceIds = instanceIds
+					terminateErr := Retry(ctx, func(ctx context.Context) error {
+						_, e := client.TerminateInstancesWithContext(ctx, terminateReq)
+						return e
+					})
+					// 如果删除失败，且不是因为instanceId不存在，则报错
+					// instanceId不存在代表之前开机不成功，此处不需要再次删除。若是LAUNCH_FAILED会预到Code=InvalidInstanceId.NotFound，跳过尝试下一个subnet继续尝试开机即可
+					if terminateErr != nil && terminateErr.(*errors.TencentCloudSDKError).Code != "InvalidInstanceId.NotFound" {
+						// undefined behavior, just halt
+						// halt use put to store error in state, it cannot append
+						var builder strings.Builder
+						for _, instanceId := range instanceIds {
+							builder.WriteString(*instanceId)
+							builder.WriteString(",")
+						}
+						return Halt(state, terminateErr, fmt.Sprintf("Failed to terminate instance %s may need to delete it manually", builder.String()))
 					}
-					return Halt(state, terminateErr, fmt.Sprintf("Failed to terminate instance %s may need to delete it manually", builder.String()))
 				}
 			}
 		}
 	}
 	// 最后一次开机也不成功，报错
+	if tried == 0 && s.unsoldDiskType != "" && !s.diskTypesSold() {
+		return Halt(state, fmt.Errorf("disk type %s not sold in any candidate zone", s.unsoldDiskType), "Failed to run instance")
+	}
 	if err != nil {
-		return Halt(state, fmt.Errorf("tried %d configurations but no luck", len(subnets.([]*vpc.Subnet))), "Failed to run instance")
+		return Halt(state, fmt.Errorf("tried %d configurations but no luck", tried), "Failed to run instance")
 	}
 
 	describeReq := cvm.NewDescribeInstancesRequest()
@@ -230,6 +308,11 @@ loop:
 		return Halt(state, err, "Failed to wait for instance ready")
 	}
 
+	// tagging for cost allocation doesn't fail the build
+	if err := s.tagDisks(ctx, state, describeResp.Response.InstanceSet[0]); err != nil {
+		Message(state, fmt.Sprintf("%s, skip tagging disks", err), "Failed to tag disks")
+	}
+
 	state.Put("instance", describeResp.Response.InstanceSet[0])
 	// instance_id is the generic term used so that users can have access to the
 	// instance id inside of the provisioners, used in step_provision.
@@ -239,7 +322,200 @@ loop:
 	return multistep.ActionContinue
 }
 
-func (s *stepRunInstance) getUserData(_ multistep.StateBag) (string, error) {
+// getLaunchTargets returns the combinations of dedicated hosts in the zone of
+// subnet and vpc ips in the cidr block of subnet.
+func (s *stepRunInstance) getLaunchTargets(state multistep.StateBag, subnet *vpc.Subnet) []launchTarget {
+	hosts := []*dedicatedHost{nil}
+	if len(s.dedicatedHosts) != 0 {
+		hosts = nil
+		for _, host := range s.dedicatedHosts {
+			if host.Zone == *subnet.Zone {
+				hosts = append(hosts, host)
+			}
+		}
+		if len(hosts) == 0 {
+			Message(state, fmt.Sprintf("no dedicated host in zone(%s) of subnet(%s), skip it",
+				*subnet.Zone, *subnet.SubnetId), "")
+			return nil
+		}
+	}
+
+	var targets []launchTarget
+	privateIps := s.getPrivateIps(state, subnet)
+	for _, host := range hosts {
+		for _, privateIp := range privateIps {
+			targets = append(targets, launchTarget{host: host, privateIp: privateIp})
+		}
+	}
+
+	return targets
+}
+
+// getPrivateIps returns the vpc ip candidates in the cidr block of subnet,
+// an empty ip means letting the cloud allocate one.
+func (s *stepRunInstance) getPrivateIps(state multistep.StateBag, subnet *vpc.Subnet) []string {
+	if len(s.VpcIpCandidates) == 0 {
+		return []string{""}
+	}
+
+	var privateIps []string
+	for _, ip := range s.VpcIpCandidates {
+		if CheckIpInCidr(ip, *subnet.CidrBlock) {
+			privateIps = append(privateIps, ip)
+		}
+	}
+	if len(privateIps) == 0 {
+		Message(state, fmt.Sprintf("no vpc ip in cidr block(%s) of subnet(%s), skip it",
+			*subnet.CidrBlock, *subnet.SubnetId), "")
+	}
+
+	return privateIps
+}
+
+// checkZoneDiskTypes checks whether the disk types are sold in zone for the
+// charge type of the instance, the check is skipped if the disk types cannot
+// be got from cbs.
+func (s *stepRunInstance) checkZoneDiskTypes(ctx context.Context, state multistep.StateBag, zone string, req *cvm.RunInstancesRequest) bool {
+	if s.zoneDiskTypes == nil {
+		s.zoneDiskTypes = make(map[string]bool)
+	}
+	if ok, checked := s.zoneDiskTypes[zone]; checked {
+		return ok
+	}
+
+	client := state.Get("common_client").(APISender)
+	instanceChargeType := ""
+	if req.InstanceChargeType != nil {
+		instanceChargeType = *req.InstanceChargeType
+	} else if s.LaunchTemplate != nil && s.LaunchTemplate.InstanceChargeType != nil {
+		instanceChargeType = *s.LaunchTemplate.InstanceChargeType
+	}
+	usages := map[string][]string{
+		"SYSTEM_DISK": {*req.SystemDisk.DiskType},
+	}
+	for _, disk := range req.DataDisks {
+		usages["DATA_DISK"] = append(usages["DATA_DISK"], *disk.DiskType)
+	}
+
+	s.zoneDiskTypes[zone] = true
+	for usage, diskTypes := range usages {
+		var available map[string]bool
+		for _, diskType := range diskTypes {
+			if diskType == "" || IsLocalDiskType(diskType) {
+				continue
+			}
+			if available == nil {
+				var err error
+				available, err = GetAvailableDiskTypes(ctx, client, zone, instanceChargeType, usage)
+				if err != nil {
+					Message(state, fmt.Sprintf("%s, skip checking disk types in zone(%s)", err, zone), "")
+					return true
+				}
+			}
+			if !available[diskType] {
+				Message(state, fmt.Sprintf("disk type(%s) is not available in zone(%s), skip it", diskType, zone), "")
+				s.zoneDiskTypes[zone] = false
+				s.unsoldDiskType = diskType
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// diskTypesSold returns whether the disk types are sold in any zone checked
+func (s *stepRunInstance) diskTypesSold() bool {
+	for _, ok := range s.zoneDiskTypes {
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tagDisks applies the resource tags to all disks, and the tags of each
+// data disk, as RunInstances does not support tagging disks.
+func (s *stepRunInstance) tagDisks(ctx context.Context, state multistep.StateBag, instance *cvm.Instance) error {
+	tags, err := ResourceTags(state, nil)
+	if err != nil {
+		return err
+	}
+
+	var diskIds []string
+	if instance.SystemDisk != nil && instance.SystemDisk.DiskId != nil {
+		diskIds = append(diskIds, *instance.SystemDisk.DiskId)
+	}
+	for _, disk := range instance.DataDisks {
+		if disk.DiskId != nil {
+			diskIds = append(diskIds, *disk.DiskId)
+		}
+	}
+	if err := TagCvmResources(ctx, state, s.Region, "volume", diskIds, tags); err != nil {
+		return err
+	}
+
+	// data disks from source image snapshots have no user settings
+	if len(s.dataDiskParams) != 0 {
+		for i, disk := range matchDataDisks(state, s.DataDisks, instance.DataDisks) {
+			if len(s.DataDisks[i].Tags) == 0 || disk.DiskId == nil {
+				continue
+			}
+			diskTags, err := ResourceTags(state, s.DataDisks[i].Tags)
+			if err != nil {
+				return err
+			}
+			if err := TagCvmResources(ctx, state, s.Region, "volume", []string{*disk.DiskId}, diskTags); err != nil {
+				return err
+			}
+		}
+	}
+
+	Message(state, strings.Join(diskIds, ","), "Disks tagged")
+
+	return nil
+}
+
+// matchDataDisks returns the data disks of instance keyed by the index of
+// their settings. DescribeInstances doesn't keep the order of the settings,
+// so the disks are matched by type and size, and the settings of the same
+// type and size but different tags are skipped as they can't be told apart.
+func matchDataDisks(state multistep.StateBag, settings []tencentCloudDataDisk, disks []*cvm.DataDisk) map[int]*cvm.DataDisk {
+	sameShape := func(setting tencentCloudDataDisk, disk *cvm.DataDisk) bool {
+		return disk.DiskSize != nil && *disk.DiskSize == setting.DiskSize &&
+			(setting.DiskType == "" || disk.DiskType != nil && *disk.DiskType == setting.DiskType)
+	}
+
+	matched := make(map[int]*cvm.DataDisk)
+	claimed := make(map[*cvm.DataDisk]bool)
+	for i, setting := range settings {
+		ambiguous := false
+		for j, other := range settings {
+			if j != i && other.DiskType == setting.DiskType && other.DiskSize == setting.DiskSize &&
+				!reflect.DeepEqual(other.Tags, setting.Tags) {
+				ambiguous = true
+				break
+			}
+		}
+		if ambiguous {
+			Message(state, fmt.Sprintf("data_disks[%d] can't be told apart from the disks of the same type "+
+				"and size, skip tagging it", i), "")
+			continue
+		}
+		for _, disk := range disks {
+			if !claimed[disk] && sameShape(setting, disk) {
+				claimed[disk] = true
+				matched[i] = disk
+				break
+			}
+		}
+	}
+
+	return matched
+}
+
+func (s *stepRunInstance) getUserData(state multistep.StateBag) (string, error) {
 	userData := s.UserData
 
 	if userData == "" && s.UserDataFile != "" {
@@ -250,10 +526,25 @@ func (s *stepRunInstance) getUserData(_ multistep.StateBag) (string, error) {
 		userData = string(data)
 	}
 
-	userData = base64.StdEncoding.EncodeToString([]byte(userData))
-	log.Printf(fmt.Sprintf("[DEBUG]getUserData: user_data: %s", userData))
+	if len(s.UserDataParts) > 0 {
+		ictx := state.Get("config").(BuildConfig).BuildInfo().Ctx
+		ictx.Data = buildTemplateData(state)
+		var err error
+		if userData, err = multipartUserData(s.UserDataParts, &ictx); err != nil {
+			return "", err
+		}
+	}
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(userData))
+	// user_data usually carries bootstrap tokens, which base64 doesn't hide
+	LogSecrets(userData, encoded)
+	log.Printf("[DEBUG] getUserData: user_data of %d bytes, sha256 %x", len(userData), sha256.Sum256([]byte(userData)))
+
+	if err := checkUserDataSize(userData); err != nil {
+		return "", err
+	}
 
-	return userData, nil
+	return encoded, nil
 }
 
 func (s *stepRunInstance) Cleanup(state multistep.StateBag) {
@@ -261,39 +552,73 @@ func (s *stepRunInstance) Cleanup(state multistep.StateBag) {
 		return
 	}
 	ctx := context.TODO()
-	client := state.Get("cvm_client").(*cvm.Client)
+	client := state.Get("cvm_client").(CVMAPI)
 
 	SayClean(state, "instance")
 
 	req := cvm.NewTerminateInstancesRequest()
 	req.InstanceIds = []*string{&s.instanceId}
 	err := Retry(ctx, func(ctx context.Context) error {
-		_, e := client.TerminateInstances(req)
+		_, e := client.TerminateInstancesWithContext(ctx, req)
 		return e
 	})
 	if err != nil {
-		Error(state, err, fmt.Sprintf("Failed to terminate instance(%s), please delete it manually", s.instanceId))
+		Leak(state, err, "instance", s.instanceId)
+		return
+	}
+
+	// the network resources are cleaned up right after, wait for the
+	// instance gone, otherwise they fail with resource in use
+	Message(state, "Waiting for instance terminated", "")
+	if err := WaitForInstanceTerminated(ctx, client, s.instanceId, 600); err != nil {
+		Leak(state, err, "instance", s.instanceId)
 	}
 }
 
-func (s *stepRunInstance) CreateCvmInstance(ctx context.Context, state multistep.StateBag, subnet *vpc.Subnet, req *cvm.RunInstancesRequest) ([]*string, error) {
-	client := state.Get("cvm_client").(*cvm.Client)
+func (s *stepRunInstance) CreateCvmInstance(ctx context.Context, state multistep.StateBag, subnet *vpc.Subnet, target launchTarget, req *cvm.RunInstancesRequest) ([]*string, error) {
+	client := state.Get("cvm_client").(CVMAPI)
 	vpcId := state.Get("vpc_id").(string)
-	Say(state,
-		fmt.Sprintf("instance-type: %s, subnet-id: %s, zone: %s",
-			*req.InstanceType, *subnet.SubnetId, *subnet.Zone,
-		), "Try to create instance")
+	privateIp := target.privateIp
+	message := fmt.Sprintf("instance-type: %s, subnet-id: %s, zone: %s",
+		*req.InstanceType, *subnet.SubnetId, *subnet.Zone)
+	if target.host != nil {
+		message = fmt.Sprintf("%s, host: %s", message, target.host)
+	}
+	if privateIp != "" {
+		message = fmt.Sprintf("%s, vpc-ip: %s", message, privateIp)
+	}
+	Say(state, message, "Try to create instance")
 	req.VirtualPrivateCloud = &cvm.VirtualPrivateCloud{
 		VpcId:    &vpcId,
 		SubnetId: subnet.SubnetId,
 	}
+	if privateIp != "" {
+		req.VirtualPrivateCloud.PrivateIpAddresses = []*string{&privateIp}
+	}
+	if s.EnableIpv6 {
+		req.VirtualPrivateCloud.Ipv6AddressCount = common.Uint64Ptr(1)
+	}
 	req.Placement = &cvm.Placement{
 		Zone: subnet.Zone,
 	}
+	if s.Placement.ProjectId > 0 {
+		req.Placement.ProjectId = &s.Placement.ProjectId
+	}
+	if target.host != nil {
+		if target.host.HostId != "" {
+			req.Placement.HostIds = []*string{&target.host.HostId}
+		} else {
+			req.Placement.HostIps = []*string{&target.host.HostIp}
+		}
+	}
 	var resp *cvm.RunInstancesResponse
 	err := Retry(ctx, func(ctx context.Context) error {
+		if params := s.extraParams(); params != nil {
+			resp = cvm.NewRunInstancesResponse()
+			return SendWithExtraParams(ctx, client, req, params, resp)
+		}
 		var e error
-		resp, e = client.RunInstances(req)
+		resp, e = client.RunInstancesWithContext(ctx, req)
 		return e
 	})
 	if err != nil {
@@ -323,3 +648,31 @@ func (s *stepRunInstance) CreateCvmInstance(ctx context.Context, state multistep
 	}
 	return resp.Response.InstanceIdSet, nil
 }
+
+// extraParams returns the parameters of disks which are not supported by
+// the vendored sdk yet.
+func (s *stepRunInstance) extraParams() map[string]interface{} {
+	params := make(map[string]interface{})
+
+	if s.DiskEncrypted {
+		systemDisk := map[string]interface{}{
+			"Encrypt": true,
+		}
+		if s.DiskKmsKeyId != "" {
+			systemDisk["KmsKeyId"] = s.DiskKmsKeyId
+		}
+		params["SystemDisk"] = systemDisk
+	}
+
+	for _, diskParams := range s.dataDiskParams {
+		if len(diskParams) > 0 {
+			params["DataDisks"] = s.dataDiskParams
+			break
+		}
+	}
+
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
@@ -5,7 +5,6 @@ package cvm
 
 import (
 	"context"
-	"fmt"
 	"strings"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
@@ -13,16 +12,16 @@ import (
 	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
 )
 
-type stepShareImage struct {
+type StepShareImage struct {
 	ShareAccounts []string
 }
 
-func (s *stepShareImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+func (s *StepShareImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	if len(s.ShareAccounts) == 0 {
 		return multistep.ActionContinue
 	}
 
-	client := state.Get("cvm_client").(*cvm.Client)
+	client := state.Get("cvm_client").(CVMAPI)
 
 	imageId := state.Get("image").(*cvm.Image).ImageId
 	Say(state, strings.Join(s.ShareAccounts, ","), "Trying to share image to")
@@ -36,7 +35,7 @@ func (s *stepShareImage) Run(ctx context.Context, state multistep.StateBag) mult
 	}
 	req.AccountIds = accounts
 	err := Retry(ctx, func(ctx context.Context) error {
-		_, e := client.ModifyImageSharePermission(req)
+		_, e := client.ModifyImageSharePermissionWithContext(ctx, req)
 		return e
 	})
 	if err != nil {
@@ -48,7 +47,7 @@ func (s *stepShareImage) Run(ctx context.Context, state multistep.StateBag) mult
 	return multistep.ActionContinue
 }
 
-func (s *stepShareImage) Cleanup(state multistep.StateBag) {
+func (s *StepShareImage) Cleanup(state multistep.StateBag) {
 	_, cancelled := state.GetOk(multistep.StateCancelled)
 	_, halted := state.GetOk(multistep.StateHalted)
 	if !cancelled && !halted {
@@ -56,7 +55,7 @@ func (s *stepShareImage) Cleanup(state multistep.StateBag) {
 	}
 
 	ctx := context.TODO()
-	client := state.Get("cvm_client").(*cvm.Client)
+	client := state.Get("cvm_client").(CVMAPI)
 
 	imageId := state.Get("image").(*cvm.Image).ImageId
 	SayClean(state, "image share")
@@ -71,10 +70,10 @@ func (s *stepShareImage) Cleanup(state multistep.StateBag) {
 	}
 	req.AccountIds = accounts
 	err := Retry(ctx, func(ctx context.Context) error {
-		_, e := client.ModifyImageSharePermission(req)
+		_, e := client.ModifyImageSharePermissionWithContext(ctx, req)
 		return e
 	})
 	if err != nil {
-		Error(state, err, fmt.Sprintf("Failed to cancel share image(%s), please delete it manually", *imageId))
+		Leak(state, err, "image share", *imageId)
 	}
 }
@@ -0,0 +1,118 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+type StepShutdownInstance struct {
+	ShutdownBehavior string
+	ShutdownCommand  string
+	ShutdownTimeout  time.Duration
+	StopType         string
+}
+
+func (s *StepShutdownInstance) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if s.ShutdownBehavior != "stop" {
+		return multistep.ActionContinue
+	}
+
+	// instance has been shut down by sysprep
+	if _, ok := state.GetOk("instance_sysprepped"); ok {
+		return multistep.ActionContinue
+	}
+
+	client := state.Get("cvm_client").(CVMAPI)
+	instance := state.Get("instance").(*cvm.Instance)
+
+	req := cvm.NewDescribeInstancesRequest()
+	req.InstanceIds = []*string{instance.InstanceId}
+	var resp *cvm.DescribeInstancesResponse
+	err := Retry(ctx, func(ctx context.Context) error {
+		var e error
+		resp, e = client.DescribeInstancesWithContext(ctx, req)
+		return e
+	})
+	if err != nil {
+		return Halt(state, err, "Failed to get instance info")
+	}
+	if *resp.Response.TotalCount == 0 {
+		return Halt(state, fmt.Errorf("instance(%s) not exist", *instance.InstanceId), "Failed to get instance info")
+	}
+	if *resp.Response.InstanceSet[0].InstanceState == "STOPPED" {
+		Message(state, "Instance has been stopped", "")
+		return multistep.ActionContinue
+	}
+
+	if s.ShutdownCommand != "" {
+		err = s.shutdown(ctx, state, instance)
+		if err == nil {
+			Message(state, "Instance stopped", "")
+			return multistep.ActionContinue
+		}
+		Message(state, fmt.Sprintf("%s, fallback to stop instance by api", err), "Failed to shutdown instance")
+	}
+
+	if action := stopInstance(ctx, state, instance, s.StopType); action != multistep.ActionContinue {
+		return action
+	}
+
+	Message(state, "Instance stopped", "")
+
+	return multistep.ActionContinue
+}
+
+// stopInstance stops instance by api with stopType and waits for it to be stopped
+func stopInstance(ctx context.Context, state multistep.StateBag, instance *cvm.Instance, stopType string) multistep.StepAction {
+	client := state.Get("cvm_client").(CVMAPI)
+
+	Say(state, fmt.Sprintf("%s with stop type %s", *instance.InstanceName, stopType), "Trying to stop instance")
+	stopReq := cvm.NewStopInstancesRequest()
+	stopReq.InstanceIds = []*string{instance.InstanceId}
+	stopReq.StopType = &stopType
+	err := Retry(ctx, func(ctx context.Context) error {
+		_, e := client.StopInstancesWithContext(ctx, stopReq)
+		return e
+	})
+	if err != nil {
+		return Halt(state, err, "Failed to stop instance")
+	}
+
+	Message(state, "Waiting for instance stop", "")
+	err = WaitForInstance(ctx, client, *instance.InstanceId, "STOPPED", 1800)
+	if err != nil {
+		return Halt(state, err, "Failed to wait for instance to be stopped")
+	}
+
+	return multistep.ActionContinue
+}
+
+// shutdown runs shutdown command through communicator and waits for instance to be stopped
+func (s *StepShutdownInstance) shutdown(ctx context.Context, state multistep.StateBag, instance *cvm.Instance) error {
+	raw, ok := state.GetOk("communicator")
+	if !ok || raw == nil {
+		return fmt.Errorf("no communicator")
+	}
+	comm := raw.(packersdk.Communicator)
+	client := state.Get("cvm_client").(CVMAPI)
+
+	Say(state, s.ShutdownCommand, "Trying to shutdown instance")
+	// the connection may be lost when instance shuts down, so do not wait for the command
+	cmd := &packersdk.RemoteCmd{Command: s.ShutdownCommand}
+	if err := comm.Start(ctx, cmd); err != nil {
+		return err
+	}
+
+	Message(state, "Waiting for instance stop", "")
+	return WaitForInstance(ctx, client, *instance.InstanceId, "STOPPED", int(s.ShutdownTimeout.Seconds()))
+}
+
+func (s *StepShutdownInstance) Cleanup(state multistep.StateBag) {}
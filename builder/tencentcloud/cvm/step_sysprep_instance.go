@@ -0,0 +1,50 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+// sysprep is started in background, because the connection will be lost
+// when instance shuts down.
+const sysprepCommand = `cmd /c start "" C:\Windows\System32\Sysprep\sysprep.exe /generalize /oobe /shutdown /quiet`
+
+type stepSysprepInstance struct {
+}
+
+func (s *stepSysprepInstance) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("cvm_client").(CVMAPI)
+	comm := state.Get("communicator").(packersdk.Communicator)
+	ui := state.Get("ui").(packersdk.Ui)
+	instance := state.Get("instance").(*cvm.Instance)
+
+	Say(state, *instance.InstanceName, "Trying to sysprep instance")
+
+	cmd := &packersdk.RemoteCmd{Command: sysprepCommand}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+		return Halt(state, err, "Failed to sysprep instance")
+	}
+	if cmd.ExitStatus() != 0 {
+		return Halt(state, fmt.Errorf("sysprep exited with status %d", cmd.ExitStatus()), "Failed to sysprep instance")
+	}
+
+	Message(state, "Waiting for instance shut down", "")
+	err := WaitForInstance(ctx, client, *instance.InstanceId, "STOPPED", 3600)
+	if err != nil {
+		return Halt(state, err, "Failed to wait for instance to be stopped")
+	}
+
+	state.Put("instance_sysprepped", true)
+	Message(state, "Instance sysprepped", "")
+
+	return multistep.ActionContinue
+}
+
+func (s *stepSysprepInstance) Cleanup(state multistep.StateBag) {}
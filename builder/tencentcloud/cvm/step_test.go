@@ -0,0 +1,1106 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
+)
+
+// stepTestEnv is the state bag of a build against the fake apis
+type stepTestEnv struct {
+	state  *multistep.BasicStateBag
+	ui     *packersdk.MockUi
+	config *Config
+	cvm    *fakeCVM
+	vpc    *fakeVPC
+	common *fakeCommon
+}
+
+func newStepTestEnv() *stepTestEnv {
+	config := &Config{buildId: "build-id"}
+	config.Region = "ap-guangzhou"
+	config.Zone = "ap-guangzhou-3"
+	config.ImageName = "packer-test"
+	config.SourceImageId = "img-source01"
+	config.PackerBuildName = "test"
+
+	env := &stepTestEnv{
+		state:  new(multistep.BasicStateBag),
+		ui:     &packersdk.MockUi{},
+		config: config,
+		cvm:    newFakeCVM("ap-guangzhou"),
+		vpc:    newFakeVPC(),
+		common: newFakeCommon(),
+	}
+	env.cvm.AddImage("img-source01", "ubuntu")
+	env.cvm.Peers["ap-shanghai"] = newFakeCVM("ap-shanghai")
+
+	env.state.Put("ui", env.ui)
+	env.state.Put("config", config)
+	env.state.Put("cvm_client", env.cvm)
+	env.state.Put("vpc_client", env.vpc)
+	env.state.Put("common_client", env.common)
+	env.state.Put("cvm_region_client", env.cvm.regionClient())
+	return env
+}
+
+// run runs the step, the state is marked halted as the runner does
+func (e *stepTestEnv) run(step multistep.Step) multistep.StepAction {
+	action := step.Run(context.Background(), e.state)
+	if action == multistep.ActionHalt {
+		e.state.Put(multistep.StateHalted, true)
+	}
+	return action
+}
+
+func (e *stepTestEnv) leaks() []*leakedResource {
+	if v, ok := e.state.GetOk("leaked_resources"); ok {
+		return v.([]*leakedResource)
+	}
+	return nil
+}
+
+// withNetwork creates the vpc, subnet and security group used by instances
+func (e *stepTestEnv) withNetwork() {
+	v := &vpc.Vpc{VpcId: common.StringPtr("vpc-test0001"), VpcName: common.StringPtr("vpc"), CidrBlock: common.StringPtr("10.0.0.0/16")}
+	e.vpc.Vpcs[*v.VpcId] = v
+	subnet := &vpc.Subnet{
+		VpcId:      v.VpcId,
+		SubnetId:   common.StringPtr("subnet-test0001"),
+		SubnetName: common.StringPtr("subnet"),
+		CidrBlock:  common.StringPtr("10.0.0.0/24"),
+		Zone:       common.StringPtr("ap-guangzhou-3"),
+	}
+	e.vpc.Subnets[*subnet.SubnetId] = subnet
+	e.vpc.SecurityGroups["sg-test0001"] = &vpc.SecurityGroup{
+		SecurityGroupId:   common.StringPtr("sg-test0001"),
+		SecurityGroupName: common.StringPtr("sg"),
+	}
+
+	e.state.Put("vpc_id", *v.VpcId)
+	e.state.Put("subnets", []*vpc.Subnet{subnet})
+	e.state.Put("security_group_id", "sg-test0001")
+	e.state.Put("source_image", e.cvm.Images["img-source01"])
+}
+
+// withInstance creates an instance in the state
+func (e *stepTestEnv) withInstance(state string) *cvm.Instance {
+	e.withNetwork()
+	instance := &cvm.Instance{
+		InstanceId:    common.StringPtr("ins-test0001"),
+		InstanceName:  common.StringPtr("packer-test"),
+		InstanceState: common.StringPtr(state),
+		LoginSettings: &cvm.LoginSettings{},
+		SystemDisk:    &cvm.SystemDisk{DiskId: common.StringPtr("disk-test0001")},
+	}
+	e.cvm.Instances[*instance.InstanceId] = instance
+	e.state.Put("instance", instance)
+	e.state.Put("instance_id", *instance.InstanceId)
+	return instance
+}
+
+// withImage creates the image of the build in the state
+func (e *stepTestEnv) withImage() *cvm.Image {
+	image := e.cvm.AddImage("img-build001", e.config.ImageName)
+	e.state.Put("image", image)
+	e.state.Put("tencentcloudimages", map[string]string{e.config.Region: *image.ImageId})
+	return image
+}
+
+type stepTestCase struct {
+	name    string
+	step    multistep.Step
+	setup   func(e *stepTestEnv)
+	action  multistep.StepAction
+	check   func(t *testing.T, e *stepTestEnv)
+	cleanup func(t *testing.T, e *stepTestEnv)
+}
+
+func runStepTests(t *testing.T, cases []stepTestCase) {
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newStepTestEnv()
+			if tc.setup != nil {
+				tc.setup(e)
+			}
+
+			if action := e.run(tc.step); action != tc.action {
+				t.Fatalf("step should return %v, got %v, error: %s", tc.action, action, e.ui.ErrorMessage)
+			}
+			if _, ok := e.state.GetOk("error"); ok != (tc.action == multistep.ActionHalt) {
+				t.Fatalf("error in state should be %t", !ok)
+			}
+			if tc.check != nil {
+				tc.check(t, e)
+			}
+
+			tc.step.Cleanup(e.state)
+			if tc.cleanup != nil {
+				tc.cleanup(t, e)
+			}
+		})
+	}
+}
+
+func TestStepPreValidate(t *testing.T) {
+	runStepTests(t, []stepTestCase{
+		{
+			name:   "image name available",
+			step:   &StepPreValidate{},
+			action: multistep.ActionContinue,
+		},
+		{
+			name:   "image name exists",
+			step:   &StepPreValidate{},
+			setup:  func(e *stepTestEnv) { e.cvm.AddImage("img-exists01", e.config.ImageName) },
+			action: multistep.ActionHalt,
+			check: func(t *testing.T, e *stepTestEnv) {
+				if e.state.Get("error") != ImageExistsError {
+					t.Fatalf("unexpected error: %v", e.state.Get("error"))
+				}
+			},
+		},
+		{
+			name:   "force delete existing image",
+			step:   &StepPreValidate{ForceDelete: true},
+			setup:  func(e *stepTestEnv) { e.cvm.AddImage("img-exists01", e.config.ImageName) },
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				if _, ok := e.cvm.Images["img-exists01"]; ok {
+					t.Fatal("existing image should be deleted")
+				}
+			},
+		},
+		{
+			name:   "describe images failed",
+			step:   &StepPreValidate{},
+			setup:  func(e *stepTestEnv) { e.cvm.fail("DescribeImages", fakeError("AuthFailure")) },
+			action: multistep.ActionHalt,
+		},
+	})
+}
+
+func TestStepCheckSourceImage(t *testing.T) {
+	runStepTests(t, []stepTestCase{
+		{
+			name:   "by id",
+			step:   &stepCheckSourceImage{SourceImageId: "img-source01"},
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				if *e.state.Get("source_image").(*cvm.Image).ImageId != "img-source01" {
+					t.Fatal("source image should be put in state")
+				}
+			},
+		},
+		{
+			name:   "by name regex",
+			step:   &stepCheckSourceImage{SourceImageName: "^ubu.*"},
+			action: multistep.ActionContinue,
+		},
+		{
+			name:   "not found",
+			step:   &stepCheckSourceImage{SourceImageId: "img-notfound"},
+			action: multistep.ActionHalt,
+		},
+	})
+}
+
+func TestStepConfigKeyPair(t *testing.T) {
+	debugKeyPath := filepath.Join(t.TempDir(), "debug.pem")
+
+	runStepTests(t, []stepTestCase{
+		{
+			name: "temporary keypair",
+			step: &stepConfigKeyPair{
+				Comm:         &communicator.Config{SSH: communicator.SSH{SSHTemporaryKeyPairName: "packer_test"}},
+				Debug:        true,
+				DebugKeyPath: debugKeyPath,
+			},
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				keyId := e.state.Get("temporary_key_pair_id").(string)
+				if _, ok := e.cvm.KeyPairs[keyId]; !ok {
+					t.Fatalf("keypair %s should be created", keyId)
+				}
+				params := e.cvm.ExtraParams["CreateKeyPair"][0]
+				if _, ok := params["TagSpecification"]; !ok {
+					t.Fatalf("keypair should be tagged: %v", params)
+				}
+			},
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if len(e.cvm.KeyPairs) != 0 {
+					t.Fatal("keypair should be deleted")
+				}
+				if len(e.leaks()) != 0 {
+					t.Fatalf("unexpected leaks: %v", e.leaks())
+				}
+			},
+		},
+		{
+			name: "delete keypair failed",
+			step: &stepConfigKeyPair{
+				Comm: &communicator.Config{SSH: communicator.SSH{SSHTemporaryKeyPairName: "packer_test"}},
+			},
+			setup:  func(e *stepTestEnv) { e.cvm.fail("DeleteKeyPairs", fakeError("InvalidKeyPairId.NotFound")) },
+			action: multistep.ActionContinue,
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if leaks := e.leaks(); len(leaks) != 1 || leaks[0].Resource != "keypair" {
+					t.Fatalf("keypair should be leaked: %v", leaks)
+				}
+			},
+		},
+		{
+			name:   "no temporary keypair",
+			step:   &stepConfigKeyPair{Comm: &communicator.Config{}},
+			action: multistep.ActionContinue,
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if e.cvm.called("CreateKeyPair") != 0 || e.cvm.called("DeleteKeyPairs") != 0 {
+					t.Fatal("keypair should not be created or deleted")
+				}
+			},
+		},
+		{
+			name: "create keypair failed",
+			step: &stepConfigKeyPair{
+				Comm: &communicator.Config{SSH: communicator.SSH{SSHTemporaryKeyPairName: "packer_test"}},
+			},
+			setup:  func(e *stepTestEnv) { e.cvm.fail("CreateKeyPair", fakeError("LimitExceeded")) },
+			action: multistep.ActionHalt,
+		},
+	})
+}
+
+func TestStepConfigVPC(t *testing.T) {
+	runStepTests(t, []stepTestCase{
+		{
+			name:   "create vpc with ipv6",
+			step:   &stepConfigVPC{VpcName: "packer_vpc", CidrBlock: "10.0.0.0/16", EnableIpv6: true},
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				vpcId := e.state.Get("vpc_id").(string)
+				if _, ok := e.vpc.Vpcs[vpcId]; !ok {
+					t.Fatalf("vpc %s should be created", vpcId)
+				}
+				if _, ok := e.state.GetOk("vpc_ipv6_cidr_block"); !ok {
+					t.Fatal("vpc ipv6 cidr block should be put in state")
+				}
+			},
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if len(e.vpc.Vpcs) != 0 {
+					t.Fatal("vpc should be deleted")
+				}
+			},
+		},
+		{
+			name: "existing vpc",
+			step: &stepConfigVPC{VpcId: "vpc-test0001"},
+			setup: func(e *stepTestEnv) {
+				e.withNetwork()
+				e.state.Remove("vpc_id")
+			},
+			action: multistep.ActionContinue,
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if e.vpc.called("DeleteVpc") != 0 {
+					t.Fatal("existing vpc should not be deleted")
+				}
+			},
+		},
+		{
+			name:   "vpc not found",
+			step:   &stepConfigVPC{VpcId: "vpc-notfound"},
+			action: multistep.ActionHalt,
+		},
+		{
+			name:   "delete vpc failed",
+			step:   &stepConfigVPC{VpcName: "packer_vpc", CidrBlock: "10.0.0.0/16"},
+			setup:  func(e *stepTestEnv) { e.vpc.fail("DeleteVpc", fakeError("UnsupportedOperation")) },
+			action: multistep.ActionContinue,
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if leaks := e.leaks(); len(leaks) != 1 || leaks[0].Resource != "vpc" {
+					t.Fatalf("vpc should be leaked: %v", leaks)
+				}
+			},
+		},
+	})
+}
+
+func TestStepConfigSubnet(t *testing.T) {
+	runStepTests(t, []stepTestCase{
+		{
+			name: "create subnet with ipv6",
+			step: &stepConfigSubnet{SubnetCidrBlock: "10.0.8.0/24", Zone: "ap-guangzhou-3", EnableIpv6: true},
+			setup: func(e *stepTestEnv) {
+				e.withNetwork()
+				e.state.Put("vpc_ipv6_cidr_block", "2402:4e00:1000:1100::/56")
+			},
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				subnets := e.state.Get("subnets").([]*vpc.Subnet)
+				if len(subnets) != 1 || *subnets[0].Ipv6CidrBlock != "2402:4e00:1000:1100::/64" {
+					t.Fatalf("subnet with ipv6 should be created: %v", subnets)
+				}
+			},
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if len(e.vpc.Subnets) != 1 {
+					t.Fatal("created subnet should be deleted")
+				}
+			},
+		},
+		{
+			name:   "existing subnet by name",
+			step:   &stepConfigSubnet{SubnetName: "subnet"},
+			setup:  func(e *stepTestEnv) { e.withNetwork() },
+			action: multistep.ActionContinue,
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if e.vpc.called("DeleteSubnet") != 0 {
+					t.Fatal("existing subnet should not be deleted")
+				}
+			},
+		},
+		{
+			name:   "existing subnet without ipv6",
+			step:   &stepConfigSubnet{SubnetId: "subnet-test0001", EnableIpv6: true},
+			setup:  func(e *stepTestEnv) { e.withNetwork() },
+			action: multistep.ActionHalt,
+		},
+		{
+			name:   "create subnet failed",
+			step:   &stepConfigSubnet{SubnetCidrBlock: "10.0.8.0/24", Zone: "ap-guangzhou-3"},
+			setup:  func(e *stepTestEnv) { e.state.Put("vpc_id", "vpc-notfound") },
+			action: multistep.ActionHalt,
+		},
+	})
+}
+
+func TestStepConfigSecurityGroup(t *testing.T) {
+	runStepTests(t, []stepTestCase{
+		{
+			name:   "create security group",
+			step:   &stepConfigSecurityGroup{SecurityGroupName: "packer_sg", WinRMPort: 5985, EnableIpv6: true},
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				sgId := e.state.Get("security_group_id").(string)
+				policies := e.vpc.Policies[sgId]
+				if len(policies) != 2 || len(policies[0].Ingress) != 3 || len(policies[1].Egress) != 2 {
+					t.Fatalf("unexpected policies: %v", policies)
+				}
+			},
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if len(e.vpc.SecurityGroups) != 0 {
+					t.Fatal("security group should be deleted")
+				}
+			},
+		},
+		{
+			name:   "security groups of launch template",
+			step:   &stepConfigSecurityGroup{},
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				if e.state.Get("security_group_id").(string) != "" {
+					t.Fatal("security group id should be empty")
+				}
+			},
+		},
+		{
+			name:   "existing security group not found",
+			step:   &stepConfigSecurityGroup{SecurityGroupId: "sg-notfound"},
+			action: multistep.ActionHalt,
+		},
+		{
+			name:   "create policies failed",
+			step:   &stepConfigSecurityGroup{SecurityGroupName: "packer_sg"},
+			setup:  func(e *stepTestEnv) { e.vpc.fail("CreateSecurityGroupPolicies", fakeError("LimitExceeded")) },
+			action: multistep.ActionHalt,
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if len(e.vpc.SecurityGroups) != 0 {
+					t.Fatal("security group should be deleted after failure")
+				}
+			},
+		},
+	})
+}
+
+func testStepRunInstance() *stepRunInstance {
+	return &stepRunInstance{
+		InstanceTypeCandidates: []string{"S5.SMALL1", "S5.SMALL2"},
+		InstanceName:           "packer-test",
+		Comm:                   &communicator.Config{},
+		DiskType:               "CLOUD_PREMIUM",
+		DiskSize:               50,
+		Region:                 "ap-guangzhou",
+		DataDisks: []tencentCloudDataDisk{
+			{DiskType: "CLOUD_SSD", DiskSize: 100, DiskName: "data", Tags: map[string]string{"disk": "data"}},
+		},
+	}
+}
+
+func TestStepRunInstance(t *testing.T) {
+	runStepTests(t, []stepTestCase{
+		{
+			name:   "run instance",
+			step:   testStepRunInstance(),
+			setup:  func(e *stepTestEnv) { e.withNetwork() },
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				instance := e.state.Get("instance").(*cvm.Instance)
+				if *instance.InstanceState != "RUNNING" || *instance.InstanceType != "S5.SMALL1" {
+					t.Fatalf("unexpected instance: %v", fakeJSON(instance))
+				}
+				if e.cvm.called("RunInstances") != 1 || len(e.cvm.ExtraParams["RunInstances"]) != 1 {
+					t.Fatal("instance should be created with extra params once")
+				}
+				dataDisk := ResourceName("cvm", "ap-guangzhou", "100000000001", "volume", *instance.DataDisks[0].DiskId)
+				if e.common.Tags[dataDisk]["disk"] != "data" || e.common.Tags[dataDisk][BuildIdTagKey] != "build-id" {
+					t.Fatalf("data disk should be tagged: %v", e.common.Tags)
+				}
+			},
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if len(e.cvm.Instances) != 0 {
+					t.Fatal("instance should be terminated")
+				}
+				if len(e.leaks()) != 0 {
+					t.Fatalf("unexpected leaks: %v", e.leaks())
+				}
+			},
+		},
+		{
+			name: "tag disks failed",
+			step: testStepRunInstance(),
+			setup: func(e *stepTestEnv) {
+				e.withNetwork()
+				e.common.fail("GetCallerIdentity", fakeError("UnauthorizedOperation"))
+			},
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				if e.common.called("GetCallerIdentity") == 0 {
+					t.Fatal("disks should be tagged")
+				}
+				if _, ok := e.state.GetOk("instance"); !ok {
+					t.Fatal("instance should be kept as tagging doesn't fail the build")
+				}
+			},
+		},
+		{
+			name: "keep tags of launch template",
+			step: func() *stepRunInstance {
+				step := testStepRunInstance()
+				step.Tags = map[string]string{"usage": "web"}
+				step.LaunchTemplateId = "lt-test0001"
+				step.LaunchTemplate = &cvm.LaunchTemplateVersionData{
+					TagSpecification: []*cvm.TagSpecification{
+						{
+							ResourceType: common.StringPtr("instance"),
+							Tags: []*cvm.Tag{
+								{Key: common.StringPtr("owner"), Value: common.StringPtr("ops")},
+								{Key: common.StringPtr("usage"), Value: common.StringPtr("template")},
+							},
+						},
+					},
+				}
+				return step
+			}(),
+			setup:  func(e *stepTestEnv) { e.withNetwork() },
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				tags := make(map[string]string)
+				for _, tag := range e.state.Get("instance").(*cvm.Instance).Tags {
+					tags[*tag.Key] = *tag.Value
+				}
+				if tags["owner"] != "ops" || tags["usage"] != "web" || tags[BuildIdTagKey] != "build-id" {
+					t.Fatalf("tags of launch template should be merged under the resource tags: %v", tags)
+				}
+			},
+		},
+		{
+			name: "tag data disks in any order",
+			step: func() *stepRunInstance {
+				step := testStepRunInstance()
+				step.DataDisks = append(step.DataDisks, tencentCloudDataDisk{
+					DiskType: "CLOUD_PREMIUM", DiskSize: 200, Tags: map[string]string{"disk": "log"},
+				})
+				return step
+			}(),
+			setup: func(e *stepTestEnv) {
+				e.withNetwork()
+				e.cvm.ReverseDataDisks = true
+			},
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				instance := e.state.Get("instance").(*cvm.Instance)
+				expected := map[string]string{"CLOUD_SSD": "data", "CLOUD_PREMIUM": "log"}
+				for _, disk := range instance.DataDisks {
+					name := ResourceName("cvm", "ap-guangzhou", "100000000001", "volume", *disk.DiskId)
+					if tag := e.common.Tags[name]["disk"]; tag != expected[*disk.DiskType] {
+						t.Errorf("%s disk should be tagged %q, got %q", *disk.DiskType, expected[*disk.DiskType], tag)
+					}
+				}
+			},
+		},
+		{
+			name: "skip zones without disk type",
+			step: testStepRunInstance(),
+			setup: func(e *stepTestEnv) {
+				e.withNetwork()
+				e.common.DiskTypes = map[string][]string{"ap-guangzhou-3": {"CLOUD_PREMIUM"}}
+			},
+			action: multistep.ActionHalt,
+			check: func(t *testing.T, e *stepTestEnv) {
+				if e.cvm.called("RunInstances") != 0 {
+					t.Fatal("instance should not be created")
+				}
+				if err := e.state.Get("error").(error); !strings.Contains(err.Error(), "disk type CLOUD_SSD not sold in any candidate zone") {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			name: "check disk types of prepaid instance",
+			step: func() *stepRunInstance {
+				step := testStepRunInstance()
+				step.InstanceChargeType = "PREPAID"
+				return step
+			}(),
+			setup:  func(e *stepTestEnv) { e.withNetwork() },
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				for _, chargeType := range e.common.DiskChargeTypes {
+					if chargeType != "PREPAID" {
+						t.Fatalf("disk types should be checked for prepaid disks, got %v", e.common.DiskChargeTypes)
+					}
+				}
+				if len(e.common.DiskChargeTypes) == 0 {
+					t.Fatal("disk types should be checked")
+				}
+			},
+		},
+		{
+			name: "run instance failed",
+			step: testStepRunInstance(),
+			setup: func(e *stepTestEnv) {
+				e.withNetwork()
+				e.cvm.fail("RunInstances", fakeError("ResourcesSoldOut.SpecifiedInstanceType"))
+			},
+			action: multistep.ActionHalt,
+			check: func(t *testing.T, e *stepTestEnv) {
+				// each instance type is tried
+				if e.cvm.called("RunInstances") != 2 {
+					t.Fatalf("instance types should be tried, got %d calls", e.cvm.called("RunInstances"))
+				}
+			},
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if e.cvm.called("TerminateInstances") != 0 {
+					t.Fatal("no instance should be terminated")
+				}
+			},
+		},
+		{
+			name: "terminate instance failed",
+			step: testStepRunInstance(),
+			setup: func(e *stepTestEnv) {
+				e.withNetwork()
+				e.cvm.fail("TerminateInstances", fakeError("UnauthorizedOperation"))
+			},
+			action: multistep.ActionContinue,
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if len(e.cvm.Instances) != 1 {
+					t.Fatal("instance should be left")
+				}
+				if leaks := e.leaks(); len(leaks) != 1 || leaks[0].Resource != "instance" {
+					t.Fatalf("instance should be leaked: %v", leaks)
+				}
+			},
+		},
+	})
+}
+
+func TestStepRunInstance_getUserData(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	userData := "#!/bin/sh\necho bootstrap-token-1234"
+	step := &stepRunInstance{UserData: userData}
+	encoded, err := step.getUserData(new(multistep.BasicStateBag))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoded != base64.StdEncoding.EncodeToString([]byte(userData)) {
+		t.Fatalf("unexpected user_data %s", encoded)
+	}
+	if strings.Contains(buf.String(), "bootstrap-token-1234") || strings.Contains(buf.String(), encoded) {
+		t.Fatalf("user_data should not be logged: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), fmt.Sprintf("%d bytes", len(userData))) {
+		t.Fatalf("size of user_data should be logged: %s", buf.String())
+	}
+	for _, secret := range []string{userData, encoded} {
+		if packersdk.LogSecretFilter.FilterString(secret) != "<sensitive>" {
+			t.Fatalf("%s should be filtered", secret)
+		}
+	}
+}
+
+func TestStepRunInstance_CleanupAfterFailure(t *testing.T) {
+	e := newStepTestEnv()
+	e.withNetwork()
+
+	steps := []multistep.Step{
+		&stepConfigSecurityGroup{SecurityGroupName: "packer_sg"},
+		testStepRunInstance(),
+		&stepCreateImage{},
+	}
+	e.cvm.fail("CreateImage", fakeError("InvalidParameterValue"))
+
+	var ran []multistep.Step
+	for _, step := range steps {
+		ran = append(ran, step)
+		if e.run(step) == multistep.ActionHalt {
+			break
+		}
+	}
+	if len(ran) != len(steps) {
+		t.Fatalf("should halt at creating image, ran %d steps", len(ran))
+	}
+	if len(e.cvm.Instances) != 1 || len(e.vpc.SecurityGroups) != 2 {
+		t.Fatal("instance and security group should be created")
+	}
+
+	for i := len(ran) - 1; i >= 0; i-- {
+		ran[i].Cleanup(e.state)
+	}
+
+	if len(e.cvm.Instances) != 0 {
+		t.Fatal("instance should be terminated")
+	}
+	if len(e.vpc.SecurityGroups) != 1 {
+		t.Fatal("created security group should be deleted")
+	}
+	if e.cvm.called("DeleteImages") != 0 {
+		t.Fatal("no image should be deleted")
+	}
+	if len(e.leaks()) != 0 {
+		t.Fatalf("unexpected leaks: %v", e.leaks())
+	}
+}
+
+func TestStepConfigEip(t *testing.T) {
+	runStepTests(t, []stepTestCase{
+		{
+			name:   "allocate eip",
+			step:   &stepConfigEip{AssociateEip: true},
+			setup:  func(e *stepTestEnv) { e.withInstance("RUNNING") },
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				if len(e.vpc.Addresses) != 1 {
+					t.Fatal("eip should be allocated")
+				}
+				for _, address := range e.vpc.Addresses {
+					if *address.AddressStatus != "BIND" || e.state.Get("eip_address") != *address.AddressIp {
+						t.Fatalf("eip should be associated: %v", fakeJSON(address))
+					}
+				}
+			},
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if len(e.vpc.Addresses) != 0 {
+					t.Fatal("eip should be released")
+				}
+			},
+		},
+		{
+			name: "existing eip",
+			step: &stepConfigEip{AssociateEip: true, EipAddresses: []string{"1.1.1.2", "1.1.1.3"}},
+			setup: func(e *stepTestEnv) {
+				e.withInstance("RUNNING")
+				for id, ip := range map[string]string{"eip-test0002": "1.1.1.2", "eip-test0003": "1.1.1.3"} {
+					e.vpc.Addresses[id] = &vpc.Address{
+						AddressId:     common.StringPtr(id),
+						AddressIp:     common.StringPtr(ip),
+						AddressStatus: common.StringPtr("UNBIND"),
+					}
+				}
+				e.vpc.Addresses["eip-test0002"].AddressStatus = common.StringPtr("BIND")
+			},
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				if e.state.Get("eip_address") != "1.1.1.3" {
+					t.Fatalf("unbind eip should be used, got %v", e.state.Get("eip_address"))
+				}
+			},
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if len(e.vpc.Addresses) != 2 || *e.vpc.Addresses["eip-test0003"].AddressStatus != "UNBIND" {
+					t.Fatal("existing eip should be disassociated but not released")
+				}
+			},
+		},
+		{
+			name: "associate eip failed",
+			step: &stepConfigEip{AssociateEip: true},
+			setup: func(e *stepTestEnv) {
+				e.withInstance("RUNNING")
+				e.vpc.fail("AssociateAddress", fakeError("InvalidInstanceId.NotFound"))
+			},
+			action: multistep.ActionHalt,
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if len(e.vpc.Addresses) != 0 {
+					t.Fatal("eip should be released after failure")
+				}
+			},
+		},
+		{
+			name:   "disabled",
+			step:   &stepConfigEip{},
+			action: multistep.ActionContinue,
+		},
+	})
+}
+
+func TestStepShutdownInstance(t *testing.T) {
+	runStepTests(t, []stepTestCase{
+		{
+			name:   "stop instance",
+			step:   &StepShutdownInstance{ShutdownBehavior: "stop", StopType: "SOFT_FIRST"},
+			setup:  func(e *stepTestEnv) { e.withInstance("RUNNING") },
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				if *e.cvm.Instances["ins-test0001"].InstanceState != "STOPPED" {
+					t.Fatal("instance should be stopped")
+				}
+			},
+		},
+		{
+			name:   "stopped instance",
+			step:   &StepShutdownInstance{ShutdownBehavior: "stop"},
+			setup:  func(e *stepTestEnv) { e.withInstance("STOPPED") },
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				if e.cvm.called("StopInstances") != 0 {
+					t.Fatal("stopped instance should not be stopped again")
+				}
+			},
+		},
+		{
+			name: "stop instance failed",
+			step: &StepShutdownInstance{ShutdownBehavior: "stop"},
+			setup: func(e *stepTestEnv) {
+				e.withInstance("RUNNING")
+				e.cvm.fail("StopInstances", fakeError("UnsupportedOperation"))
+			},
+			action: multistep.ActionHalt,
+		},
+		{
+			name:   "keep running",
+			step:   &StepShutdownInstance{ShutdownBehavior: "none"},
+			action: multistep.ActionContinue,
+		},
+	})
+}
+
+func TestStepDetachTempKeyPair(t *testing.T) {
+	runStepTests(t, []stepTestCase{
+		{
+			name: "detach keypair",
+			step: &StepDetachTempKeyPair{StopType: "HARD"},
+			setup: func(e *stepTestEnv) {
+				instance := e.withInstance("RUNNING")
+				instance.LoginSettings.KeyIds = []*string{common.StringPtr("skey-test0001")}
+				e.state.Put("temporary_key_pair_id", "skey-test0001")
+			},
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				instance := e.cvm.Instances["ins-test0001"]
+				if *instance.InstanceState != "STOPPED" || len(instance.LoginSettings.KeyIds) != 0 {
+					t.Fatalf("keypair should be detached from stopped instance: %v", fakeJSON(instance))
+				}
+				if len(e.cvm.StopTypes) != 1 || e.cvm.StopTypes[0] != "HARD" {
+					t.Fatalf("instance should be stopped with stop type HARD, got %v", e.cvm.StopTypes)
+				}
+			},
+		},
+		{
+			name:   "no temporary keypair",
+			step:   &StepDetachTempKeyPair{},
+			action: multistep.ActionContinue,
+		},
+		{
+			name: "detach keypair failed",
+			step: &StepDetachTempKeyPair{},
+			setup: func(e *stepTestEnv) {
+				e.withInstance("STOPPED")
+				e.state.Put("temporary_key_pair_id", "skey-test0001")
+				e.cvm.fail("DisassociateInstancesKeyPairs", fakeError("InvalidKeyPairId.NotFound"))
+			},
+			action: multistep.ActionHalt,
+		},
+	})
+}
+
+func TestStepDetachCamRole(t *testing.T) {
+	runStepTests(t, []stepTestCase{
+		{
+			name: "detach cam role",
+			step: &stepDetachCamRole{DetachCamRole: true},
+			setup: func(e *stepTestEnv) {
+				e.withInstance("RUNNING").CamRoleName = common.StringPtr("role")
+			},
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				if *e.cvm.Instances["ins-test0001"].CamRoleName != "" {
+					t.Fatal("cam role should be detached")
+				}
+			},
+		},
+		{
+			name:   "keep cam role",
+			step:   &stepDetachCamRole{},
+			action: multistep.ActionContinue,
+		},
+		{
+			name: "detach cam role failed",
+			step: &stepDetachCamRole{DetachCamRole: true},
+			setup: func(e *stepTestEnv) {
+				e.withInstance("RUNNING")
+				e.cvm.fail("ModifyInstancesAttribute", fakeError("InvalidParameter"))
+			},
+			action: multistep.ActionHalt,
+		},
+	})
+}
+
+func TestStepRebootInstance(t *testing.T) {
+	runStepTests(t, []stepTestCase{
+		{
+			name:   "reboot running instance",
+			step:   &stepRebootInstance{ShutdownBehavior: "reboot", StopType: "SOFT"},
+			setup:  func(e *stepTestEnv) { e.withInstance("RUNNING") },
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				if e.cvm.called("RebootInstances") != 1 || *e.cvm.Instances["ins-test0001"].InstanceState != "RUNNING" {
+					t.Fatal("instance should be rebooted")
+				}
+			},
+		},
+		{
+			name:   "start stopped instance",
+			step:   &stepRebootInstance{ShutdownBehavior: "reboot"},
+			setup:  func(e *stepTestEnv) { e.withInstance("STOPPED") },
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				if e.cvm.called("StartInstances") != 1 || *e.cvm.Instances["ins-test0001"].InstanceState != "RUNNING" {
+					t.Fatal("instance should be started")
+				}
+			},
+		},
+		{
+			name: "sysprepped instance",
+			step: &stepRebootInstance{ShutdownBehavior: "reboot"},
+			setup: func(e *stepTestEnv) {
+				e.withInstance("STOPPED")
+				e.state.Put("instance_sysprepped", true)
+			},
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				if *e.cvm.Instances["ins-test0001"].InstanceState != "STOPPED" {
+					t.Fatal("sysprepped instance should not be started")
+				}
+			},
+		},
+		{
+			name: "reboot failed",
+			step: &stepRebootInstance{ShutdownBehavior: "reboot"},
+			setup: func(e *stepTestEnv) {
+				e.withInstance("RUNNING")
+				e.cvm.fail("RebootInstances", fakeError("UnsupportedOperation"))
+			},
+			action: multistep.ActionHalt,
+		},
+	})
+}
+
+func TestStepSysprepInstance(t *testing.T) {
+	runStepTests(t, []stepTestCase{
+		{
+			name: "sysprep",
+			step: &stepSysprepInstance{},
+			setup: func(e *stepTestEnv) {
+				e.withInstance("RUNNING")
+				// sysprep shuts down the instance
+				_ = e.cvm.transit("ins-test0001", "STOPPING", "STOPPED")
+				e.state.Put("communicator", &packersdk.MockCommunicator{})
+			},
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				comm := e.state.Get("communicator").(*packersdk.MockCommunicator)
+				if comm.StartCmd.Command != sysprepCommand {
+					t.Fatalf("unexpected command: %s", comm.StartCmd.Command)
+				}
+				if _, ok := e.state.GetOk("instance_sysprepped"); !ok {
+					t.Fatal("instance should be marked sysprepped")
+				}
+			},
+		},
+		{
+			name: "sysprep failed",
+			step: &stepSysprepInstance{},
+			setup: func(e *stepTestEnv) {
+				e.withInstance("RUNNING")
+				e.state.Put("communicator", &packersdk.MockCommunicator{StartExitStatus: 1})
+			},
+			action: multistep.ActionHalt,
+		},
+	})
+}
+
+func TestStepCreateImage(t *testing.T) {
+	runStepTests(t, []stepTestCase{
+		{
+			name: "create image",
+			step: &stepCreateImage{},
+			setup: func(e *stepTestEnv) {
+				e.withInstance("STOPPED").DataDisks = []*cvm.DataDisk{{DiskId: common.StringPtr("disk-test0002")}}
+				e.config.ImageTags = map[string]string{"image": "{{ .SourceImageName }}"}
+			},
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				image := e.state.Get("image").(*cvm.Image)
+				if *image.ImageState != "NORMAL" || len(image.SnapshotSet) != 2 {
+					t.Fatalf("unexpected image: %v", fakeJSON(image))
+				}
+				if e.state.Get("tencentcloudimages").(map[string]string)["ap-guangzhou"] != *image.ImageId {
+					t.Fatal("image should be put in state")
+				}
+				snapshot := ResourceName("cvm", "ap-guangzhou", "100000000001", "snapshot", *image.SnapshotSet[1].SnapshotId)
+				if e.common.Tags[snapshot]["image"] != "ubuntu" {
+					t.Fatalf("snapshot should be tagged: %v", e.common.Tags)
+				}
+			},
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if len(e.cvm.Images) != 2 {
+					t.Fatal("image should be kept")
+				}
+			},
+		},
+		{
+			name:   "create image of running instance",
+			step:   &stepCreateImage{},
+			setup:  func(e *stepTestEnv) { e.withInstance("RUNNING") },
+			action: multistep.ActionHalt,
+		},
+		{
+			name: "tag snapshots failed",
+			step: &stepCreateImage{},
+			setup: func(e *stepTestEnv) {
+				e.withInstance("STOPPED")
+				e.common.fail("TagResources", fakeError("UnauthorizedOperation"))
+			},
+			action: multistep.ActionContinue,
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if len(e.cvm.Images) != 2 {
+					t.Fatal("image should be kept as tagging doesn't fail the build")
+				}
+			},
+		},
+	})
+}
+
+func TestStepShareImage(t *testing.T) {
+	runStepTests(t, []stepTestCase{
+		{
+			name:   "share image",
+			step:   &StepShareImage{ShareAccounts: []string{"100000000002"}},
+			setup:  func(e *stepTestEnv) { e.withImage() },
+			action: multistep.ActionContinue,
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if !e.cvm.Shares["img-build001"]["100000000002"] {
+					t.Fatal("image should be shared")
+				}
+			},
+		},
+		{
+			name:   "cancel share after failure",
+			step:   &StepShareImage{ShareAccounts: []string{"100000000002"}},
+			setup:  func(e *stepTestEnv) { e.withImage() },
+			action: multistep.ActionContinue,
+			check:  func(t *testing.T, e *stepTestEnv) { e.state.Put(multistep.StateHalted, true) },
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if e.cvm.Shares["img-build001"]["100000000002"] {
+					t.Fatal("image share should be cancelled")
+				}
+			},
+		},
+		{
+			name: "share image failed",
+			step: &StepShareImage{ShareAccounts: []string{"100000000002"}},
+			setup: func(e *stepTestEnv) {
+				e.withImage()
+				e.cvm.fail("ModifyImageSharePermission", fakeError("InvalidAccountId.NotFound"))
+			},
+			action: multistep.ActionHalt,
+			cleanup: func(t *testing.T, e *stepTestEnv) {
+				if leaks := e.leaks(); len(leaks) != 1 || leaks[0].Resource != "image share" {
+					t.Fatalf("image share should be leaked: %v", leaks)
+				}
+			},
+		},
+	})
+}
+
+func TestStepCopyImage(t *testing.T) {
+	runStepTests(t, []stepTestCase{
+		{
+			name:   "copy image",
+			step:   &StepCopyImage{DesinationRegions: []string{"ap-guangzhou", "ap-shanghai"}, SourceRegion: "ap-guangzhou"},
+			setup:  func(e *stepTestEnv) { e.withImage() },
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				images := e.state.Get("tencentcloudimages").(map[string]string)
+				copied, ok := e.cvm.Peers["ap-shanghai"].Images[images["ap-shanghai"]]
+				if !ok || *copied.ImageState != "NORMAL" {
+					t.Fatalf("image should be copied: %v", images)
+				}
+				resource := ResourceName("cvm", "ap-shanghai", "100000000001", "image", *copied.ImageId)
+				if e.common.Tags[resource][BuildIdTagKey] != "build-id" {
+					t.Fatalf("copied image should be tagged: %v", e.common.Tags)
+				}
+			},
+		},
+		{
+			name:   "copy encrypted image",
+			step:   &StepCopyImage{DesinationRegions: []string{"ap-shanghai"}, SourceRegion: "ap-guangzhou", Encrypted: true, KmsKeyIds: map[string]string{"ap-shanghai": "kms-key"}},
+			setup:  func(e *stepTestEnv) { e.withImage() },
+			action: multistep.ActionContinue,
+			check: func(t *testing.T, e *stepTestEnv) {
+				params := e.cvm.ExtraParams["SyncImages"]
+				if len(params) != 1 || params[0]["KmsKeyId"] != "kms-key" || params[0]["Encrypt"] != true {
+					t.Fatalf("image should be copied with kms key: %v", params)
+				}
+			},
+		},
+		{
+			name:   "copy to unknown region",
+			step:   &StepCopyImage{DesinationRegions: []string{"ap-nowhere"}, SourceRegion: "ap-guangzhou"},
+			setup:  func(e *stepTestEnv) { e.withImage() },
+			action: multistep.ActionHalt,
+		},
+		{
+			name:   "source region only",
+			step:   &StepCopyImage{DesinationRegions: []string{"ap-guangzhou"}, SourceRegion: "ap-guangzhou"},
+			action: multistep.ActionContinue,
+		},
+	})
+}
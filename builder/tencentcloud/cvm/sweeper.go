@@ -0,0 +1,505 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
+)
+
+// SweepNamePrefix is the name prefix of the temporary resources created by
+// packer, see packerId in RunConfig.Prepare.
+const SweepNamePrefix = "packer_"
+
+// the order of resource types is the order of deletion
+const (
+	SweepInstance      = "instance"
+	SweepKeyPair       = "key pair"
+	SweepSecurityGroup = "security group"
+	SweepSubnet        = "subnet"
+	SweepVpc           = "vpc"
+)
+
+var sweepOrder = []string{SweepInstance, SweepKeyPair, SweepSecurityGroup, SweepSubnet, SweepVpc}
+
+// vpc api returns the created time in Beijing time without zone
+var sweepTimeZone = time.FixedZone("CST", 8*3600)
+
+// SweepResource is an orphaned resource left by a packer build
+type SweepResource struct {
+	Region      string
+	Type        string
+	Id          string
+	Name        string
+	CreatedTime time.Time
+}
+
+func (r *SweepResource) String() string {
+	return fmt.Sprintf("%s %s(%s) %s, created at %s", r.Region, r.Type, r.Id, r.Name,
+		r.CreatedTime.Format(time.RFC3339))
+}
+
+// Sweeper finds and deletes the resources left by interrupted packer builds,
+// which are tagged with packer-build-id or named with prefix packer_.
+type Sweeper struct {
+	SecretId  string
+	SecretKey string
+	Regions   []string
+	// Only resources created before OlderThan ago are swept, so that
+	// resources of running builds are kept.
+	OlderThan time.Duration
+	// Timeout in seconds to wait for instances terminated
+	InstanceTimeout int
+	// Call the apis through the private network endpoints
+	UseInternalEndpoint bool
+	Ui                  packersdk.Ui
+
+	now func() time.Time
+	// newClients returns the clients of region, NewCvmClient and
+	// NewVpcClient are used if it is nil
+	newClients func(region string) (CVMAPI, VPCAPI, error)
+}
+
+// Find returns all orphaned resources in the regions
+func (s *Sweeper) Find(ctx context.Context) ([]*SweepResource, error) {
+	var resources []*SweepResource
+	for _, region := range s.regions() {
+		cvmClient, vpcClient, err := s.clients(region)
+		if err != nil {
+			return nil, err
+		}
+		found, err := s.findInRegion(ctx, region, cvmClient, vpcClient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find resources in region %s: %s", region, err)
+		}
+		resources = append(resources, found...)
+	}
+
+	return resources, nil
+}
+
+// Sweep deletes the resources in dependency order: instances, key pairs,
+// security groups, subnets and vpcs. It keeps going on failures and
+// returns an error listing the resources not deleted.
+func (s *Sweeper) Sweep(ctx context.Context, resources []*SweepResource) error {
+	byRegion := make(map[string][]*SweepResource)
+	var regions []string
+	for _, r := range resources {
+		if _, ok := byRegion[r.Region]; !ok {
+			regions = append(regions, r.Region)
+		}
+		byRegion[r.Region] = append(byRegion[r.Region], r)
+	}
+
+	var failed []string
+	for _, region := range regions {
+		cvmClient, vpcClient, err := s.clients(region)
+		if err != nil {
+			return err
+		}
+		failed = append(failed, s.sweepRegion(ctx, cvmClient, vpcClient, byRegion[region])...)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to delete %d resources: %s", len(failed), strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+func (s *Sweeper) regions() []string {
+	if len(s.Regions) > 0 {
+		return s.Regions
+	}
+	cachePath, err := RegionCachePath()
+	if err != nil {
+		cachePath = ""
+	}
+	client, err := NewCvmClient(s.accessConfig(), string(Guangzhou))
+	if err != nil {
+		return staticRegions()
+	}
+	return LoadRegions(context.TODO(), client, cachePath)
+}
+
+func (s *Sweeper) accessConfig() *TencentCloudAccessConfig {
+	return &TencentCloudAccessConfig{
+		SecretId:            s.SecretId,
+		SecretKey:           s.SecretKey,
+		UseInternalEndpoint: s.UseInternalEndpoint,
+	}
+}
+
+func (s *Sweeper) clients(region string) (CVMAPI, VPCAPI, error) {
+	if s.newClients != nil {
+		return s.newClients(region)
+	}
+	cvmClient, err := NewCvmClient(s.accessConfig(), region)
+	if err != nil {
+		return nil, nil, err
+	}
+	vpcClient, err := NewVpcClient(s.accessConfig(), region)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cvmClient, vpcClient, nil
+}
+
+func (s *Sweeper) findInRegion(ctx context.Context, region string, cvmClient CVMAPI,
+	vpcClient VPCAPI) ([]*SweepResource, error) {
+	var resources []*SweepResource
+	add := func(resourceType, id, name, createdTime string, tagKeys []string) bool {
+		r := s.match(region, resourceType, id, name, createdTime, tagKeys)
+		if r != nil {
+			resources = append(resources, r)
+		}
+		return r != nil
+	}
+
+	instances, err := describeAllInstances(ctx, cvmClient)
+	if err != nil {
+		return nil, err
+	}
+	swept := make(map[string]bool)
+	for _, instance := range instances {
+		var keys []string
+		for _, tag := range instance.Tags {
+			keys = append(keys, *tag.Key)
+		}
+		swept[*instance.InstanceId] = add(SweepInstance, *instance.InstanceId, stringValue(instance.InstanceName),
+			stringValue(instance.CreatedTime), keys)
+	}
+
+	keyPairs, err := describeAllKeyPairs(ctx, cvmClient)
+	if err != nil {
+		return nil, err
+	}
+	for _, keyPair := range keyPairs {
+		// a key pair still bound to an instance kept can not be deleted
+		inUse := false
+		for _, id := range keyPair.AssociatedInstanceIds {
+			if !swept[*id] {
+				inUse = true
+			}
+		}
+		if inUse {
+			continue
+		}
+		add(SweepKeyPair, *keyPair.KeyId, stringValue(keyPair.KeyName), stringValue(keyPair.CreatedTime), nil)
+	}
+
+	securityGroups, err := describeAllSecurityGroups(ctx, vpcClient)
+	if err != nil {
+		return nil, err
+	}
+	for _, sg := range securityGroups {
+		add(SweepSecurityGroup, *sg.SecurityGroupId, stringValue(sg.SecurityGroupName),
+			stringValue(sg.CreatedTime), vpcTagKeys(sg.TagSet))
+	}
+
+	subnets, err := describeAllSubnets(ctx, vpcClient)
+	if err != nil {
+		return nil, err
+	}
+	for _, subnet := range subnets {
+		add(SweepSubnet, *subnet.SubnetId, stringValue(subnet.SubnetName),
+			stringValue(subnet.CreatedTime), vpcTagKeys(subnet.TagSet))
+	}
+
+	vpcs, err := describeAllVpcs(ctx, vpcClient)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vpcs {
+		add(SweepVpc, *v.VpcId, stringValue(v.VpcName), stringValue(v.CreatedTime), vpcTagKeys(v.TagSet))
+	}
+
+	return resources, nil
+}
+
+// match returns the resource if it is created by packer and old enough
+func (s *Sweeper) match(region, resourceType, id, name, createdTime string, tagKeys []string) *SweepResource {
+	byPacker := strings.HasPrefix(name, SweepNamePrefix)
+	for _, key := range tagKeys {
+		if key == BuildIdTagKey {
+			byPacker = true
+		}
+	}
+	if !byPacker {
+		return nil
+	}
+
+	created, err := parseCreatedTime(createdTime)
+	if err != nil {
+		// do not delete anything we are not sure about
+		return nil
+	}
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	if now().Sub(created) < s.OlderThan {
+		return nil
+	}
+
+	return &SweepResource{
+		Region:      region,
+		Type:        resourceType,
+		Id:          id,
+		Name:        name,
+		CreatedTime: created,
+	}
+}
+
+func (s *Sweeper) sweepRegion(ctx context.Context, cvmClient CVMAPI, vpcClient VPCAPI,
+	resources []*SweepResource) []string {
+	byType := make(map[string][]*SweepResource)
+	for _, r := range resources {
+		byType[r.Type] = append(byType[r.Type], r)
+	}
+
+	var failed []string
+	for _, resourceType := range sweepOrder {
+		if resourceType == SweepInstance && len(byType[resourceType]) > 0 {
+			failed = append(failed, s.sweepInstances(ctx, cvmClient, byType[resourceType])...)
+			continue
+		}
+		for _, r := range byType[resourceType] {
+			s.Ui.Say(fmt.Sprintf("Deleting %s...", r))
+			err := Retry(ctx, func(ctx context.Context) error {
+				return deleteSweepResource(ctx, cvmClient, vpcClient, r)
+			})
+			if err != nil {
+				s.Ui.Error(fmt.Sprintf("Failed to delete %s(%s): %s", r.Type, r.Id, err))
+				failed = append(failed, r.Id)
+			}
+		}
+	}
+
+	return failed
+}
+
+// sweepInstances terminates the instances and waits for them gone, as the
+// network resources can not be deleted while the instances exist.
+func (s *Sweeper) sweepInstances(ctx context.Context, client CVMAPI, instances []*SweepResource) []string {
+	var failed []string
+	var ids []*string
+	for _, r := range instances {
+		s.Ui.Say(fmt.Sprintf("Deleting %s...", r))
+		req := cvm.NewTerminateInstancesRequest()
+		req.InstanceIds = []*string{common.StringPtr(r.Id)}
+		err := Retry(ctx, func(ctx context.Context) error {
+			_, e := client.TerminateInstancesWithContext(ctx, req)
+			return e
+		})
+		if err != nil {
+			s.Ui.Error(fmt.Sprintf("Failed to delete %s(%s): %s", r.Type, r.Id, err))
+			failed = append(failed, r.Id)
+			continue
+		}
+		ids = append(ids, common.StringPtr(r.Id))
+	}
+	if len(ids) == 0 {
+		return failed
+	}
+
+	s.Ui.Message("Waiting for instances terminated...")
+	timeout := s.InstanceTimeout
+	if timeout <= 0 {
+		timeout = 600
+	}
+	req := cvm.NewDescribeInstancesRequest()
+	req.InstanceIds = ids
+	for {
+		var resp *cvm.DescribeInstancesResponse
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			resp, e = client.DescribeInstancesWithContext(ctx, req)
+			return e
+		})
+		if err != nil {
+			s.Ui.Error(fmt.Sprintf("Failed to wait for instances terminated: %s, the network resources may fail to delete", err))
+			break
+		}
+		if *resp.Response.TotalCount == 0 {
+			break
+		}
+		time.Sleep(DefaultWaitForInterval * time.Second)
+		timeout = timeout - DefaultWaitForInterval
+		if timeout <= 0 {
+			s.Ui.Error("Wait for instances terminated timeout, the network resources may fail to delete")
+			break
+		}
+	}
+
+	return failed
+}
+
+func deleteSweepResource(ctx context.Context, cvmClient CVMAPI, vpcClient VPCAPI, r *SweepResource) error {
+	var err error
+	switch r.Type {
+	case SweepKeyPair:
+		req := cvm.NewDeleteKeyPairsRequest()
+		req.KeyIds = []*string{common.StringPtr(r.Id)}
+		_, err = cvmClient.DeleteKeyPairsWithContext(ctx, req)
+	case SweepSecurityGroup:
+		req := vpc.NewDeleteSecurityGroupRequest()
+		req.SecurityGroupId = common.StringPtr(r.Id)
+		_, err = vpcClient.DeleteSecurityGroupWithContext(ctx, req)
+	case SweepSubnet:
+		req := vpc.NewDeleteSubnetRequest()
+		req.SubnetId = common.StringPtr(r.Id)
+		_, err = vpcClient.DeleteSubnetWithContext(ctx, req)
+	case SweepVpc:
+		req := vpc.NewDeleteVpcRequest()
+		req.VpcId = common.StringPtr(r.Id)
+		_, err = vpcClient.DeleteVpcWithContext(ctx, req)
+	default:
+		err = fmt.Errorf("unknown resource type: %s", r.Type)
+	}
+	return err
+}
+
+func describeAllInstances(ctx context.Context, client CVMAPI) ([]*cvm.Instance, error) {
+	var instances []*cvm.Instance
+	req := cvm.NewDescribeInstancesRequest()
+	req.Limit = common.Int64Ptr(100)
+	for offset := int64(0); ; offset += *req.Limit {
+		req.Offset = common.Int64Ptr(offset)
+		var resp *cvm.DescribeInstancesResponse
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			resp, e = client.DescribeInstancesWithContext(ctx, req)
+			return e
+		})
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, resp.Response.InstanceSet...)
+		if int64(len(resp.Response.InstanceSet)) < *req.Limit {
+			return instances, nil
+		}
+	}
+}
+
+func describeAllKeyPairs(ctx context.Context, client CVMAPI) ([]*cvm.KeyPair, error) {
+	var keyPairs []*cvm.KeyPair
+	req := cvm.NewDescribeKeyPairsRequest()
+	req.Limit = common.Int64Ptr(100)
+	for offset := int64(0); ; offset += *req.Limit {
+		req.Offset = common.Int64Ptr(offset)
+		var resp *cvm.DescribeKeyPairsResponse
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			resp, e = client.DescribeKeyPairsWithContext(ctx, req)
+			return e
+		})
+		if err != nil {
+			return nil, err
+		}
+		keyPairs = append(keyPairs, resp.Response.KeyPairSet...)
+		if int64(len(resp.Response.KeyPairSet)) < *req.Limit {
+			return keyPairs, nil
+		}
+	}
+}
+
+func describeAllSecurityGroups(ctx context.Context, client VPCAPI) ([]*vpc.SecurityGroup, error) {
+	var securityGroups []*vpc.SecurityGroup
+	req := vpc.NewDescribeSecurityGroupsRequest()
+	req.Limit = common.StringPtr("100")
+	for offset := 0; ; offset += 100 {
+		req.Offset = common.StringPtr(fmt.Sprint(offset))
+		var resp *vpc.DescribeSecurityGroupsResponse
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			resp, e = client.DescribeSecurityGroupsWithContext(ctx, req)
+			return e
+		})
+		if err != nil {
+			return nil, err
+		}
+		securityGroups = append(securityGroups, resp.Response.SecurityGroupSet...)
+		if len(resp.Response.SecurityGroupSet) < 100 {
+			return securityGroups, nil
+		}
+	}
+}
+
+func describeAllSubnets(ctx context.Context, client VPCAPI) ([]*vpc.Subnet, error) {
+	var subnets []*vpc.Subnet
+	req := vpc.NewDescribeSubnetsRequest()
+	req.Limit = common.StringPtr("100")
+	for offset := 0; ; offset += 100 {
+		req.Offset = common.StringPtr(fmt.Sprint(offset))
+		var resp *vpc.DescribeSubnetsResponse
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			resp, e = client.DescribeSubnetsWithContext(ctx, req)
+			return e
+		})
+		if err != nil {
+			return nil, err
+		}
+		subnets = append(subnets, resp.Response.SubnetSet...)
+		if len(resp.Response.SubnetSet) < 100 {
+			return subnets, nil
+		}
+	}
+}
+
+func describeAllVpcs(ctx context.Context, client VPCAPI) ([]*vpc.Vpc, error) {
+	var vpcs []*vpc.Vpc
+	req := vpc.NewDescribeVpcsRequest()
+	req.Limit = common.StringPtr("100")
+	for offset := 0; ; offset += 100 {
+		req.Offset = common.StringPtr(fmt.Sprint(offset))
+		var resp *vpc.DescribeVpcsResponse
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			resp, e = client.DescribeVpcsWithContext(ctx, req)
+			return e
+		})
+		if err != nil {
+			return nil, err
+		}
+		vpcs = append(vpcs, resp.Response.VpcSet...)
+		if len(resp.Response.VpcSet) < 100 {
+			return vpcs, nil
+		}
+	}
+}
+
+// parseCreatedTime parses the created time of cvm (RFC3339 in UTC) and vpc
+// (Beijing time without zone) resources.
+func parseCreatedTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05", s, sweepTimeZone)
+}
+
+func vpcTagKeys(tags []*vpc.Tag) []string {
+	var keys []string
+	for _, tag := range tags {
+		if tag.Key != nil {
+			keys = append(keys, *tag.Key)
+		}
+	}
+	return keys
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,203 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
+)
+
+func TestSweeper_match(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := &Sweeper{
+		OlderThan: time.Hour,
+		now:       func() time.Time { return now },
+	}
+
+	cases := []struct {
+		name        string
+		createdTime string
+		tagKeys     []string
+		match       bool
+	}{
+		{"packer_abcd1234", "2023-05-01T10:00:00Z", nil, true},
+		{"packer_abcd1234", "2023-05-01T11:30:00Z", nil, false},
+		{"my-instance", "2023-05-01T10:00:00Z", nil, false},
+		{"my-instance", "2023-05-01T10:00:00Z", []string{BuildIdTagKey}, true},
+		// vpc resources are created in Beijing time
+		{"packer_abcd1234", "2023-05-01 18:00:00", nil, true},
+		{"packer_abcd1234", "2023-05-01 19:30:00", nil, false},
+		{"packer_abcd1234", "", nil, false},
+	}
+
+	for _, c := range cases {
+		r := s.match("ap-guangzhou", SweepInstance, "ins-12345678", c.name, c.createdTime, c.tagKeys)
+		if (r != nil) != c.match {
+			t.Fatalf("match(%s, %s, %v) should be %t", c.name, c.createdTime, c.tagKeys, c.match)
+		}
+	}
+}
+
+// newFakeSweeper returns a sweeper of the fakes with the orphaned resources
+// of a packer build, an instance kept and a key pair bound to it.
+func newFakeSweeper() (*Sweeper, *fakeCVM, *fakeVPC, *packersdk.MockUi) {
+	cvmClient := newFakeCVM("ap-guangzhou")
+	vpcClient := newFakeVPC()
+	ui := &packersdk.MockUi{}
+
+	cvmClient.Instances["ins-packer"] = &cvm.Instance{
+		InstanceId:    common.StringPtr("ins-packer"),
+		InstanceName:  common.StringPtr("packer_abcd1234"),
+		InstanceState: common.StringPtr("RUNNING"),
+		CreatedTime:   common.StringPtr("2023-05-01T10:00:00Z"),
+	}
+	cvmClient.Instances["ins-kept"] = &cvm.Instance{
+		InstanceId:    common.StringPtr("ins-kept"),
+		InstanceName:  common.StringPtr("web"),
+		InstanceState: common.StringPtr("RUNNING"),
+		CreatedTime:   common.StringPtr("2023-05-01T10:00:00Z"),
+	}
+	cvmClient.KeyPairs["skey-packer"] = &cvm.KeyPair{
+		KeyId:                 common.StringPtr("skey-packer"),
+		KeyName:               common.StringPtr("packer_abcd1234"),
+		AssociatedInstanceIds: []*string{common.StringPtr("ins-packer")},
+		CreatedTime:           common.StringPtr("2023-05-01T10:00:00Z"),
+	}
+	cvmClient.KeyPairs["skey-bound"] = &cvm.KeyPair{
+		KeyId:                 common.StringPtr("skey-bound"),
+		KeyName:               common.StringPtr("packer_efgh5678"),
+		AssociatedInstanceIds: []*string{common.StringPtr("ins-kept")},
+		CreatedTime:           common.StringPtr("2023-05-01T10:00:00Z"),
+	}
+	vpcClient.SecurityGroups["sg-packer"] = &vpc.SecurityGroup{
+		SecurityGroupId:   common.StringPtr("sg-packer"),
+		SecurityGroupName: common.StringPtr("packer_abcd1234"),
+		CreatedTime:       common.StringPtr("2023-05-01 18:00:00"),
+	}
+	vpcClient.Subnets["subnet-packer"] = &vpc.Subnet{
+		SubnetId:    common.StringPtr("subnet-packer"),
+		SubnetName:  common.StringPtr("packer_abcd1234"),
+		VpcId:       common.StringPtr("vpc-packer"),
+		Zone:        common.StringPtr("ap-guangzhou-3"),
+		CreatedTime: common.StringPtr("2023-05-01 18:00:00"),
+	}
+	vpcClient.Vpcs["vpc-packer"] = &vpc.Vpc{
+		VpcId:       common.StringPtr("vpc-packer"),
+		VpcName:     common.StringPtr("packer_abcd1234"),
+		CreatedTime: common.StringPtr("2023-05-01 18:00:00"),
+	}
+
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := &Sweeper{
+		Regions:   []string{"ap-guangzhou"},
+		OlderThan: time.Hour,
+		Ui:        ui,
+		now:       func() time.Time { return now },
+		newClients: func(region string) (CVMAPI, VPCAPI, error) {
+			return cvmClient, vpcClient, nil
+		},
+	}
+	return s, cvmClient, vpcClient, ui
+}
+
+// deleteCalls returns the deleting calls of the fake in order
+func deleteCalls(f *fakeAPI) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []string
+	for _, c := range f.calls {
+		if strings.HasPrefix(c, "Delete") || strings.HasPrefix(c, "Terminate") {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+func TestSweeper_Sweep(t *testing.T) {
+	s, cvmClient, vpcClient, ui := newFakeSweeper()
+	ctx := context.Background()
+
+	resources, err := s.Find(ctx)
+	if err != nil {
+		t.Fatalf("find: %s", err)
+	}
+	var ids []string
+	for _, r := range resources {
+		ids = append(ids, r.Id)
+	}
+	sort.Strings(ids)
+	expected := []string{"ins-packer", "sg-packer", "skey-packer", "subnet-packer", "vpc-packer"}
+	if strings.Join(ids, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected resources %v found, got %v", expected, ids)
+	}
+
+	// the order of the resources found does not matter
+	for i, j := 0, len(resources)-1; i < j; i, j = i+1, j-1 {
+		resources[i], resources[j] = resources[j], resources[i]
+	}
+	if err := s.Sweep(ctx, resources); err != nil {
+		t.Fatalf("sweep: %s", err)
+	}
+
+	var deleted []string
+	for _, m := range ui.SayMessages {
+		if strings.HasPrefix(m.Message, "Deleting ") {
+			deleted = append(deleted, m.Message)
+		}
+	}
+	if len(deleted) != len(sweepOrder) {
+		t.Fatalf("expected %d resources deleted, got %v", len(sweepOrder), deleted)
+	}
+	for i, resourceType := range sweepOrder {
+		if !strings.Contains(deleted[i], " "+resourceType+"(") {
+			t.Errorf("expected %s deleted at %d, got %q", resourceType, i, deleted[i])
+		}
+	}
+
+	if calls := deleteCalls(&cvmClient.fakeAPI); strings.Join(calls, ",") != "TerminateInstances,DeleteKeyPairs" {
+		t.Errorf("unexpected cvm calls %v", calls)
+	}
+	if calls := deleteCalls(&vpcClient.fakeAPI); strings.Join(calls, ",") != "DeleteSecurityGroup,DeleteSubnet,DeleteVpc" {
+		t.Errorf("unexpected vpc calls %v", calls)
+	}
+	if _, ok := cvmClient.Instances["ins-packer"]; ok {
+		t.Errorf("expected instance terminated")
+	}
+	if _, ok := cvmClient.KeyPairs["skey-bound"]; !ok {
+		t.Errorf("expected key pair bound to the instance kept not deleted")
+	}
+	if _, ok := cvmClient.Instances["ins-kept"]; !ok {
+		t.Errorf("expected instance kept")
+	}
+}
+
+func TestSweeper_Sweep_describeError(t *testing.T) {
+	s, cvmClient, _, ui := newFakeSweeper()
+	ctx := context.Background()
+
+	resources, err := s.Find(ctx)
+	if err != nil {
+		t.Fatalf("find: %s", err)
+	}
+	described := cvmClient.called("DescribeInstances")
+	cvmClient.fail("DescribeInstances", fakeError("AuthFailure.UnauthorizedOperation"))
+
+	if err := s.Sweep(ctx, resources); err != nil {
+		t.Fatalf("sweep: %s", err)
+	}
+	if !strings.Contains(ui.ErrorMessage, "AuthFailure.UnauthorizedOperation") {
+		t.Errorf("expected describe error reported, got %q", ui.ErrorMessage)
+	}
+	if n := cvmClient.called("DescribeInstances") - described; n != 1 {
+		t.Errorf("expected instances described once after terminated, got %d describes", n)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
+)
+
+const (
+	// BuildIdTagKey is the tag key of the build id on every resource created
+	BuildIdTagKey = "packer-build-id"
+	// BuildNameTagKey is the tag key of the build name on every resource created
+	BuildNameTagKey = "packer-build-name"
+)
+
+// BuildInfo is the info of a build read by the steps and helpers shared by
+// the builders of this plugin
+type BuildInfo struct {
+	Region      string
+	BuildId     string
+	BuildName   string
+	DefaultTags map[string]string
+	ImageName   string
+	ImageTags   map[string]string
+	// Ctx renders the templates in tags
+	Ctx interpolate.Context
+}
+
+// BuildConfig is the config of a builder kept in state as "config", which
+// provides the build info to the shared steps.
+type BuildConfig interface {
+	BuildInfo() *BuildInfo
+}
+
+// NewBuildId returns the build id tagged on every resource created, packer
+// shares it with plugins of the same run.
+func NewBuildId() string {
+	if id := os.Getenv("PACKER_RUN_UUID"); id != "" {
+		return id
+	}
+	return uuid.TimeOrderedUUID()
+}
+
+// tagTemplateData is the build info available in tag values and user data
+// parts as template
+type tagTemplateData struct {
+	BuildRegion     string
+	SourceImageId   string
+	SourceImageName string
+}
+
+// sts and tag are not vendored, so requests and responses are defined here.
+const (
+	stsService = "sts"
+	stsVersion = "2018-08-13"
+	tagService = "tag"
+	tagVersion = "2018-08-13"
+)
+
+type getCallerIdentityResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		AccountId *string `json:"AccountId"`
+		RequestId *string `json:"RequestId"`
+	} `json:"Response"`
+}
+
+type tagResourcesResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		RequestId *string `json:"RequestId"`
+	} `json:"Response"`
+}
+
+// GetOwnerUin returns the uin of the account which owns the resources
+func GetOwnerUin(ctx context.Context, client APISender) (string, error) {
+	req := tchttp.NewCommonRequest(stsService, stsVersion, "GetCallerIdentity")
+	resp := &getCallerIdentityResponse{BaseResponse: &tchttp.BaseResponse{}}
+	err := Retry(ctx, func(ctx context.Context) error {
+		req.SetContext(ctx)
+		return client.Send(req, resp)
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Response == nil || resp.Response.AccountId == nil {
+		return "", fmt.Errorf("no account id in response")
+	}
+
+	return *resp.Response.AccountId, nil
+}
+
+// ResourceName returns the six-segment name of resource used by tag service,
+// e.g. qcs::cvm:ap-guangzhou:uin/100000000001:volume/disk-xxxxxxxx
+func ResourceName(service, region, uin, resourcePrefix, resourceId string) string {
+	return fmt.Sprintf("qcs::%s:%s:uin/%s:%s/%s", service, region, uin, resourcePrefix, resourceId)
+}
+
+// TagResources adds tags to resources specified by six-segment names
+func TagResources(ctx context.Context, client APISender, resources []string, tags map[string]string) error {
+	if len(resources) == 0 || len(tags) == 0 {
+		return nil
+	}
+
+	keys := sortedTagKeys(tags)
+	tagList := make([]map[string]string, 0, len(keys))
+	for _, k := range keys {
+		tagList = append(tagList, map[string]string{
+			"TagKey":   k,
+			"TagValue": tags[k],
+		})
+	}
+
+	req := tchttp.NewCommonRequest(tagService, tagVersion, "TagResources")
+	err := req.SetActionParameters(map[string]interface{}{
+		"ResourceList": resources,
+		"Tags":         tagList,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp := &tagResourcesResponse{BaseResponse: &tchttp.BaseResponse{}}
+	return Retry(ctx, func(ctx context.Context) error {
+		req.SetContext(ctx)
+		return client.Send(req, resp)
+	})
+}
+
+// TagCvmResources adds tags to cvm resources in region, such as volume,
+// snapshot and image, the owner uin is cached in state.
+func TagCvmResources(ctx context.Context, state multistep.StateBag, region string, resourcePrefix string, ids []string, tags map[string]string) error {
+	return TagServiceResources(ctx, state, "cvm", region, resourcePrefix, ids, tags)
+}
+
+// TagServiceResources adds tags to the resources of service in region, such
+// as the blueprints of lighthouse, the owner uin is cached in state.
+func TagServiceResources(ctx context.Context, state multistep.StateBag, service, region string, resourcePrefix string, ids []string, tags map[string]string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	client := state.Get("common_client").(APISender)
+	uin, ok := state.GetOk("owner_uin")
+	if !ok {
+		ownerUin, err := GetOwnerUin(ctx, client)
+		if err != nil {
+			return err
+		}
+		state.Put("owner_uin", ownerUin)
+		uin = ownerUin
+	}
+
+	resources := make([]string, 0, len(ids))
+	for _, id := range ids {
+		resources = append(resources, ResourceName(service, region, uin.(string), resourcePrefix, id))
+	}
+
+	return TagResources(ctx, client, resources, tags)
+}
+
+// imageSnapshotIds returns the ids of snapshots of image
+func imageSnapshotIds(image *cvm.Image) []string {
+	var ids []string
+	for _, snapshot := range image.SnapshotSet {
+		if snapshot.SnapshotId != nil {
+			ids = append(ids, *snapshot.SnapshotId)
+		}
+	}
+	return ids
+}
+
+// ResourceTags returns the tags applied to the resources created by builder,
+// which are the default tags and the build tags merged with tags. Templates
+// in tag keys and values are rendered with the build info.
+func ResourceTags(state multistep.StateBag, tags map[string]string) (map[string]string, error) {
+	info := state.Get("config").(BuildConfig).BuildInfo()
+	ictx := info.Ctx
+	ictx.Data = buildTemplateData(state)
+
+	result := make(map[string]string)
+	for _, m := range []map[string]string{info.DefaultTags, tags} {
+		for k, v := range m {
+			key, err := interpolate.Render(k, &ictx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render tag key(%s): %w", k, err)
+			}
+			value, err := interpolate.Render(v, &ictx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render tag value(%s) of %s: %w", v, k, err)
+			}
+			result[key] = value
+		}
+	}
+
+	result[BuildIdTagKey] = info.BuildId
+	if info.BuildName != "" {
+		result[BuildNameTagKey] = info.BuildName
+	}
+
+	return result, nil
+}
+
+// buildTemplateData returns the build info of state for templates
+func buildTemplateData(state multistep.StateBag) *tagTemplateData {
+	data := &tagTemplateData{
+		BuildRegion: state.Get("config").(BuildConfig).BuildInfo().Region,
+	}
+	if image, ok := state.GetOk("source_image"); ok {
+		if image.(*cvm.Image).ImageId != nil {
+			data.SourceImageId = *image.(*cvm.Image).ImageId
+		}
+		if image.(*cvm.Image).ImageName != nil {
+			data.SourceImageName = *image.(*cvm.Image).ImageName
+		}
+	}
+	return data
+}
+
+func sortedTagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CvmTags converts tags to cvm tags
+func CvmTags(tags map[string]string) []*cvm.Tag {
+	var result []*cvm.Tag
+	for _, k := range sortedTagKeys(tags) {
+		result = append(result, &cvm.Tag{
+			Key:   common.StringPtr(k),
+			Value: common.StringPtr(tags[k]),
+		})
+	}
+	return result
+}
+
+// vpcTags converts tags to vpc tags
+func vpcTags(tags map[string]string) []*vpc.Tag {
+	var result []*vpc.Tag
+	for _, k := range sortedTagKeys(tags) {
+		result = append(result, &vpc.Tag{
+			Key:   common.StringPtr(k),
+			Value: common.StringPtr(tags[k]),
+		})
+	}
+	return result
+}
@@ -0,0 +1,54 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+func TestResourceTags(t *testing.T) {
+	config := &Config{
+		buildId: "build-id",
+	}
+	config.Region = "ap-guangzhou"
+	config.PackerBuildName = "build-name"
+	config.DefaultTags = map[string]string{
+		"team":   "platform",
+		"source": "{{ .SourceImageName }}",
+		"region": "{{ .BuildRegion }}",
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", config)
+	state.Put("source_image", &cvm.Image{
+		ImageId:   common.StringPtr("img-qwer1234"),
+		ImageName: common.StringPtr("ubuntu"),
+	})
+
+	tags, err := ResourceTags(state, map[string]string{"team": "image", "id": "{{ .SourceImageId }}"})
+	if err != nil {
+		t.Fatalf("shouldn't have err: %v", err)
+	}
+
+	expected := map[string]string{
+		"team":          "image",
+		"source":        "ubuntu",
+		"region":        "ap-guangzhou",
+		"id":            "img-qwer1234",
+		BuildIdTagKey:   "build-id",
+		BuildNameTagKey: "build-name",
+	}
+	if len(tags) != len(expected) {
+		t.Fatalf("invalid tags: %v", tags)
+	}
+	for k, v := range expected {
+		if tags[k] != v {
+			t.Fatalf("invalid tag %s: %s, expected %s", k, tags[k], v)
+		}
+	}
+}
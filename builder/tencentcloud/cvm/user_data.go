@@ -0,0 +1,139 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+// MaxUserDataSize is the max size of user data encoded in base64 accepted by
+// RunInstances
+const MaxUserDataSize = 16 * 1024
+
+// checkUserDataSize checks the size of userData encoded in base64 against
+// the limit of RunInstances
+func checkUserDataSize(userData string) error {
+	if size := base64.StdEncoding.EncodedLen(len(userData)); size > MaxUserDataSize {
+		return fmt.Errorf("user_data is %d bytes encoded in base64, exceeding the limit of %d bytes",
+			size, MaxUserDataSize)
+	}
+	return nil
+}
+
+// checkUserDataSize checks the size of the user data which can be assembled
+// before launching cvm, the user data with interpolated parts is checked
+// when launching cvm. The errors of reading files are reported by Prepare.
+func (cf *TencentCloudRunConfig) checkUserDataSize() error {
+	userData := cf.UserData
+	if userData == "" && cf.UserDataFile != "" {
+		data, err := os.ReadFile(cf.UserDataFile)
+		if err != nil {
+			return nil
+		}
+		userData = string(data)
+	}
+
+	if len(cf.UserDataParts) > 0 {
+		for _, part := range cf.UserDataParts {
+			if part.Interpolate {
+				return nil
+			}
+		}
+		var err error
+		if userData, err = multipartUserData(cf.UserDataParts, nil); err != nil {
+			return nil
+		}
+	}
+
+	return checkUserDataSize(userData)
+}
+
+// userDataContentTypes are the mime types of the content types of user data
+// parts supported by cloud-init
+var userDataContentTypes = map[string]string{
+	"cloud-config":  "text/cloud-config",
+	"x-shellscript": "text/x-shellscript",
+	"jinja2":        "text/jinja2",
+}
+
+func (p *tencentCloudUserDataPart) prepare(index int) []error {
+	var errs []error
+	if _, ok := userDataContentTypes[p.ContentType]; !ok {
+		errs = append(errs, fmt.Errorf("user_data_parts[%d]: content_type(%s) is invalid, "+
+			"valid values are cloud-config, x-shellscript and jinja2", index, p.ContentType))
+	}
+	switch {
+	case p.Content != "" && p.File != "":
+		errs = append(errs, fmt.Errorf("user_data_parts[%d]: only one of content or file can be specified", index))
+	case p.Content == "" && p.File == "":
+		errs = append(errs, fmt.Errorf("user_data_parts[%d]: content or file must be specified", index))
+	case p.File != "":
+		if _, err := os.Stat(p.File); err != nil {
+			errs = append(errs, fmt.Errorf("user_data_parts[%d]: file not exist: %s", index, err))
+		}
+	}
+	return errs
+}
+
+// render returns the content of part, which is rendered with ictx if
+// interpolate is set
+func (p *tencentCloudUserDataPart) render(ictx *interpolate.Context) (string, error) {
+	content := p.Content
+	if p.File != "" {
+		data, err := os.ReadFile(p.File)
+		if err != nil {
+			return "", err
+		}
+		content = string(data)
+	}
+	if !p.Interpolate {
+		return content, nil
+	}
+	return interpolate.Render(content, ictx)
+}
+
+// multipartUserData assembles parts into a MIME multipart document, which is
+// processed by cloud-init part by part
+func multipartUserData(parts []tencentCloudUserDataPart, ictx *interpolate.Context) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	for i, part := range parts {
+		content, err := part.render(ictx)
+		if err != nil {
+			return "", fmt.Errorf("failed to render user_data_parts[%d]: %s", i, err)
+		}
+
+		filename := fmt.Sprintf("part-%03d", i+1)
+		if part.File != "" {
+			filename = filepath.Base(part.File)
+		}
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", fmt.Sprintf("%s; charset=\"utf-8\"", userDataContentTypes[part.ContentType]))
+		header.Set("MIME-Version", "1.0")
+		header.Set("Content-Transfer-Encoding", "7bit")
+		header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+		pw, err := w.CreatePart(header)
+		if err != nil {
+			return "", err
+		}
+		if _, err := pw.Write([]byte(content)); err != nil {
+			return "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Content-Type: multipart/mixed; boundary=\"%s\"\nMIME-Version: 1.0\n\n%s",
+		w.Boundary(), body.String()), nil
+}
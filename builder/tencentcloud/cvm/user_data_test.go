@@ -0,0 +1,166 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+func TestStepRunInstance_getUserDataParts(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "bootstrap.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho {{ .SourceImageId }} in {{ .BuildRegion }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jinja := filepath.Join(dir, "hostname.yaml")
+	if err := os.WriteFile(jinja, []byte("## template: jinja\n#cloud-config\nhostname: {{ v1.instance_id }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &Config{TencentCloudAccessConfig: TencentCloudAccessConfig{Region: "ap-guangzhou"}})
+	state.Put("source_image", &cvm.Image{ImageId: common.StringPtr("img-source01")})
+	step := &stepRunInstance{
+		UserDataParts: []tencentCloudUserDataPart{
+			{ContentType: "cloud-config", Content: "#cloud-config\npackages: [nginx]"},
+			{ContentType: "x-shellscript", File: script, Interpolate: true},
+			{ContentType: "jinja2", File: jinja},
+		},
+	}
+
+	encoded, err := step.getUserData(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userData, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(userData)))
+	if err != nil {
+		t.Fatalf("user_data should be a MIME document: %s", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("unexpected content type %q: %v", msg.Header.Get("Content-Type"), err)
+	}
+
+	expected := []struct {
+		contentType string
+		filename    string
+		content     string
+	}{
+		{"text/cloud-config", "part-001", "#cloud-config\npackages: [nginx]"},
+		{"text/x-shellscript", "bootstrap.sh", "#!/bin/sh\necho img-source01 in ap-guangzhou"},
+		{"text/jinja2", "hostname.yaml", "## template: jinja\n#cloud-config\nhostname: {{ v1.instance_id }}"},
+	}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for i, want := range expected {
+		part, err := r.NextPart()
+		if err != nil {
+			t.Fatalf("part %d: %s", i, err)
+		}
+		if contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type")); contentType != want.contentType {
+			t.Errorf("part %d: expected content type %s, got %s", i, want.contentType, contentType)
+		}
+		if part.FileName() != want.filename {
+			t.Errorf("part %d: expected filename %s, got %s", i, want.filename, part.FileName())
+		}
+		content, _ := io.ReadAll(part)
+		if string(content) != want.content {
+			t.Errorf("part %d: expected content %q, got %q", i, want.content, content)
+		}
+	}
+	if _, err := r.NextPart(); err != io.EOF {
+		t.Fatalf("expected %d parts only", len(expected))
+	}
+}
+
+func TestStepRunInstance_getUserDataLimit(t *testing.T) {
+	// 12 KB is 16 KB encoded in base64
+	step := &stepRunInstance{UserData: strings.Repeat("a", 12*1024)}
+	if _, err := step.getUserData(new(multistep.BasicStateBag)); err != nil {
+		t.Fatalf("user_data of 16 KB encoded should be accepted: %s", err)
+	}
+
+	step.UserData += "a"
+	if _, err := step.getUserData(new(multistep.BasicStateBag)); err == nil ||
+		!strings.Contains(err.Error(), "exceeding the limit") {
+		t.Fatalf("expected user_data limit error, got %v", err)
+	}
+}
+
+func TestTencentCloudRunConfigPrepare_UserDataSize(t *testing.T) {
+	// 12 KB is 16 KB encoded in base64
+	large := strings.Repeat("a", 12*1024+1)
+	file := filepath.Join(t.TempDir(), "user_data.sh")
+	if err := os.WriteFile(file, []byte(large), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cf := testConfig()
+	cf.UserData = large
+	if err := cf.Prepare(nil); err == nil || !strings.Contains(fmt.Sprint(err), "exceeding the limit") {
+		t.Fatalf("expected user_data limit error, got %v", err)
+	}
+
+	cf = testConfig()
+	cf.UserDataFile = file
+	if err := cf.Prepare(nil); err == nil || !strings.Contains(fmt.Sprint(err), "exceeding the limit") {
+		t.Fatalf("expected user_data_file limit error, got %v", err)
+	}
+
+	cf = testConfig()
+	cf.UserDataParts = []tencentCloudUserDataPart{
+		{ContentType: "x-shellscript", Content: "#!/bin/sh\necho ok"},
+		{ContentType: "x-shellscript", File: file},
+	}
+	if err := cf.Prepare(nil); err == nil || !strings.Contains(fmt.Sprint(err), "exceeding the limit") {
+		t.Fatalf("expected user_data_parts limit error, got %v", err)
+	}
+
+	// the interpolated parts are checked when launching cvm
+	cf.UserDataParts[0].Interpolate = true
+	if err := cf.Prepare(nil); err != nil {
+		t.Fatalf("shouldn't have error: %v", err)
+	}
+}
+
+func TestBuilder_Prepare_userDataParts(t *testing.T) {
+	content := "#!/bin/sh\necho {{ .BuildRegion }}"
+	raw := map[string]interface{}{
+		"secret_id":                "secret-id",
+		"secret_key":               "secret-key",
+		"region":                   "ap-guangzhou",
+		"image_name":               "packer-test",
+		"source_image_id":          "img-source01",
+		"instance_type_candidates": []string{"S5.MEDIUM2"},
+		"communicator":             "none",
+		"user_data_parts": []map[string]interface{}{
+			{"content_type": "x-shellscript", "content": content, "interpolate": true},
+		},
+	}
+
+	var b Builder
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("prepare: %s", err)
+	}
+	if got := b.config.UserDataParts[0].Content; got != content {
+		t.Fatalf("user_data_parts should be rendered when launching cvm, got %q", got)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package cvm
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+const (
+	passwordLowers   = "abcdefghijklmnopqrstuvwxyz"
+	passwordUppers   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	passwordDigits   = "0123456789"
+	passwordSpecials = "()`~!@#$%^&*-+=_|{}[]:;<>,.?"
+)
+
+// winrmBootstrapScript enables WinRM over HTTPS with a self-signed certificate,
+// it is executed by cloudbase-init on the first boot.
+const winrmBootstrapScript = `#ps1_sysnative
+$ErrorActionPreference = "Stop"
+$cert = New-SelfSignedCertificate -DnsName $env:COMPUTERNAME -CertStoreLocation Cert:\LocalMachine\My
+Enable-PSRemoting -Force -SkipNetworkProfileCheck
+Get-ChildItem WSMan:\localhost\Listener | Where-Object { $_.Keys -contains "Transport=HTTPS" } | Remove-Item -Recurse -Force
+New-Item -Path WSMan:\localhost\Listener -Transport HTTPS -Address * -Port %d -CertificateThumbPrint $cert.Thumbprint -Force
+Set-Item WSMan:\localhost\Service\Auth\Basic -Value $true
+Set-Item WSMan:\localhost\MaxTimeoutms -Value 1800000
+New-NetFirewallRule -DisplayName "Packer WinRM HTTPS" -Direction Inbound -Protocol TCP -LocalPort %d -Action Allow
+`
+
+// WinRMUserData returns user_data that enables WinRM over HTTPS on port
+func WinRMUserData(port int) string {
+	return fmt.Sprintf(winrmBootstrapScript, port, port)
+}
+
+// GeneratePassword generates a random password meeting the rules of cvm,
+// which contains lower and upper letters, digits and special characters,
+// and does not start with a special character.
+func GeneratePassword(length int) (string, error) {
+	if length < 12 || length > 30 {
+		return "", fmt.Errorf("password length should be between 12 and 30")
+	}
+
+	groups := []string{passwordUppers, passwordLowers, passwordDigits, passwordSpecials}
+	all := passwordLowers + passwordUppers + passwordDigits + passwordSpecials
+
+	password := make([]byte, length)
+	for i := range password {
+		charset := all
+		if i < len(groups) {
+			// make sure every kind of characters appears at least once
+			charset = groups[i]
+		}
+		c, err := randomChar(charset)
+		if err != nil {
+			return "", err
+		}
+		password[i] = c
+	}
+
+	// shuffle all characters but the first one, which is always a letter
+	for i := length - 1; i > 1; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i)))
+		if err != nil {
+			return "", err
+		}
+		j := int(n.Int64()) + 1
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
+
+func randomChar(charset string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+	if err != nil {
+		return 0, err
+	}
+	return charset[n.Int64()], nil
+}
@@ -0,0 +1,349 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lighthouse
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+	tchttp "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/http"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// lighthouse is not vendored, so requests and responses are defined here.
+const (
+	lighthouseService = "lighthouse"
+	lighthouseVersion = "2020-03-24"
+)
+
+// Blueprint is a blueprint got by DescribeBlueprints
+type Blueprint struct {
+	BlueprintId    *string `json:"BlueprintId"`
+	BlueprintName  *string `json:"BlueprintName"`
+	BlueprintType  *string `json:"BlueprintType"`
+	BlueprintState *string `json:"BlueprintState"`
+	PlatformType   *string `json:"PlatformType"`
+	OsName         *string `json:"OsName"`
+	Description    *string `json:"Description"`
+}
+
+// Instance is a lighthouse instance got by DescribeInstances
+type Instance struct {
+	InstanceId           *string   `json:"InstanceId"`
+	InstanceName         *string   `json:"InstanceName"`
+	BundleId             *string   `json:"BundleId"`
+	BlueprintId          *string   `json:"BlueprintId"`
+	Zone                 *string   `json:"Zone"`
+	InstanceState        *string   `json:"InstanceState"`
+	LatestOperation      *string   `json:"LatestOperation"`
+	LatestOperationState *string   `json:"LatestOperationState"`
+	PublicAddresses      []*string `json:"PublicAddresses"`
+	PrivateAddresses     []*string `json:"PrivateAddresses"`
+}
+
+// KeyPair is a key pair created by CreateKeyPair
+type KeyPair struct {
+	KeyId      *string `json:"KeyId"`
+	KeyName    *string `json:"KeyName"`
+	PrivateKey *string `json:"PrivateKey"`
+}
+
+// InstanceOptions are the options of an instance created by CreateInstance
+type InstanceOptions struct {
+	BundleId     string
+	BlueprintId  string
+	InstanceName string
+	Zone         string
+	KeyId        string
+	Tags         map[string]string
+}
+
+type describeBlueprintsResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		TotalCount   *int64       `json:"TotalCount"`
+		BlueprintSet []*Blueprint `json:"BlueprintSet"`
+		RequestId    *string      `json:"RequestId"`
+	} `json:"Response"`
+}
+
+type createBlueprintResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		BlueprintId *string `json:"BlueprintId"`
+		RequestId   *string `json:"RequestId"`
+	} `json:"Response"`
+}
+
+type createInstancesResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		InstanceIdSet []*string `json:"InstanceIdSet"`
+		RequestId     *string   `json:"RequestId"`
+	} `json:"Response"`
+}
+
+type describeInstancesResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		TotalCount  *int64      `json:"TotalCount"`
+		InstanceSet []*Instance `json:"InstanceSet"`
+		RequestId   *string     `json:"RequestId"`
+	} `json:"Response"`
+}
+
+type createKeyPairResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		KeyPair   *KeyPair `json:"KeyPair"`
+		RequestId *string  `json:"RequestId"`
+	} `json:"Response"`
+}
+
+type lighthouseResponse struct {
+	*tchttp.BaseResponse
+	Response *struct {
+		RequestId *string `json:"RequestId"`
+	} `json:"Response"`
+}
+
+// send sends the lighthouse action with params, and retries on the
+// retryable errors
+func send(ctx context.Context, client cvm.APISender, action string, params map[string]interface{}, response tchttp.Response) error {
+	req := tchttp.NewCommonRequest(lighthouseService, lighthouseVersion, action)
+	if err := req.SetActionParameters(params); err != nil {
+		return err
+	}
+	return cvm.Retry(ctx, func(ctx context.Context) error {
+		req.SetContext(ctx)
+		return client.Send(req, response)
+	})
+}
+
+func describeBlueprints(ctx context.Context, client cvm.APISender, params map[string]interface{}) ([]*Blueprint, error) {
+	resp := &describeBlueprintsResponse{BaseResponse: &tchttp.BaseResponse{}}
+	if err := send(ctx, client, "DescribeBlueprints", params, resp); err != nil {
+		return nil, err
+	}
+	return resp.Response.BlueprintSet, nil
+}
+
+// DescribeBlueprint returns the blueprint, or nil if it doesn't exist
+func DescribeBlueprint(ctx context.Context, client cvm.APISender, blueprintId string) (*Blueprint, error) {
+	blueprints, err := describeBlueprints(ctx, client, map[string]interface{}{"BlueprintIds": []string{blueprintId}})
+	if err != nil || len(blueprints) == 0 {
+		return nil, err
+	}
+	return blueprints[0], nil
+}
+
+// GetBlueprintByName returns the custom blueprint named name, or nil if it
+// doesn't exist
+func GetBlueprintByName(ctx context.Context, client cvm.APISender, name string) (*Blueprint, error) {
+	blueprints, err := describeBlueprints(ctx, client, map[string]interface{}{
+		"Filters": []map[string]interface{}{
+			{"Name": "blueprint-name", "Values": []string{name}},
+			{"Name": "blueprint-type", "Values": []string{"PRIVATE"}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, blueprint := range blueprints {
+		if blueprint.BlueprintName != nil && *blueprint.BlueprintName == name {
+			return blueprint, nil
+		}
+	}
+	return nil, nil
+}
+
+// WaitForBlueprint wait for blueprint reaches state
+func WaitForBlueprint(ctx context.Context, client cvm.APISender, blueprintId string, state string, timeout int) (*Blueprint, error) {
+	for {
+		blueprint, err := DescribeBlueprint(ctx, client, blueprintId)
+		if err != nil {
+			return nil, err
+		}
+		if blueprint == nil {
+			return nil, fmt.Errorf("blueprint(%s) not exist", blueprintId)
+		}
+		if blueprint.BlueprintState != nil && *blueprint.BlueprintState == state {
+			return blueprint, nil
+		}
+		time.Sleep(cvm.DefaultWaitForInterval * time.Second)
+		timeout = timeout - cvm.DefaultWaitForInterval
+		if timeout <= 0 {
+			return nil, fmt.Errorf("wait blueprint(%s) state(%s) timeout", blueprintId, state)
+		}
+	}
+}
+
+// CreateBlueprint creates a custom blueprint from the instance, and returns
+// its id
+func CreateBlueprint(ctx context.Context, client cvm.APISender, instanceId, name, description string) (string, error) {
+	params := map[string]interface{}{
+		"BlueprintName": name,
+		"InstanceId":    instanceId,
+	}
+	if description != "" {
+		params["Description"] = description
+	}
+	resp := &createBlueprintResponse{BaseResponse: &tchttp.BaseResponse{}}
+	if err := send(ctx, client, "CreateBlueprint", params, resp); err != nil {
+		return "", err
+	}
+	return *resp.Response.BlueprintId, nil
+}
+
+// DeleteBlueprint deletes the custom blueprint
+func DeleteBlueprint(ctx context.Context, client cvm.APISender, blueprintId string) error {
+	params := map[string]interface{}{"BlueprintIds": []string{blueprintId}}
+	return send(ctx, client, "DeleteBlueprints", params, &lighthouseResponse{BaseResponse: &tchttp.BaseResponse{}})
+}
+
+// ShareBlueprint shares the blueprint to accounts
+func ShareBlueprint(ctx context.Context, client cvm.APISender, blueprintId string, accounts []string) error {
+	params := map[string]interface{}{
+		"BlueprintId": blueprintId,
+		"AccountIds":  accounts,
+	}
+	return send(ctx, client, "ShareBlueprintAcrossAccounts", params, &lighthouseResponse{BaseResponse: &tchttp.BaseResponse{}})
+}
+
+// CancelShareBlueprint cancels the sharing of blueprint to accounts
+func CancelShareBlueprint(ctx context.Context, client cvm.APISender, blueprintId string, accounts []string) error {
+	params := map[string]interface{}{
+		"BlueprintId": blueprintId,
+		"AccountIds":  accounts,
+	}
+	return send(ctx, client, "CancelShareBlueprintAcrossAccounts", params, &lighthouseResponse{BaseResponse: &tchttp.BaseResponse{}})
+}
+
+// SyncBlueprint copies the blueprint to regions, the copies have the same
+// name
+func SyncBlueprint(ctx context.Context, client cvm.APISender, blueprintId string, regions []string) error {
+	params := map[string]interface{}{
+		"BlueprintId":        blueprintId,
+		"DestinationRegions": regions,
+	}
+	return send(ctx, client, "SyncBlueprint", params, &lighthouseResponse{BaseResponse: &tchttp.BaseResponse{}})
+}
+
+// CreateInstance creates a monthly instance, and returns its id. The client
+// token makes the retries idempotent.
+func CreateInstance(ctx context.Context, client cvm.APISender, opts *InstanceOptions) (string, error) {
+	params := map[string]interface{}{
+		"BundleId":    opts.BundleId,
+		"BlueprintId": opts.BlueprintId,
+		"InstanceChargePrepaid": map[string]interface{}{
+			"Period":    1,
+			"RenewFlag": "NOTIFY_AND_MANUAL_RENEW",
+		},
+		"InstanceCount": 1,
+		"ClientToken":   uuid.TimeOrderedUUID(),
+	}
+	if opts.InstanceName != "" {
+		params["InstanceName"] = opts.InstanceName
+	}
+	if opts.Zone != "" {
+		params["Zones"] = []string{opts.Zone}
+	}
+	if opts.KeyId != "" {
+		params["LoginConfiguration"] = map[string]interface{}{
+			"AutoGeneratePassword": "YES",
+			"KeyIds":               []string{opts.KeyId},
+		}
+	}
+	if len(opts.Tags) > 0 {
+		tags := make([]map[string]string, 0, len(opts.Tags))
+		for k, v := range opts.Tags {
+			tags = append(tags, map[string]string{"Key": k, "Value": v})
+		}
+		params["Tags"] = tags
+	}
+
+	resp := &createInstancesResponse{BaseResponse: &tchttp.BaseResponse{}}
+	if err := send(ctx, client, "CreateInstances", params, resp); err != nil {
+		return "", err
+	}
+	if len(resp.Response.InstanceIdSet) == 0 {
+		return "", fmt.Errorf("no instance created")
+	}
+	return *resp.Response.InstanceIdSet[0], nil
+}
+
+// DescribeInstance returns the instance, or nil if it doesn't exist
+func DescribeInstance(ctx context.Context, client cvm.APISender, instanceId string) (*Instance, error) {
+	resp := &describeInstancesResponse{BaseResponse: &tchttp.BaseResponse{}}
+	err := send(ctx, client, "DescribeInstances", map[string]interface{}{"InstanceIds": []string{instanceId}}, resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Response.InstanceSet) == 0 {
+		return nil, nil
+	}
+	return resp.Response.InstanceSet[0], nil
+}
+
+// WaitForInstance wait for instance reaches state with its latest
+// operation done, an empty state waits for the instance to be gone
+func WaitForInstance(ctx context.Context, client cvm.APISender, instanceId string, state string, timeout int) (*Instance, error) {
+	for {
+		instance, err := DescribeInstance(ctx, client, instanceId)
+		if err != nil {
+			return nil, err
+		}
+		if instance == nil {
+			if state == "" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("instance(%s) not exist", instanceId)
+		}
+		if instance.LatestOperationState != nil && *instance.LatestOperationState == "FAILED" {
+			return nil, fmt.Errorf("instance(%s) operation %s failed", instanceId, *instance.LatestOperation)
+		}
+		if instance.InstanceState != nil && *instance.InstanceState == state &&
+			(instance.LatestOperationState == nil || *instance.LatestOperationState != "OPERATING") {
+			return instance, nil
+		}
+		time.Sleep(cvm.DefaultWaitForInterval * time.Second)
+		timeout = timeout - cvm.DefaultWaitForInterval
+		if timeout <= 0 {
+			return nil, fmt.Errorf("wait instance(%s) state(%s) timeout", instanceId, state)
+		}
+	}
+}
+
+// instanceAction runs the action on the instance, such as StopInstances
+func instanceAction(ctx context.Context, client cvm.APISender, action, instanceId string) error {
+	params := map[string]interface{}{"InstanceIds": []string{instanceId}}
+	return send(ctx, client, action, params, &lighthouseResponse{BaseResponse: &tchttp.BaseResponse{}})
+}
+
+// CreateKeyPair creates a key pair named name
+func CreateKeyPair(ctx context.Context, client cvm.APISender, name string) (*KeyPair, error) {
+	resp := &createKeyPairResponse{BaseResponse: &tchttp.BaseResponse{}}
+	if err := send(ctx, client, "CreateKeyPair", map[string]interface{}{"KeyName": name}, resp); err != nil {
+		return nil, err
+	}
+	return resp.Response.KeyPair, nil
+}
+
+// DeleteKeyPair deletes the key pair, which must not be bound to instances
+func DeleteKeyPair(ctx context.Context, client cvm.APISender, keyId string) error {
+	params := map[string]interface{}{"KeyIds": []string{keyId}}
+	return send(ctx, client, "DeleteKeyPairs", params, &lighthouseResponse{BaseResponse: &tchttp.BaseResponse{}})
+}
+
+// DetachKeyPair unbinds the key pair from the instance
+func DetachKeyPair(ctx context.Context, client cvm.APISender, keyId, instanceId string) error {
+	params := map[string]interface{}{
+		"KeyIds":      []string{keyId},
+		"InstanceIds": []string{instanceId},
+	}
+	return send(ctx, client, "DisassociateInstancesKeyPairs", params, &lighthouseResponse{BaseResponse: &tchttp.BaseResponse{}})
+}
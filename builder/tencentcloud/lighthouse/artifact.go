@@ -0,0 +1,99 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lighthouse
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+type Artifact struct {
+	// TencentCloudBlueprints are the ids of blueprints keyed by region
+	TencentCloudBlueprints map[string]string
+	BuilderIdValue         string
+	RegionClient           func(region string) cvm.APISender
+
+	// StateData should store data such as GeneratedData
+	// to be shared with post-processors
+	StateData map[string]interface{}
+}
+
+func (a *Artifact) BuilderId() string {
+	return a.BuilderIdValue
+}
+
+func (*Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	parts := make([]string, 0, len(a.TencentCloudBlueprints))
+	for region, blueprintId := range a.TencentCloudBlueprints {
+		parts = append(parts, fmt.Sprintf("%s:%s", region, blueprintId))
+	}
+	sort.Strings(parts)
+
+	return strings.Join(parts, ",")
+}
+
+func (a *Artifact) String() string {
+	parts := make([]string, 0, len(a.TencentCloudBlueprints))
+	for region, blueprintId := range a.TencentCloudBlueprints {
+		parts = append(parts, fmt.Sprintf("%s: %s", region, blueprintId))
+	}
+	sort.Strings(parts)
+
+	return fmt.Sprintf("Tencentcloud lighthouse blueprints(%s) were created.\n\n", strings.Join(parts, ","))
+}
+
+func (a *Artifact) State(name string) interface{} {
+	if _, ok := a.StateData[name]; ok {
+		return a.StateData[name]
+	}
+
+	switch name {
+	case "atlas.artifact.metadata":
+		return a.stateAtlasMetadata()
+	default:
+		return nil
+	}
+}
+
+func (a *Artifact) Destroy() error {
+	ctx := context.TODO()
+	errors := make([]error, 0)
+
+	for region, blueprintId := range a.TencentCloudBlueprints {
+		log.Printf("Delete tencentcloud lighthouse blueprint ID(%s) from region(%s)", blueprintId, region)
+
+		if err := DeleteBlueprint(ctx, a.RegionClient(region), blueprintId); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	if len(errors) == 1 {
+		return errors[0]
+	} else if len(errors) > 1 {
+		return &packersdk.MultiError{Errors: errors}
+	} else {
+		return nil
+	}
+}
+
+func (a *Artifact) stateAtlasMetadata() interface{} {
+	metadata := make(map[string]string)
+	for region, blueprintId := range a.TencentCloudBlueprints {
+		k := fmt.Sprintf("region.%s", region)
+		metadata[k] = blueprintId
+	}
+
+	return metadata
+}
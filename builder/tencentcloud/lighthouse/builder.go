@@ -0,0 +1,248 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package lighthouse
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+const BuilderId = "tencent.cloud.lighthouse"
+
+type Config struct {
+	common.PackerConfig          `mapstructure:",squash"`
+	cvm.TencentCloudAccessConfig `mapstructure:",squash"`
+	// Communicator settings, only `ssh` and `none` are supported
+	Comm communicator.Config `mapstructure:",squash"`
+
+	// The blueprint the instance is created from, such as a `PURE_OS`
+	// blueprint of a linux distribution.
+	SourceBlueprintId string `mapstructure:"source_blueprint_id" required:"true"`
+	// The bundle the instance is created with, which is the package of cpu,
+	// memory, system disk and bandwidth, such as `bundle_starter_s5_01`.
+	BundleId string `mapstructure:"bundle_id" required:"true"`
+	// The name of the instance. Default value is `packer_<random>`.
+	InstanceName string `mapstructure:"instance_name" required:"false"`
+	// The name of the custom blueprint created from the instance, it should
+	// be composed of no more than 60 characters.
+	BlueprintName string `mapstructure:"blueprint_name" required:"true"`
+	// The description of the custom blueprint, it should be no more than 60
+	// characters.
+	BlueprintDescription string `mapstructure:"blueprint_description" required:"false"`
+	// Regions that the blueprint will be copied to after it is created.
+	BlueprintCopyRegions []string `mapstructure:"blueprint_copy_regions" required:"false"`
+	// Accounts that the blueprint will be shared to after it is created.
+	BlueprintShareAccounts []string `mapstructure:"blueprint_share_accounts" required:"false"`
+	// Key/value pair tags that will be applied to the blueprint and its
+	// copies.
+	BlueprintTags map[string]string `mapstructure:"blueprint_tags" required:"false"`
+	// Key/value pair tags that will be applied to the instance.
+	RunTags map[string]string `mapstructure:"run_tags" required:"false"`
+	// Key/value pair tags that will be applied to every resource the builder
+	// creates, including the instance and the blueprint. `packer-build-id`
+	// and `packer-build-name` tags are always added. Build info such as
+	// `{{ .BuildRegion }}` can be used in the tags as template.
+	DefaultTags map[string]string `mapstructure:"default_tags" required:"false"`
+	// Do not check region when validate.
+	SkipRegionValidation bool `mapstructure:"skip_region_validation" required:"false"`
+
+	ctx     interpolate.Context
+	buildId string
+}
+
+type Builder struct {
+	config Config
+	runner multistep.Runner
+}
+
+func (b *Builder) ConfigSpec() hcldec.ObjectSpec { return b.config.FlatMapstructure().HCL2Spec() }
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
+	err := config.Decode(&b.config, &config.DecodeOpts{
+		PluginType:         BuilderId,
+		Interpolate:        true,
+		InterpolateContext: &b.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				// rendered with build info when creating resources
+				"default_tags",
+				"run_tags",
+				"blueprint_tags",
+			},
+		},
+	}, raws...)
+	b.config.ctx.EnableEnv = true
+	if err != nil {
+		return nil, nil, err
+	}
+
+	//tencentcloud support key pair name length max to 25
+	packerId := fmt.Sprintf("packer_%s", uuid.TimeOrderedUUID()[:8])
+	if b.config.InstanceName == "" {
+		b.config.InstanceName = packerId
+	}
+	if b.config.Comm.Type == "" {
+		b.config.Comm.Type = "ssh"
+	}
+	if b.config.Comm.SSHUsername == "" {
+		b.config.Comm.SSHUsername = "root"
+	}
+	if b.config.Comm.SSHPrivateKeyFile == "" && b.config.Comm.SSHPassword == "" && !b.config.Comm.SSHAgentAuth {
+		b.config.Comm.SSHTemporaryKeyPairName = packerId
+	}
+
+	// Accumulate any errors
+	var errs *packersdk.MultiError
+	errs = packersdk.MultiErrorAppend(errs, cvm.PrepareAccess(&b.config.ctx, &b.config.TencentCloudAccessConfig,
+		b.config.SkipRegionValidation)...)
+	errs = packersdk.MultiErrorAppend(errs, b.config.Comm.Prepare(&b.config.ctx)...)
+
+	if b.config.Comm.Type != "ssh" && b.config.Comm.Type != "none" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("communicator(%s) is not supported, only ssh and none are supported", b.config.Comm.Type))
+	}
+	if b.config.SourceBlueprintId == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("source_blueprint_id must be specified"))
+	} else if !cvm.CheckResourceIdFormat("lhbp", b.config.SourceBlueprintId) {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("source_blueprint_id wrong format"))
+	}
+	if b.config.BundleId == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("bundle_id must be specified"))
+	}
+	if b.config.BlueprintName == "" {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("blueprint_name must be specified"))
+	} else if utf8.RuneCountInString(b.config.BlueprintName) > 60 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("blueprint_name length should not exceed 60 characters"))
+	}
+	if utf8.RuneCountInString(b.config.BlueprintDescription) > 60 {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("blueprint_description length should not exceed 60 characters"))
+	}
+
+	regions, regionErrs := cvm.PrepareCopyRegions(&b.config.TencentCloudAccessConfig, b.config.BlueprintCopyRegions,
+		b.config.SkipRegionValidation)
+	b.config.BlueprintCopyRegions = regions
+	errs = packersdk.MultiErrorAppend(errs, regionErrs...)
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, nil, errs
+	}
+
+	cvm.LogSecrets(append(b.config.TencentCloudAccessConfig.SensitiveValues(),
+		b.config.Comm.SSHPassword,
+		b.config.Comm.SSHBastionPassword,
+		string(b.config.Comm.SSHPrivateKey),
+	)...)
+
+	return nil, nil, nil
+}
+
+// BuildInfo returns the build info of the config for the shared steps
+func (c *Config) BuildInfo() *cvm.BuildInfo {
+	return &cvm.BuildInfo{
+		Region:      c.Region,
+		BuildId:     c.buildId,
+		BuildName:   c.PackerBuildName,
+		DefaultTags: c.DefaultTags,
+		ImageName:   c.BlueprintName,
+		ImageTags:   c.BlueprintTags,
+		Ctx:         c.ctx,
+	}
+}
+
+func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
+	if err := cvm.SetApiTraceFile(b.config.ApiTraceFile); err != nil {
+		return nil, err
+	}
+	defer cvm.SetApiTraceFile("")
+
+	b.config.buildId = cvm.NewBuildId()
+
+	regionClient := func(region string) cvm.APISender {
+		return cvm.NewCommonClient(&b.config.TencentCloudAccessConfig, region)
+	}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &b.config)
+	state.Put("common_client", regionClient(b.config.Region))
+	state.Put("common_region_client", regionClientFunc(regionClient))
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+
+	steps := []multistep.Step{
+		&stepPreValidate{
+			BlueprintName: b.config.BlueprintName,
+		},
+		&stepCheckSourceBlueprint{
+			SourceBlueprintId: b.config.SourceBlueprintId,
+		},
+		&stepConfigKeyPair{
+			Debug:        b.config.PackerDebug,
+			Comm:         &b.config.Comm,
+			DebugKeyPath: fmt.Sprintf("lighthouse_%s.pem", b.config.PackerBuildName),
+		},
+		&stepRunInstance{
+			BundleId:     b.config.BundleId,
+			BlueprintId:  b.config.SourceBlueprintId,
+			InstanceName: b.config.InstanceName,
+			Zone:         b.config.Zone,
+			Tags:         b.config.RunTags,
+		},
+		&communicator.StepConnect{
+			Config:    &b.config.Comm,
+			SSHConfig: b.config.Comm.SSHConfigFunc(),
+			Host:      instanceHost,
+		},
+		&commonsteps.StepProvision{},
+		&commonsteps.StepCleanupTempKeys{
+			Comm: &b.config.Comm,
+		},
+		&stepStopInstance{},
+		&stepCreateBlueprint{
+			BlueprintName:        b.config.BlueprintName,
+			BlueprintDescription: b.config.BlueprintDescription,
+		},
+		&stepShareBlueprint{
+			ShareAccounts: b.config.BlueprintShareAccounts,
+		},
+		&stepCopyBlueprint{
+			DesinationRegions: b.config.BlueprintCopyRegions,
+			SourceRegion:      b.config.Region,
+		},
+	}
+
+	b.runner = commonsteps.NewRunner(steps, b.config.PackerConfig, ui)
+	b.runner.Run(ctx, state)
+	cvm.ReportLeaks(state)
+
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, rawErr.(error)
+	}
+
+	if _, ok := state.GetOk("blueprints"); !ok {
+		return nil, nil
+	}
+
+	artifact := &Artifact{
+		TencentCloudBlueprints: state.Get("blueprints").(map[string]string),
+		BuilderIdValue:         BuilderId,
+		RegionClient:           regionClient,
+		StateData:              map[string]interface{}{"generated_data": state.Get("generated_data")},
+	}
+
+	return artifact, nil
+}
@@ -0,0 +1,192 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lighthouse
+
+import (
+	"strings"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/mockapi"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"secret_id":              "secret-id",
+		"secret_key":             "secret-key",
+		"region":                 "ap-guangzhou",
+		"source_blueprint_id":    "lhbp-source01",
+		"bundle_id":              "bundle_starter_s5_01",
+		"blueprint_name":         "packer-blueprint",
+		"skip_region_validation": true,
+	}
+}
+
+func TestBuilder_Prepare(t *testing.T) {
+	raw := testConfig()
+	raw["blueprint_copy_regions"] = []string{"ap-shanghai", "ap-shanghai"}
+
+	var b Builder
+	if _, _, err := b.Prepare(raw); err != nil {
+		t.Fatalf("prepare: %s", err)
+	}
+
+	if b.config.Comm.Type != "ssh" || b.config.Comm.SSHUsername != "root" {
+		t.Errorf("unexpected defaults: communicator %q, ssh_username %q", b.config.Comm.Type, b.config.Comm.SSHUsername)
+	}
+	if b.config.Comm.SSHTemporaryKeyPairName == "" || b.config.InstanceName != b.config.Comm.SSHTemporaryKeyPairName {
+		t.Errorf("expected temporary keypair named as instance %q, got %q", b.config.InstanceName,
+			b.config.Comm.SSHTemporaryKeyPairName)
+	}
+	if len(b.config.BlueprintCopyRegions) != 1 {
+		t.Errorf("expected duplicated regions removed, got %v", b.config.BlueprintCopyRegions)
+	}
+}
+
+func TestBuilder_Prepare_errors(t *testing.T) {
+	mockapi.CheckPrepareErrors(t, func() packersdk.Builder { return &Builder{} }, testConfig(), map[string]mockapi.PrepareError{
+		"no source blueprint": {Key: "source_blueprint_id", Err: "source_blueprint_id must be specified"},
+		"source blueprint":    {Key: "source_blueprint_id", Value: "img-source01", Err: "source_blueprint_id wrong format"},
+		"no bundle":           {Key: "bundle_id", Err: "bundle_id must be specified"},
+		"no blueprint name":   {Key: "blueprint_name", Err: "blueprint_name must be specified"},
+		"blueprint name":      {Key: "blueprint_name", Value: strings.Repeat("a", 61), Err: "blueprint_name length should not exceed"},
+		"description":         {Key: "blueprint_description", Value: strings.Repeat("a", 61), Err: "blueprint_description length should not exceed"},
+		"communicator":        {Key: "communicator", Value: "winrm", Err: "communicator(winrm) is not supported"},
+	})
+}
+
+// runBuild runs a build of the mock api server with the none communicator
+func runBuild(t *testing.T, server *mockapi.Server) (packersdk.Artifact, error) {
+	return server.Build(t, &Builder{}, nil, map[string]interface{}{
+		"source_blueprint_id":      "lhbp-source01",
+		"bundle_id":                "bundle_starter_s5_01",
+		"blueprint_name":           "packer-blueprint",
+		"blueprint_copy_regions":   []string{"ap-guangzhou", "ap-shanghai"},
+		"blueprint_share_accounts": []string{"100000000002"},
+		"blueprint_tags":           map[string]string{"usage": "web"},
+		"communicator":             "none",
+		"skip_region_validation":   true,
+	})
+}
+
+// customBlueprints returns the custom blueprints in region
+func customBlueprints(server *mockapi.Server, region string) []*mockapi.Blueprint {
+	var blueprints []*mockapi.Blueprint
+	for _, bp := range server.Blueprints(region) {
+		if bp.BlueprintType == "PRIVATE" {
+			blueprints = append(blueprints, bp)
+		}
+	}
+	return blueprints
+}
+
+// shareActions are the actions sharing and deleting blueprints
+var shareActions = []string{"ShareBlueprintAcrossAccounts", "CancelShareBlueprintAcrossAccounts", "DeleteBlueprints"}
+
+func TestBuilder_Run(t *testing.T) {
+	cases := []struct {
+		name   string
+		faults []mockapi.Fault
+		err    string
+		// untagged means the blueprints are built without tags
+		untagged bool
+		// shareActions are the requests sharing and deleting blueprints in order
+		shareActions string
+	}{
+		{
+			name:         "success",
+			shareActions: "ShareBlueprintAcrossAccounts",
+		},
+		{
+			name:         "tag failed",
+			faults:       []mockapi.Fault{{Action: "TagResources", Code: "AuthFailure.UnauthorizedOperation"}},
+			untagged:     true,
+			shareActions: "ShareBlueprintAcrossAccounts",
+		},
+		{
+			name:   "create blueprint failed",
+			faults: []mockapi.Fault{{Action: "CreateBlueprint", Code: mockapi.CodeQuota}},
+			err:    mockapi.CodeQuota,
+		},
+		{
+			name:         "share blueprint failed",
+			faults:       []mockapi.Fault{{Action: "ShareBlueprintAcrossAccounts", Code: mockapi.CodeQuota}},
+			err:          mockapi.CodeQuota,
+			shareActions: "ShareBlueprintAcrossAccounts,DeleteBlueprints",
+		},
+		{
+			name:         "sync blueprint failed",
+			faults:       []mockapi.Fault{{Action: "SyncBlueprint", Code: mockapi.CodeQuota}},
+			err:          mockapi.CodeQuota,
+			shareActions: "ShareBlueprintAcrossAccounts,CancelShareBlueprintAcrossAccounts,DeleteBlueprints",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := mockapi.NewServer("AKIDmock", "mock-secret")
+			defer server.Close()
+			server.AddBlueprint("ap-guangzhou", "lhbp-source01", "TencentOS Server 3.1")
+			for _, f := range tc.faults {
+				server.Inject(f)
+			}
+
+			artifact, err := runBuild(t, server)
+			for _, region := range []string{"ap-guangzhou", "ap-shanghai"} {
+				server.AssertNoLeaks(t, region, "blueprint")
+			}
+			// the blueprint shared is unshared before it is deleted
+			if actions := server.ActionsOf(shareActions...); actions != tc.shareActions {
+				t.Errorf("expected share actions %q, got %q", tc.shareActions, actions)
+			}
+
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error %q, got %v", tc.err, err)
+				}
+				for _, region := range []string{"ap-guangzhou", "ap-shanghai"} {
+					if n := len(customBlueprints(server, region)); n != 0 {
+						t.Errorf("expected blueprints deleted in %s, got %d blueprints", region, n)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err)
+			}
+
+			blueprints := artifact.(*Artifact).TencentCloudBlueprints
+			for _, region := range []string{"ap-guangzhou", "ap-shanghai"} {
+				found := false
+				for _, bp := range customBlueprints(server, region) {
+					if bp.BlueprintId == blueprints[region] && bp.BlueprintName == "packer-blueprint" && bp.BlueprintState == "NORMAL" {
+						found = true
+					}
+				}
+				if !found {
+					t.Fatalf("expected blueprint %q named packer-blueprint in %s", blueprints[region], region)
+				}
+				name := server.ResourceName("lighthouse", region, "blueprint", blueprints[region])
+				if tags := server.Tags(name); !tc.untagged && (tags["usage"] != "web" || tags["packer-build-id"] == "") {
+					t.Errorf("unexpected tags of blueprint in %s: %v", region, tags)
+				}
+			}
+			for _, bp := range customBlueprints(server, "ap-guangzhou") {
+				if len(bp.SharedAccounts) != 1 || bp.SharedAccounts[0] != "100000000002" {
+					t.Errorf("expected blueprint shared to 100000000002, got %v", bp.SharedAccounts)
+				}
+			}
+
+			if err := artifact.Destroy(); err != nil {
+				t.Fatalf("destroy: %s", err)
+			}
+			for _, region := range []string{"ap-guangzhou", "ap-shanghai"} {
+				if n := len(customBlueprints(server, region)); n != 0 {
+					t.Errorf("expected blueprints destroyed in %s, got %d blueprints", region, n)
+				}
+			}
+		})
+	}
+}
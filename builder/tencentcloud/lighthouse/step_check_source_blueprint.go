@@ -0,0 +1,46 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lighthouse
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// stepCheckSourceBlueprint checks the source blueprint is a linux blueprint
+// available to create instances.
+type stepCheckSourceBlueprint struct {
+	SourceBlueprintId string
+}
+
+func (s *stepCheckSourceBlueprint) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("common_client").(cvm.APISender)
+
+	cvm.Say(state, s.SourceBlueprintId, "Trying to check source blueprint")
+
+	blueprint, err := DescribeBlueprint(ctx, client, s.SourceBlueprintId)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to get source blueprint info")
+	}
+	if blueprint == nil {
+		return cvm.Halt(state, fmt.Errorf("blueprint(%s) not found", s.SourceBlueprintId), "")
+	}
+	if blueprint.PlatformType != nil && *blueprint.PlatformType == "WINDOWS" {
+		return cvm.Halt(state, fmt.Errorf("blueprint(%s) is a windows blueprint, which is not supported", s.SourceBlueprintId), "")
+	}
+	if blueprint.BlueprintState != nil && *blueprint.BlueprintState != "NORMAL" {
+		return cvm.Halt(state, fmt.Errorf("blueprint(%s) is %s", s.SourceBlueprintId, *blueprint.BlueprintState), "")
+	}
+
+	state.Put("source_blueprint", blueprint)
+	cvm.Message(state, *blueprint.BlueprintName, "Blueprint found")
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCheckSourceBlueprint) Cleanup(multistep.StateBag) {}
@@ -0,0 +1,104 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lighthouse
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// stepConfigKeyPair loads the configured private key, or creates a temporary
+// key pair bound to the instance when it is created.
+type stepConfigKeyPair struct {
+	Debug        bool
+	Comm         *communicator.Config
+	DebugKeyPath string
+	keyId        string
+}
+
+func (s *stepConfigKeyPair) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("common_client").(cvm.APISender)
+
+	if s.Comm.SSHPrivateKeyFile != "" {
+		cvm.Say(state, "Using existing SSH private key", "")
+		privateKeyBytes, err := os.ReadFile(s.Comm.SSHPrivateKeyFile)
+		if err != nil {
+			return cvm.Halt(state, err, fmt.Sprintf("Failed to load configured private key(%s)", s.Comm.SSHPrivateKeyFile))
+		}
+		s.Comm.SSHPrivateKey = privateKeyBytes
+		cvm.LogSecrets(string(privateKeyBytes))
+		cvm.Message(state, fmt.Sprintf("Loaded %d bytes private key data", len(s.Comm.SSHPrivateKey)), "")
+		return multistep.ActionContinue
+	}
+
+	if s.Comm.SSHTemporaryKeyPairName == "" {
+		cvm.Say(state, "Not to use temporary keypair", "")
+		return multistep.ActionContinue
+	}
+
+	cvm.Say(state, s.Comm.SSHTemporaryKeyPairName, "Trying to create a new keypair")
+
+	keyPair, err := CreateKeyPair(ctx, client, s.Comm.SSHTemporaryKeyPairName)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to create keypair")
+	}
+
+	// set keyId to delete when Cleanup
+	s.keyId = *keyPair.KeyId
+	state.Put("temporary_key_pair_id", s.keyId)
+	cvm.Message(state, s.keyId, "Keypair created")
+
+	s.Comm.SSHKeyPairName = s.keyId
+	s.Comm.SSHPrivateKey = []byte(*keyPair.PrivateKey)
+	cvm.LogSecrets(*keyPair.PrivateKey)
+
+	if s.Debug {
+		cvm.Message(state, fmt.Sprintf("Saving temporary key to %s for debug purposes", s.DebugKeyPath), "")
+		f, err := os.Create(s.DebugKeyPath)
+		if err != nil {
+			return cvm.Halt(state, err, "Failed to saving debug key file")
+		}
+		defer f.Close()
+		if _, err := f.Write([]byte(*keyPair.PrivateKey)); err != nil {
+			return cvm.Halt(state, err, "Failed to writing debug key file")
+		}
+		if runtime.GOOS != "windows" {
+			if err := f.Chmod(0600); err != nil {
+				return cvm.Halt(state, err, "Failed to chmod debug key file")
+			}
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepConfigKeyPair) Cleanup(state multistep.StateBag) {
+	if s.keyId == "" {
+		return
+	}
+
+	ctx := context.TODO()
+	client := state.Get("common_client").(cvm.APISender)
+
+	cvm.SayClean(state, "keypair")
+
+	// the keypair is detached by stepStopInstance, or unbound when the
+	// instance is terminated
+	if err := DeleteKeyPair(ctx, client, s.keyId); err != nil {
+		cvm.Leak(state, err, "keypair", s.keyId)
+	}
+
+	if s.Debug {
+		if err := os.Remove(s.DebugKeyPath); err != nil {
+			cvm.Leak(state, err, "debug key file", s.DebugKeyPath)
+		}
+	}
+}
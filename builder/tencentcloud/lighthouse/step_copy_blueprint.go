@@ -0,0 +1,119 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lighthouse
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// regionClientFunc returns the client sending common requests to region, it
+// is put in the state bag as "common_region_client"
+type regionClientFunc func(region string) cvm.APISender
+
+// stepCopyBlueprint copies the blueprint to the destination regions, and adds
+// the copies to "blueprints".
+type stepCopyBlueprint struct {
+	DesinationRegions []string
+	SourceRegion      string
+	// copied are the regions the blueprint is copied to
+	copied []string
+}
+
+func (s *stepCopyBlueprint) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	var regions []string
+	for _, region := range s.DesinationRegions {
+		if region != s.SourceRegion {
+			regions = append(regions, region)
+		}
+	}
+	if len(regions) == 0 {
+		return multistep.ActionContinue
+	}
+
+	client := state.Get("common_client").(cvm.APISender)
+	config := state.Get("config").(*Config)
+	regionClient := state.Get("common_region_client").(regionClientFunc)
+	blueprint := state.Get("blueprint").(*Blueprint)
+	blueprints := state.Get("blueprints").(map[string]string)
+
+	cvm.Say(state, strings.Join(regions, ","), "Trying to copy blueprint to")
+
+	if err := SyncBlueprint(ctx, client, *blueprint.BlueprintId, regions); err != nil {
+		return cvm.Halt(state, err, "Failed to copy blueprint")
+	}
+	s.copied = regions
+
+	tags, err := cvm.ResourceTags(state, config.BlueprintTags)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to get tags")
+	}
+
+	cvm.Message(state, "Waiting for blueprint ready", "")
+	for _, region := range regions {
+		rc := regionClient(region)
+
+		// the copies have the same name as the blueprint
+		copied, err := GetBlueprintByName(ctx, rc, *blueprint.BlueprintName)
+		if err != nil {
+			return cvm.Halt(state, err, "Failed to get blueprint")
+		}
+		if copied == nil {
+			return cvm.Halt(state, fmt.Errorf("blueprint %s not found in %s", *blueprint.BlueprintName, region),
+				"Failed to copy blueprint")
+		}
+		if _, err := WaitForBlueprint(ctx, rc, *copied.BlueprintId, "NORMAL", 3600); err != nil {
+			return cvm.Halt(state, err, "Failed to wait for blueprint ready")
+		}
+		// tagging for cost allocation doesn't fail the build
+		err = cvm.TagServiceResources(ctx, state, lighthouseService, region, "blueprint", []string{*copied.BlueprintId}, tags)
+		if err != nil {
+			cvm.Message(state, fmt.Sprintf("%s, skip tagging blueprint", err), "Failed to tag blueprint")
+		}
+
+		blueprints[region] = *copied.BlueprintId
+	}
+
+	cvm.Message(state, strings.Join(regions, ","), "Blueprint copied to")
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCopyBlueprint) Cleanup(state multistep.StateBag) {
+	if len(s.copied) == 0 {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	ctx := context.TODO()
+	regionClient := state.Get("common_region_client").(regionClientFunc)
+	blueprint := state.Get("blueprint").(*Blueprint)
+
+	cvm.SayClean(state, "copied blueprints")
+
+	for _, region := range s.copied {
+		rc := regionClient(region)
+		copied, err := GetBlueprintByName(ctx, rc, *blueprint.BlueprintName)
+		if err == nil && copied != nil {
+			// the copy may be still in progress
+			_, err = WaitForBlueprint(ctx, rc, *copied.BlueprintId, "NORMAL", 3600)
+			if err == nil {
+				err = DeleteBlueprint(ctx, rc, *copied.BlueprintId)
+			}
+		}
+		if err != nil {
+			cvm.Leak(state, err, "blueprint", fmt.Sprintf("%s in %s", *blueprint.BlueprintName, region))
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lighthouse
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// stepCreateBlueprint creates the custom blueprint from the stopped
+// instance.
+//
+// Produces:
+//
+//	blueprints map[string]string - The ids of the blueprints keyed by region
+type stepCreateBlueprint struct {
+	BlueprintName        string
+	BlueprintDescription string
+	blueprintId          string
+}
+
+func (s *stepCreateBlueprint) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("common_client").(cvm.APISender)
+	config := state.Get("config").(*Config)
+	instance := state.Get("instance").(*Instance)
+
+	cvm.Say(state, s.BlueprintName, "Trying to create a new blueprint")
+
+	blueprintId, err := CreateBlueprint(ctx, client, *instance.InstanceId, s.BlueprintName, s.BlueprintDescription)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to create blueprint")
+	}
+	s.blueprintId = blueprintId
+
+	cvm.Message(state, "Waiting for blueprint ready", "")
+	blueprint, err := WaitForBlueprint(ctx, client, blueprintId, "NORMAL", 3600)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to wait for blueprint ready")
+	}
+
+	tags, err := cvm.ResourceTags(state, config.BlueprintTags)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to get tags")
+	}
+	// tagging for cost allocation doesn't fail the build
+	err = cvm.TagServiceResources(ctx, state, lighthouseService, config.Region, "blueprint", []string{blueprintId}, tags)
+	if err != nil {
+		cvm.Message(state, fmt.Sprintf("%s, skip tagging blueprint", err), "Failed to tag blueprint")
+	}
+
+	state.Put("blueprint", blueprint)
+	cvm.Message(state, blueprintId, "Blueprint created")
+
+	blueprints := make(map[string]string)
+	blueprints[config.Region] = blueprintId
+	state.Put("blueprints", blueprints)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateBlueprint) Cleanup(state multistep.StateBag) {
+	if s.blueprintId == "" {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	ctx := context.TODO()
+	client := state.Get("common_client").(cvm.APISender)
+
+	cvm.SayClean(state, "blueprint")
+
+	// the blueprint may be still creating
+	_, err := WaitForBlueprint(ctx, client, s.blueprintId, "NORMAL", 3600)
+	if err == nil {
+		err = DeleteBlueprint(ctx, client, s.blueprintId)
+	}
+	if err != nil {
+		cvm.Leak(state, err, "blueprint", s.blueprintId)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lighthouse
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// stepPreValidate checks no custom blueprint is named BlueprintName.
+type stepPreValidate struct {
+	BlueprintName string
+}
+
+func (s *stepPreValidate) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("common_client").(cvm.APISender)
+
+	cvm.Say(state, s.BlueprintName, "Trying to check blueprint name")
+
+	blueprint, err := GetBlueprintByName(ctx, client, s.BlueprintName)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to get blueprints info")
+	}
+	if blueprint != nil {
+		return cvm.Halt(state, fmt.Errorf("blueprint(%s) named %s exists", *blueprint.BlueprintId, s.BlueprintName), "")
+	}
+
+	cvm.Message(state, "useable", "Blueprint name")
+
+	return multistep.ActionContinue
+}
+
+func (s *stepPreValidate) Cleanup(multistep.StateBag) {}
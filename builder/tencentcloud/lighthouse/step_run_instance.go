@@ -0,0 +1,113 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lighthouse
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// stepRunInstance creates the instance from the source blueprint, and waits
+// for it to be running.
+type stepRunInstance struct {
+	BundleId     string
+	BlueprintId  string
+	InstanceName string
+	Zone         string
+	Tags         map[string]string
+	instanceId   string
+}
+
+func (s *stepRunInstance) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("common_client").(cvm.APISender)
+
+	keyId := ""
+	if id, ok := state.GetOk("temporary_key_pair_id"); ok {
+		keyId = id.(string)
+	}
+
+	tags, err := cvm.ResourceTags(state, s.Tags)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to get tags")
+	}
+
+	cvm.Say(state, s.BlueprintId, "Trying to create a new instance from blueprint")
+
+	instanceId, err := CreateInstance(ctx, client, &InstanceOptions{
+		BundleId:     s.BundleId,
+		BlueprintId:  s.BlueprintId,
+		InstanceName: s.InstanceName,
+		Zone:         s.Zone,
+		KeyId:        keyId,
+		Tags:         tags,
+	})
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to create instance")
+	}
+	s.instanceId = instanceId
+
+	cvm.Message(state, "Waiting for instance ready", "")
+	instance, err := WaitForInstance(ctx, client, instanceId, "RUNNING", 1800)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to wait for instance ready")
+	}
+
+	state.Put("instance", instance)
+	// instance_id is the generic term used so that users can have access to the
+	// instance id inside of the provisioners, used in step_provision.
+	state.Put("instance_id", instanceId)
+	cvm.Message(state, instanceId, "Instance created")
+
+	return multistep.ActionContinue
+}
+
+func (s *stepRunInstance) Cleanup(state multistep.StateBag) {
+	if s.instanceId == "" {
+		return
+	}
+
+	ctx := context.TODO()
+	client := state.Get("common_client").(cvm.APISender)
+
+	cvm.SayClean(state, "instance")
+
+	instance, err := DescribeInstance(ctx, client, s.instanceId)
+	if err != nil {
+		cvm.Leak(state, err, "instance", s.instanceId)
+		return
+	}
+	if instance == nil {
+		return
+	}
+
+	// the monthly instance is isolated before it can be terminated
+	if *instance.InstanceState != "SHUTDOWN" {
+		err = instanceAction(ctx, client, "IsolateInstances", s.instanceId)
+		if err == nil {
+			_, err = WaitForInstance(ctx, client, s.instanceId, "SHUTDOWN", 1800)
+		}
+	}
+	if err == nil {
+		err = instanceAction(ctx, client, "TerminateInstances", s.instanceId)
+	}
+	if err == nil {
+		_, err = WaitForInstance(ctx, client, s.instanceId, "", 1800)
+	}
+	if err != nil {
+		cvm.Leak(state, err, "instance", s.instanceId)
+	}
+}
+
+// instanceHost returns the public ip of the instance for communicator
+func instanceHost(state multistep.StateBag) (string, error) {
+	instance := state.Get("instance").(*Instance)
+	if len(instance.PublicAddresses) > 0 && instance.PublicAddresses[0] != nil {
+		return *instance.PublicAddresses[0], nil
+	}
+	return "", fmt.Errorf("instance(%s) has no public ip", *instance.InstanceId)
+}
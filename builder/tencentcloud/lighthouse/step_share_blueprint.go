@@ -0,0 +1,61 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lighthouse
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// stepShareBlueprint shares the blueprint to ShareAccounts.
+type stepShareBlueprint struct {
+	ShareAccounts []string
+	shared        bool
+}
+
+func (s *stepShareBlueprint) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if len(s.ShareAccounts) == 0 {
+		return multistep.ActionContinue
+	}
+
+	client := state.Get("common_client").(cvm.APISender)
+	blueprint := state.Get("blueprint").(*Blueprint)
+
+	cvm.Say(state, strings.Join(s.ShareAccounts, ","), "Trying to share blueprint to")
+
+	if err := ShareBlueprint(ctx, client, *blueprint.BlueprintId, s.ShareAccounts); err != nil {
+		return cvm.Halt(state, err, "Failed to share blueprint")
+	}
+	s.shared = true
+
+	cvm.Message(state, "Blueprint shared", "")
+
+	return multistep.ActionContinue
+}
+
+func (s *stepShareBlueprint) Cleanup(state multistep.StateBag) {
+	if !s.shared {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		return
+	}
+
+	ctx := context.TODO()
+	client := state.Get("common_client").(cvm.APISender)
+	blueprint := state.Get("blueprint").(*Blueprint)
+
+	cvm.SayClean(state, "blueprint share")
+
+	if err := CancelShareBlueprint(ctx, client, *blueprint.BlueprintId, s.ShareAccounts); err != nil {
+		cvm.Leak(state, err, "blueprint share", *blueprint.BlueprintId)
+	}
+}
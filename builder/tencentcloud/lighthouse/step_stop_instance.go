@@ -0,0 +1,55 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package lighthouse
+
+import (
+	"context"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+// stepStopInstance stops the instance before creating blueprint, and
+// detaches the temporary keypair from it, so that the instances created from
+// the blueprint are not accessible by the keypair.
+type stepStopInstance struct{}
+
+func (s *stepStopInstance) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("common_client").(cvm.APISender)
+	instance := state.Get("instance").(*Instance)
+
+	current, err := DescribeInstance(ctx, client, *instance.InstanceId)
+	if err != nil {
+		return cvm.Halt(state, err, "Failed to get instance info")
+	}
+	if current == nil || *current.InstanceState != "STOPPED" {
+		cvm.Say(state, *instance.InstanceName, "Trying to stop instance")
+
+		if err := instanceAction(ctx, client, "StopInstances", *instance.InstanceId); err != nil {
+			return cvm.Halt(state, err, "Failed to stop instance")
+		}
+		cvm.Message(state, "Waiting for instance stop", "")
+		if _, err := WaitForInstance(ctx, client, *instance.InstanceId, "STOPPED", 1800); err != nil {
+			return cvm.Halt(state, err, "Failed to wait for instance to be stopped")
+		}
+	}
+
+	if keyId, ok := state.GetOk("temporary_key_pair_id"); ok {
+		cvm.Say(state, keyId.(string), "Trying to detach keypair")
+
+		if err := DetachKeyPair(ctx, client, keyId.(string), *instance.InstanceId); err != nil {
+			return cvm.Halt(state, err, "Fail to detach keypair from instance")
+		}
+		cvm.Message(state, "Waiting for keypair detached", "")
+		if _, err := WaitForInstance(ctx, client, *instance.InstanceId, "STOPPED", 1800); err != nil {
+			return cvm.Halt(state, err, "Failed to wait for keypair detached")
+		}
+		cvm.Message(state, "Keypair detached", "")
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepStopInstance) Cleanup(multistep.StateBag) {}
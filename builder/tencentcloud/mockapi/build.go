@@ -0,0 +1,112 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package mockapi
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// BuildConfig returns the config of a build with the server: the credential,
+// the region ap-guangzhou, the endpoints of all services pointing to the
+// server and the api_retry without delay. settings are merged into it.
+func (s *Server) BuildConfig(settings map[string]interface{}) map[string]interface{} {
+	raw := map[string]interface{}{
+		"secret_id":    s.SecretId,
+		"secret_key":   s.SecretKey,
+		"region":       "ap-guangzhou",
+		"cvm_endpoint": s.URL,
+		"vpc_endpoint": s.URL,
+		"endpoints": map[string]string{
+			"cbs":        s.URL,
+			"lighthouse": s.URL,
+			"tag":        s.URL,
+			"sts":        s.URL,
+		},
+		"api_retry": map[string]interface{}{
+			"base_delay": "1ms",
+			"max_delay":  "10ms",
+			"rate_limit": -1,
+		},
+	}
+	for k, v := range settings {
+		raw[k] = v
+	}
+	return raw
+}
+
+// Build prepares the builder with BuildConfig of settings and runs it,
+// a nil hook is a packersdk.MockHook.
+func (s *Server) Build(t testing.TB, b packersdk.Builder, hook packersdk.Hook, settings map[string]interface{}) (packersdk.Artifact, error) {
+	t.Helper()
+	if _, _, err := b.Prepare(s.BuildConfig(settings)); err != nil {
+		t.Fatalf("prepare: %s", err)
+	}
+	if hook == nil {
+		hook = &packersdk.MockHook{}
+	}
+	return b.Run(context.Background(), &packersdk.MockUi{}, hook)
+}
+
+// AssertNoLeaks checks no resource is left in region, except the kinds of
+// resources kept by the build, such as image.
+func (s *Server) AssertNoLeaks(t testing.TB, region string, kept ...string) {
+	t.Helper()
+	for resource, n := range s.Resources(region) {
+		keep := false
+		for _, k := range kept {
+			keep = keep || resource == k
+		}
+		if !keep && n != 0 {
+			t.Errorf("%d %s leaked in %s", n, resource, region)
+		}
+	}
+}
+
+// ActionsOf returns the requests of the actions in order, joined by comma
+func (s *Server) ActionsOf(actions ...string) string {
+	var calls []string
+	for _, action := range s.Actions() {
+		for _, a := range actions {
+			if action == a {
+				calls = append(calls, action)
+			}
+		}
+	}
+	return strings.Join(calls, ",")
+}
+
+// PrepareError is a setting making Prepare fail with the error Err, a nil
+// Value removes the setting.
+type PrepareError struct {
+	Key   string
+	Value interface{}
+	Err   string
+}
+
+// CheckPrepareErrors prepares a builder of newBuilder for each case, with
+// the setting of the case applied to a copy of config.
+func CheckPrepareErrors(t *testing.T, newBuilder func() packersdk.Builder, config map[string]interface{}, cases map[string]PrepareError) {
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			raw := make(map[string]interface{}, len(config))
+			for k, v := range config {
+				raw[k] = v
+			}
+			if tc.Value == nil {
+				delete(raw, tc.Key)
+			} else {
+				raw[tc.Key] = tc.Value
+			}
+
+			_, _, err := newBuilder().Prepare(raw)
+			if err == nil || !strings.Contains(err.Error(), tc.Err) {
+				t.Fatalf("expected error %q, got %v", tc.Err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,498 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package mockapi
+
+import (
+	"sort"
+)
+
+func init() {
+	register("cbs", map[string]handler{
+		"DescribeDiskConfigQuota":  describeDiskConfigQuota,
+		"CreateDisks":              createDisks,
+		"DescribeDisks":            describeDisks,
+		"AttachDisks":              attachDisks,
+		"DetachDisks":              detachDisks,
+		"TerminateDisks":           terminateDisks,
+		"CreateSnapshot":           createSnapshot,
+		"DescribeSnapshots":        describeSnapshots,
+		"DeleteSnapshots":          deleteSnapshots,
+		"CopySnapshotCrossRegions": copySnapshotCrossRegions,
+	})
+}
+
+// Disk is a cloud disk
+type Disk struct {
+	DiskId     string
+	DiskName   string
+	DiskType   string
+	DiskUsage  string
+	DiskSize   int64
+	DiskState  string
+	Attached   bool
+	InstanceId string
+	Zone       string
+	SnapshotId string
+	// Tags are the tags of CreateDisks
+	Tags map[string]string
+
+	t *transition
+}
+
+func (d *Disk) view() map[string]interface{} {
+	return map[string]interface{}{
+		"DiskId":     d.DiskId,
+		"DiskName":   d.DiskName,
+		"DiskType":   d.DiskType,
+		"DiskUsage":  d.DiskUsage,
+		"DiskSize":   d.DiskSize,
+		"DiskState":  d.DiskState,
+		"Attached":   d.Attached,
+		"InstanceId": d.InstanceId,
+		"Placement":  map[string]interface{}{"Zone": d.Zone},
+	}
+}
+
+// Snapshot is a snapshot of cloud disk
+type Snapshot struct {
+	SnapshotId    string
+	SnapshotName  string
+	SnapshotState string
+	DiskId        string
+	DiskUsage     string
+	DiskSize      int64
+
+	t *transition
+}
+
+func (sn *Snapshot) view() map[string]interface{} {
+	return map[string]interface{}{
+		"SnapshotId":    sn.SnapshotId,
+		"SnapshotName":  sn.SnapshotName,
+		"SnapshotState": sn.SnapshotState,
+		"DiskId":        sn.DiskId,
+		"DiskUsage":     sn.DiskUsage,
+		"DiskSize":      sn.DiskSize,
+	}
+}
+
+// diskTypes are the cloud disk types sold in every zone
+var diskTypes = []string{"CLOUD_BASIC", "CLOUD_PREMIUM", "CLOUD_SSD", "CLOUD_HSSD", "CLOUD_BSSD"}
+
+func describeDiskConfigQuota(s *Server, r *region, body []byte) (interface{}, error) {
+	var req struct {
+		Zones     []string
+		DiskUsage string
+	}
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	zones := req.Zones
+	if len(zones) == 0 {
+		zones = r.zones()
+	}
+
+	var configs []map[string]interface{}
+	for _, zone := range zones {
+		if !r.validZone(zone) {
+			return nil, errorf("InvalidParameterValue.ZoneNotExist", "zone %s not found", zone)
+		}
+		for _, diskType := range diskTypes {
+			configs = append(configs, map[string]interface{}{
+				"Zone":           zone,
+				"DiskType":       diskType,
+				"DiskUsage":      req.DiskUsage,
+				"DiskChargeType": "POSTPAID_BY_HOUR",
+				"Available":      true,
+				"MinDiskSize":    10,
+				"MaxDiskSize":    32000,
+				"StepSize":       10,
+			})
+		}
+	}
+	return map[string]interface{}{"DiskConfigSet": configs}, nil
+}
+
+// snapshotSize returns the disk size of snapshot, which may be a snapshot
+// of image
+func (r *region) snapshotSize(id string) (int64, bool) {
+	if sn, ok := r.snapshots[id]; ok {
+		return sn.DiskSize, true
+	}
+	for _, img := range r.images {
+		for _, sn := range img.SnapshotSet {
+			if *sn.SnapshotId == id {
+				return *sn.DiskSize, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func createDisks(s *Server, r *region, body []byte) (interface{}, error) {
+	var req struct {
+		Placement struct {
+			Zone string
+		}
+		DiskChargeType string
+		DiskType       string
+		DiskName       string
+		DiskSize       int64
+		DiskCount      int
+		SnapshotId     string
+		ClientToken    string
+		Tags           []struct {
+			Key   string
+			Value string
+		}
+	}
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if ids, ok := r.clientTokens[req.ClientToken]; ok && req.ClientToken != "" {
+		return map[string]interface{}{"DiskIdSet": ids}, nil
+	}
+	if !r.validZone(req.Placement.Zone) {
+		return nil, errorf("InvalidParameterValue.ZoneNotExist", "zone %s not found", req.Placement.Zone)
+	}
+	if req.DiskType == "" {
+		return nil, errorf("MissingParameter", "DiskType is required")
+	}
+	if req.SnapshotId != "" {
+		size, ok := r.snapshotSize(req.SnapshotId)
+		if !ok {
+			return nil, errorf("InvalidSnapshot.NotFound", "snapshot %s not found", req.SnapshotId)
+		}
+		if req.DiskSize == 0 {
+			req.DiskSize = size
+		}
+		if req.DiskSize < size {
+			return nil, errorf("InvalidParameterValue.DiskSizeNotMatch", "disk size %d is smaller than snapshot %d",
+				req.DiskSize, size)
+		}
+	}
+	if req.DiskSize < 10 {
+		return nil, errorf("InvalidParameterValue.DiskSizeNotMatch", "disk size %d is invalid", req.DiskSize)
+	}
+	if req.DiskCount == 0 {
+		req.DiskCount = 1
+	}
+
+	var ids []*string
+	for i := 0; i < req.DiskCount; i++ {
+		d := &Disk{
+			DiskId:     s.id("disk"),
+			DiskName:   req.DiskName,
+			DiskType:   req.DiskType,
+			DiskUsage:  "DATA_DISK",
+			DiskSize:   req.DiskSize,
+			DiskState:  "UNATTACHED",
+			Zone:       req.Placement.Zone,
+			SnapshotId: req.SnapshotId,
+			Tags:       make(map[string]string),
+		}
+		for _, tag := range req.Tags {
+			d.Tags[tag.Key] = tag.Value
+		}
+		r.disks[d.DiskId] = d
+		id := d.DiskId
+		ids = append(ids, &id)
+	}
+	if req.ClientToken != "" {
+		r.clientTokens[req.ClientToken] = ids
+	}
+	return map[string]interface{}{"DiskIdSet": ids}, nil
+}
+
+func (r *region) settleDisks() {
+	for id, d := range r.disks {
+		if d.t == nil || !d.t.due() {
+			continue
+		}
+		if d.t.next == "" {
+			delete(r.disks, id)
+			continue
+		}
+		d.DiskState = d.t.next
+		d.t = nil
+	}
+}
+
+func (r *region) getDisks(ids []string) ([]*Disk, error) {
+	var disks []*Disk
+	for _, id := range ids {
+		d, ok := r.disks[id]
+		if !ok {
+			return nil, errorf("InvalidDisk.NotFound", "disk %s not found", id)
+		}
+		disks = append(disks, d)
+	}
+	return disks, nil
+}
+
+func describeDisks(s *Server, r *region, body []byte) (interface{}, error) {
+	var req struct {
+		DiskIds []string
+		Offset  *uint64
+		Limit   *uint64
+	}
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	r.settleDisks()
+
+	var disks []*Disk
+	if len(req.DiskIds) > 0 {
+		for _, id := range req.DiskIds {
+			if d, ok := r.disks[id]; ok {
+				disks = append(disks, d)
+			}
+		}
+	} else {
+		for _, d := range r.disks {
+			disks = append(disks, d)
+		}
+		sort.Slice(disks, func(i, j int) bool { return disks[i].DiskId < disks[j].DiskId })
+	}
+
+	start, end := page(len(disks), req.Offset, req.Limit)
+	set := make([]map[string]interface{}, 0, end-start)
+	for _, d := range disks[start:end] {
+		set = append(set, d.view())
+	}
+	return map[string]interface{}{"TotalCount": len(disks), "DiskSet": set}, nil
+}
+
+func attachDisks(s *Server, r *region, body []byte) (interface{}, error) {
+	var req struct {
+		DiskIds    []string
+		InstanceId string
+	}
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	ins, ok := r.instances[req.InstanceId]
+	if !ok {
+		return nil, errorf("InvalidInstanceId.NotFound", "instance %s not found", req.InstanceId)
+	}
+	if err := ins.checkState("RUNNING", "STOPPED"); err != nil {
+		return nil, err
+	}
+	disks, err := r.getDisks(req.DiskIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range disks {
+		if d.DiskState != "UNATTACHED" {
+			return nil, errorf("InvalidDisk.NotSupported", "disk %s is %s", d.DiskId, d.DiskState)
+		}
+		if d.Zone != *ins.Placement.Zone {
+			return nil, errorf("InvalidParameter.DiskAndInstanceNotInSameZone",
+				"disk %s is not in zone of instance %s", d.DiskId, req.InstanceId)
+		}
+	}
+	for _, d := range disks {
+		d.DiskState = "ATTACHING"
+		d.Attached = true
+		d.InstanceId = req.InstanceId
+		d.t = &transition{next: "ATTACHED", polls: s.Polls}
+	}
+	return map[string]interface{}{}, nil
+}
+
+func detachDisks(s *Server, r *region, body []byte) (interface{}, error) {
+	var req struct {
+		DiskIds []string
+	}
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	disks, err := r.getDisks(req.DiskIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range disks {
+		if d.DiskState != "ATTACHED" {
+			return nil, errorf("InvalidDisk.NotSupported", "disk %s is %s", d.DiskId, d.DiskState)
+		}
+	}
+	for _, d := range disks {
+		d.DiskState = "DETACHING"
+		d.Attached = false
+		d.InstanceId = ""
+		d.t = &transition{next: "UNATTACHED", polls: s.Polls}
+	}
+	return map[string]interface{}{}, nil
+}
+
+func terminateDisks(s *Server, r *region, body []byte) (interface{}, error) {
+	var req struct {
+		DiskIds []string
+	}
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	disks, err := r.getDisks(req.DiskIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range disks {
+		if d.DiskState != "UNATTACHED" {
+			return nil, errorf("InvalidDisk.NotSupported", "disk %s is %s", d.DiskId, d.DiskState)
+		}
+	}
+	for _, d := range disks {
+		delete(r.disks, d.DiskId)
+	}
+	return map[string]interface{}{}, nil
+}
+
+func createSnapshot(s *Server, r *region, body []byte) (interface{}, error) {
+	var req struct {
+		DiskId       string
+		SnapshotName string
+	}
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	d, ok := r.disks[req.DiskId]
+	if !ok {
+		return nil, errorf("InvalidDisk.NotFound", "disk %s not found", req.DiskId)
+	}
+	if d.t != nil {
+		return nil, errorf("InvalidDisk.Busy", "disk %s is %s", d.DiskId, d.DiskState)
+	}
+
+	sn := &Snapshot{
+		SnapshotId:    s.id("snap"),
+		SnapshotName:  req.SnapshotName,
+		SnapshotState: "CREATING",
+		DiskId:        d.DiskId,
+		DiskUsage:     d.DiskUsage,
+		DiskSize:      d.DiskSize,
+		t:             &transition{next: "NORMAL", polls: s.Polls},
+	}
+	r.snapshots[sn.SnapshotId] = sn
+	return map[string]interface{}{"SnapshotId": sn.SnapshotId}, nil
+}
+
+func (r *region) settleSnapshots() {
+	for id, sn := range r.snapshots {
+		if sn.t == nil || !sn.t.due() {
+			continue
+		}
+		if sn.t.next == "" {
+			delete(r.snapshots, id)
+			continue
+		}
+		sn.SnapshotState = sn.t.next
+		sn.t = nil
+	}
+}
+
+func describeSnapshots(s *Server, r *region, body []byte) (interface{}, error) {
+	var req struct {
+		SnapshotIds []string
+		Offset      *uint64
+		Limit       *uint64
+	}
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	r.settleSnapshots()
+
+	var snapshots []*Snapshot
+	if len(req.SnapshotIds) > 0 {
+		for _, id := range req.SnapshotIds {
+			if sn, ok := r.snapshots[id]; ok {
+				snapshots = append(snapshots, sn)
+			}
+		}
+	} else {
+		for _, sn := range r.snapshots {
+			snapshots = append(snapshots, sn)
+		}
+		sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].SnapshotId < snapshots[j].SnapshotId })
+	}
+
+	start, end := page(len(snapshots), req.Offset, req.Limit)
+	set := make([]map[string]interface{}, 0, end-start)
+	for _, sn := range snapshots[start:end] {
+		set = append(set, sn.view())
+	}
+	return map[string]interface{}{"TotalCount": len(snapshots), "SnapshotSet": set}, nil
+}
+
+func deleteSnapshots(s *Server, r *region, body []byte) (interface{}, error) {
+	var req struct {
+		SnapshotIds []string
+	}
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	for _, id := range req.SnapshotIds {
+		if _, ok := r.snapshots[id]; !ok {
+			return nil, errorf("InvalidSnapshot.NotFound", "snapshot %s not found", id)
+		}
+		for _, img := range r.images {
+			for _, sn := range img.SnapshotSet {
+				if *sn.SnapshotId == id {
+					return nil, errorf("InvalidSnapshot.HasBindedImage", "snapshot %s is used by image %s", id, *img.ImageId)
+				}
+			}
+		}
+	}
+	for _, id := range req.SnapshotIds {
+		delete(r.snapshots, id)
+	}
+	return map[string]interface{}{}, nil
+}
+
+func copySnapshotCrossRegions(s *Server, r *region, body []byte) (interface{}, error) {
+	var req struct {
+		SnapshotId         string
+		DestinationRegions []string
+		SnapshotName       string
+	}
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	src, ok := r.snapshots[req.SnapshotId]
+	if !ok {
+		return nil, errorf("InvalidSnapshot.NotFound", "snapshot %s not found", req.SnapshotId)
+	}
+	if src.SnapshotState != "NORMAL" {
+		return nil, errorf("InvalidSnapshot.NotSupported", "snapshot %s is %s", src.SnapshotId, src.SnapshotState)
+	}
+	for _, dest := range req.DestinationRegions {
+		if dest == r.name {
+			return nil, errorf("InvalidParameterValue", "cannot copy snapshot to source region %s", dest)
+		}
+	}
+
+	name := req.SnapshotName
+	if name == "" {
+		name = src.SnapshotName
+	}
+	var results []map[string]interface{}
+	for _, dest := range req.DestinationRegions {
+		copied := &Snapshot{
+			SnapshotId:    s.id("snap"),
+			SnapshotName:  name,
+			SnapshotState: "COPYING_FROM_REMOTE",
+			DiskId:        src.DiskId,
+			DiskUsage:     src.DiskUsage,
+			DiskSize:      src.DiskSize,
+			t:             &transition{next: "NORMAL", polls: s.Polls},
+		}
+		s.region(dest).snapshots[copied.SnapshotId] = copied
+		results = append(results, map[string]interface{}{
+			"SnapshotId":        copied.SnapshotId,
+			"DestinationRegion": dest,
+			"Code":              "Success",
+			"Message":           "",
+		})
+	}
+	return map[string]interface{}{"SnapshotCopyResultSet": results}, nil
+}
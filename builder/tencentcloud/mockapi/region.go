@@ -0,0 +1,271 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package mockapi
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
+)
+
+// transition is an asynchronous state change, the transitional state is
+// seen by a number of describes before it settles to next, an empty next
+// means the resource is gone.
+type transition struct {
+	next  string
+	polls int
+}
+
+// due reports whether the transition settles on this describe
+func (t *transition) due() bool {
+	if t.polls > 0 {
+		t.polls--
+		return false
+	}
+	return true
+}
+
+type instance struct {
+	*cvm.Instance
+	t *transition
+}
+
+// terminating reports whether the instance is going away, its network
+// resources are released already
+func (ins *instance) terminating() bool {
+	return ins.t != nil && ins.t.next == ""
+}
+
+type image struct {
+	*cvm.Image
+	t *transition
+}
+
+type address struct {
+	*vpc.Address
+	t *transition
+}
+
+// region is the resources in a region
+type region struct {
+	name           string
+	instances      map[string]*instance
+	images         map[string]*image
+	keyPairs       map[string]*cvm.KeyPair
+	shares         map[string]map[string]bool
+	vpcs           map[string]*vpc.Vpc
+	subnets        map[string]*vpc.Subnet
+	securityGroups map[string]*vpc.SecurityGroup
+	addresses      map[string]*address
+	disks          map[string]*Disk
+	snapshots      map[string]*Snapshot
+	clientTokens   map[string][]*string
+	// the lighthouse resources
+	lhInstances map[string]*LighthouseInstance
+	lhKeyPairs  map[string]*lighthouseKeyPair
+	blueprints  map[string]*Blueprint
+}
+
+func newRegion(name string) *region {
+	return &region{
+		name:           name,
+		instances:      make(map[string]*instance),
+		images:         make(map[string]*image),
+		keyPairs:       make(map[string]*cvm.KeyPair),
+		shares:         make(map[string]map[string]bool),
+		vpcs:           make(map[string]*vpc.Vpc),
+		subnets:        make(map[string]*vpc.Subnet),
+		securityGroups: make(map[string]*vpc.SecurityGroup),
+		addresses:      make(map[string]*address),
+		disks:          make(map[string]*Disk),
+		snapshots:      make(map[string]*Snapshot),
+		clientTokens:   make(map[string][]*string),
+		lhInstances:    make(map[string]*LighthouseInstance),
+		lhKeyPairs:     make(map[string]*lighthouseKeyPair),
+		blueprints:     make(map[string]*Blueprint),
+	}
+}
+
+// zones returns the zones of region, which are numbered from 1 to 3
+func (r *region) zones() []string {
+	return []string{r.name + "-1", r.name + "-2", r.name + "-3"}
+}
+
+func (r *region) validZone(zone string) bool {
+	for _, z := range r.zones() {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
+
+// transit starts the asynchronous state change of instance
+func (s *Server) transitInstance(ins *instance, state, next string) {
+	ins.InstanceState = common.StringPtr(state)
+	ins.LatestOperationState = common.StringPtr("OPERATING")
+	ins.t = &transition{next: next, polls: s.Polls}
+}
+
+func (r *region) settleInstances() {
+	for id, ins := range r.instances {
+		if ins.t == nil || !ins.t.due() {
+			continue
+		}
+		if ins.t.next == "" {
+			delete(r.instances, id)
+			continue
+		}
+		ins.InstanceState = common.StringPtr(ins.t.next)
+		ins.LatestOperationState = common.StringPtr("SUCCESS")
+		ins.t = nil
+	}
+}
+
+func (r *region) settleImages() {
+	for id, img := range r.images {
+		if img.t == nil || !img.t.due() {
+			continue
+		}
+		if img.t.next == "" {
+			delete(r.images, id)
+			continue
+		}
+		img.ImageState = common.StringPtr(img.t.next)
+		img.t = nil
+	}
+}
+
+func (r *region) settleAddresses() {
+	for id, addr := range r.addresses {
+		if addr.t == nil || !addr.t.due() {
+			continue
+		}
+		if addr.t.next == "" {
+			delete(r.addresses, id)
+			continue
+		}
+		addr.AddressStatus = common.StringPtr(addr.t.next)
+		addr.t = nil
+	}
+}
+
+// AddImage adds an image in NORMAL state to region, such as the source
+// image of builds
+func (s *Server) AddImage(regionName, id, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.region(regionName).images[id] = &image{Image: &cvm.Image{
+		ImageId:    common.StringPtr(id),
+		ImageName:  common.StringPtr(name),
+		ImageState: common.StringPtr("NORMAL"),
+		ImageType:  common.StringPtr("PUBLIC_IMAGE"),
+		OsName:     common.StringPtr(name),
+		SnapshotSet: []*cvm.Snapshot{
+			{
+				SnapshotId: common.StringPtr(s.id("snap")),
+				DiskUsage:  common.StringPtr("SYSTEM_DISK"),
+				DiskSize:   common.Int64Ptr(50),
+			},
+		},
+	}}
+}
+
+// AddInstance adds a RUNNING instance to zone of region, such as the cvm
+// packer runs on
+func (s *Server) AddInstance(regionName, zone, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.region(regionName).instances[id] = &instance{Instance: &cvm.Instance{
+		InstanceId:           common.StringPtr(id),
+		InstanceName:         common.StringPtr(id),
+		InstanceState:        common.StringPtr("RUNNING"),
+		InstanceType:         common.StringPtr("S5.MEDIUM2"),
+		LatestOperationState: common.StringPtr("SUCCESS"),
+		Placement:            &cvm.Placement{Zone: common.StringPtr(zone)},
+		SystemDisk:           &cvm.SystemDisk{DiskType: common.StringPtr("CLOUD_PREMIUM"), DiskSize: common.Int64Ptr(50)},
+	}}
+}
+
+// Instances returns the instances in region
+func (s *Server) Instances(regionName string) []*cvm.Instance {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var instances []*cvm.Instance
+	for _, ins := range s.region(regionName).instances {
+		instances = append(instances, ins.Instance)
+	}
+	sort.Slice(instances, func(i, j int) bool { return *instances[i].InstanceId < *instances[j].InstanceId })
+	return instances
+}
+
+// Images returns the images in region
+func (s *Server) Images(regionName string) []*cvm.Image {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var images []*cvm.Image
+	for _, img := range s.region(regionName).images {
+		images = append(images, img.Image)
+	}
+	sort.Slice(images, func(i, j int) bool { return *images[i].ImageId < *images[j].ImageId })
+	return images
+}
+
+// Disks returns the disks in region
+func (s *Server) Disks(regionName string) []*Disk {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var disks []*Disk
+	for _, d := range s.region(regionName).disks {
+		c := *d
+		disks = append(disks, &c)
+	}
+	sort.Slice(disks, func(i, j int) bool { return disks[i].DiskId < disks[j].DiskId })
+	return disks
+}
+
+// Snapshots returns the snapshots in region
+func (s *Server) Snapshots(regionName string) []*Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var snapshots []*Snapshot
+	for _, sn := range s.region(regionName).snapshots {
+		c := *sn
+		snapshots = append(snapshots, &c)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].SnapshotId < snapshots[j].SnapshotId })
+	return snapshots
+}
+
+// Resources returns the number of resources in region by type, which are
+// instance, image, keypair, vpc, subnet, securitygroup, eip, disk, snapshot,
+// and lighthouse_instance, lighthouse_keypair and blueprint of lighthouse
+func (s *Server) Resources(regionName string) map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.region(regionName)
+	return map[string]int{
+		"instance":      len(r.instances),
+		"image":         len(r.images),
+		"keypair":       len(r.keyPairs),
+		"vpc":           len(r.vpcs),
+		"subnet":        len(r.subnets),
+		"securitygroup": len(r.securityGroups),
+		"eip":           len(r.addresses),
+		"disk":          len(r.disks),
+		"snapshot":      len(r.snapshots),
+
+		"lighthouse_instance": len(r.lhInstances),
+		"lighthouse_keypair":  len(r.lhKeyPairs),
+		"blueprint":           len(r.blueprints),
+	}
+}
+
+// ResourceName returns the six-segment name of resource used by tag service
+func (s *Server) ResourceName(service, regionName, resourcePrefix, id string) string {
+	return fmt.Sprintf("qcs::%s:%s:uin/%s:%s/%s", service, regionName, s.AccountId, resourcePrefix, id)
+}
@@ -0,0 +1,340 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package mockapi is a fake Tencent Cloud API server for the end-to-end
+// tests of the builders. It serves the cvm, vpc, cbs, lighthouse, sts and
+// tag actions called by the builders, checks the TC3 signature of every
+// request, and simulates the asynchronous state changes of instances,
+// images, eips, disks, snapshots and blueprints.
+//
+// Point the endpoints of all services to Server.URL, or use the server as
+// the http proxy with plain http endpoints.
+package mockapi
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The error codes to inject with Fault
+const (
+	CodeSoldOut   = "ResourcesSoldOut.SpecifiedInstanceType"
+	CodeQuota     = "LimitExceeded.InstanceQuota"
+	CodeRateLimit = "RequestLimitExceeded"
+
+	// LaunchFailed is not an error code, RunInstances with the fault
+	// succeeds but the instances end up in LAUNCH_FAILED state
+	LaunchFailed = "LAUNCH_FAILED"
+)
+
+// Fault makes an action fail with the error code
+type Fault struct {
+	Action string
+	Code   string
+	// Times is the number of failures, 0 means failing forever
+	Times int
+
+	hits int
+}
+
+// Call is an api request served
+type Call struct {
+	Service string
+	Action  string
+	Region  string
+	// Code is the error code returned, empty on success
+	Code string
+}
+
+// Server is the fake api server, it is safe for concurrent use
+type Server struct {
+	*httptest.Server
+
+	SecretId  string
+	SecretKey string
+	AccountId string
+
+	// Polls is the number of describes which still see the transitional
+	// state after an asynchronous operation, 0 means it settles on the
+	// first describe.
+	Polls int
+
+	mu      sync.Mutex
+	faults  []*Fault
+	calls   []Call
+	nextId  int
+	regions map[string]*region
+	// launchFailed is set while serving a RunInstances with the fault
+	launchFailed bool
+	// tags applied by TagResources, by six-segment resource name
+	tags map[string]map[string]string
+}
+
+// apiError is the error returned in api response
+type apiError struct {
+	Code    string
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("[%s] %s", e.Code, e.Message)
+}
+
+func errorf(code string, format string, args ...interface{}) *apiError {
+	return &apiError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// handler serves an action in region with the request body, the server
+// lock is held
+type handler func(s *Server, r *region, body []byte) (interface{}, error)
+
+var handlers = map[string]handler{}
+
+func register(service string, actions map[string]handler) {
+	for action, h := range actions {
+		handlers[service+"."+action] = h
+	}
+}
+
+// NewServer starts a fake api server accepting the given credential
+func NewServer(secretId, secretKey string) *Server {
+	s := newServer(secretId, secretKey)
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// NewTLSServer starts a fake api server serving https, its certificate is
+// in Server.Certificate().
+func NewTLSServer(secretId, secretKey string) *Server {
+	s := newServer(secretId, secretKey)
+	s.Server = httptest.NewTLSServer(s)
+	return s
+}
+
+func newServer(secretId, secretKey string) *Server {
+	return &Server{
+		SecretId:  secretId,
+		SecretKey: secretKey,
+		AccountId: "100000000001",
+		regions:   make(map[string]*region),
+		tags:      make(map[string]map[string]string),
+	}
+}
+
+// Inject adds a fault, faults of an action are used in order
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// Calls returns the number of requests of the action
+func (s *Server) Calls(action string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, c := range s.calls {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Actions returns the actions of the requests in order
+func (s *Server) Actions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	actions := make([]string, 0, len(s.calls))
+	for _, c := range s.calls {
+		actions = append(actions, c.Action)
+	}
+	return actions
+}
+
+// Failures returns the number of requests of the action failed with code
+func (s *Server) Failures(action, code string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, c := range s.calls {
+		if c.Action == action && c.Code == code {
+			n++
+		}
+	}
+	return n
+}
+
+// Tags returns the tags applied to the resource by TagResources
+func (s *Server) Tags(resource string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tags[resource]
+}
+
+func (s *Server) id(prefix string) string {
+	s.nextId++
+	return fmt.Sprintf("%s-%08x", prefix, s.nextId)
+}
+
+func (s *Server) region(name string) *region {
+	r, ok := s.regions[name]
+	if !ok {
+		r = newRegion(name)
+		s.regions[name] = r
+	}
+	return r
+}
+
+// fault returns the injected fault of the action
+func (s *Server) fault(action string) *Fault {
+	for _, f := range s.faults {
+		if f.Action == action && (f.Times == 0 || f.hits < f.Times) {
+			f.hits++
+			return f
+		}
+	}
+	return nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	requestId := fmt.Sprintf("%08x-mock-request", time.Now().UnixNano()&0xffffffff)
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		writeResponse(w, requestId, nil, errorf("InternalError", "%s", err))
+		return
+	}
+
+	action := req.Header.Get("X-TC-Action")
+	regionName := req.Header.Get("X-TC-Region")
+	service, err := s.verify(req, body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var resp interface{}
+	if err == nil {
+		resp, err = s.serve(service, action, regionName, body)
+	}
+	call := Call{Service: service, Action: action, Region: regionName}
+	if e, ok := err.(*apiError); ok {
+		call.Code = e.Code
+	}
+	s.calls = append(s.calls, call)
+
+	writeResponse(w, requestId, resp, err)
+}
+
+func (s *Server) serve(service, action, regionName string, body []byte) (interface{}, error) {
+	h, ok := handlers[service+"."+action]
+	if !ok {
+		return nil, errorf("InvalidAction", "action %s of %s is not supported", action, service)
+	}
+
+	f := s.fault(action)
+	if f != nil && f.Code != LaunchFailed {
+		return nil, errorf(f.Code, "injected by mock server")
+	}
+	s.launchFailed = f != nil
+	defer func() { s.launchFailed = false }()
+
+	return h(s, s.region(regionName), body)
+}
+
+// verify checks the TC3-HMAC-SHA256 signature of request, and returns the
+// service in the credential scope
+func (s *Server) verify(req *http.Request, body []byte) (string, error) {
+	auth := req.Header.Get("Authorization")
+	const algorithm = "TC3-HMAC-SHA256"
+	if !strings.HasPrefix(auth, algorithm+" ") {
+		return "", errorf("AuthFailure.SignatureFailure", "unsupported signature method")
+	}
+
+	fields := make(map[string]string)
+	for _, field := range strings.Split(strings.TrimPrefix(auth, algorithm+" "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+	scope := strings.Split(fields["Credential"], "/")
+	if len(scope) != 4 || scope[3] != "tc3_request" {
+		return "", errorf("AuthFailure.SignatureFailure", "invalid credential scope")
+	}
+	secretId, date, service := scope[0], scope[1], scope[2]
+	if secretId != s.SecretId {
+		return service, errorf("AuthFailure.SecretIdNotFound", "secret id %s not found", secretId)
+	}
+
+	timestamp := req.Header.Get("X-TC-Timestamp")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Unix(ts, 0).UTC().Format("2006-01-02") != date {
+		return service, errorf("AuthFailure.SignatureFailure", "invalid timestamp")
+	}
+
+	canonicalRequest := fmt.Sprintf("%s\n/\n\ncontent-type:%s\nhost:%s\n\n%s\n%s",
+		req.Method, req.Header.Get("Content-Type"), req.Host, fields["SignedHeaders"], sha256hex(body))
+	stringToSign := fmt.Sprintf("%s\n%s\n%s/%s/tc3_request\n%s",
+		algorithm, timestamp, date, service, sha256hex([]byte(canonicalRequest)))
+
+	key := hmacsha256([]byte("TC3"+s.SecretKey), date)
+	key = hmacsha256(key, service)
+	key = hmacsha256(key, "tc3_request")
+	signature := hex.EncodeToString(hmacsha256(key, stringToSign))
+	if !hmac.Equal([]byte(signature), []byte(fields["Signature"])) {
+		return service, errorf("AuthFailure.SignatureFailure", "the provided credentials could not be validated")
+	}
+
+	return service, nil
+}
+
+func sha256hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacsha256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+func writeResponse(w http.ResponseWriter, requestId string, resp interface{}, err error) {
+	var data map[string]interface{}
+	if err != nil {
+		e, ok := err.(*apiError)
+		if !ok {
+			e = errorf("InternalError", "%s", err)
+		}
+		data = map[string]interface{}{"Error": e}
+	} else {
+		body, _ := json.Marshal(resp)
+		data = make(map[string]interface{})
+		_ = json.Unmarshal(body, &data)
+		if data == nil {
+			data = make(map[string]interface{})
+		}
+	}
+	data["RequestId"] = requestId
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"Response": data})
+}
+
+// decode parses the request body into v
+func decode(body []byte, v interface{}) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return errorf("InvalidParameter", "%s", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package mockapi
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	cvm "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/cvm/v20170312"
+)
+
+func newClient(t *testing.T, s *Server, secretKey string) *cvm.Client {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpf := profile.NewClientProfile()
+	cpf.HttpProfile.Scheme = u.Scheme
+	cpf.HttpProfile.Endpoint = u.Host
+	client, err := cvm.NewClient(common.NewCredential(s.SecretId, secretKey), "ap-guangzhou", cpf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func errorCode(err error) string {
+	if e, ok := err.(*errors.TencentCloudSDKError); ok {
+		return e.Code
+	}
+	return ""
+}
+
+func TestServer_Signature(t *testing.T) {
+	s := NewServer("AKIDmock", "mock-secret")
+	defer s.Close()
+
+	if _, err := newClient(t, s, "mock-secret").DescribeZones(cvm.NewDescribeZonesRequest()); err != nil {
+		t.Fatalf("expected valid signature, got %s", err)
+	}
+
+	_, err := newClient(t, s, "wrong-secret").DescribeZones(cvm.NewDescribeZonesRequest())
+	if code := errorCode(err); code != "AuthFailure.SignatureFailure" {
+		t.Fatalf("expected AuthFailure.SignatureFailure, got %v", err)
+	}
+}
+
+func TestServer_Fault(t *testing.T) {
+	s := NewServer("AKIDmock", "mock-secret")
+	defer s.Close()
+	s.Inject(Fault{Action: "DescribeZones", Code: CodeRateLimit, Times: 2})
+	client := newClient(t, s, "mock-secret")
+
+	for i := 0; i < 2; i++ {
+		_, err := client.DescribeZones(cvm.NewDescribeZonesRequest())
+		if code := errorCode(err); code != CodeRateLimit {
+			t.Fatalf("call %d: expected %s, got %v", i, CodeRateLimit, err)
+		}
+	}
+	if _, err := client.DescribeZones(cvm.NewDescribeZonesRequest()); err != nil {
+		t.Fatalf("expected fault used up, got %s", err)
+	}
+	if n := s.Failures("DescribeZones", CodeRateLimit); n != 2 {
+		t.Fatalf("expected 2 failures, got %d", n)
+	}
+	if n := s.Calls("DescribeZones"); n != 3 {
+		t.Fatalf("expected 3 calls, got %d", n)
+	}
+}
+
+func TestServer_InstanceLifecycle(t *testing.T) {
+	s := NewServer("AKIDmock", "mock-secret")
+	defer s.Close()
+	s.Polls = 1
+	client := newClient(t, s, "mock-secret")
+
+	state := func(id string) string {
+		req := cvm.NewDescribeInstancesRequest()
+		req.InstanceIds = []*string{&id}
+		resp, err := client.DescribeInstances(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *resp.Response.TotalCount == 0 {
+			return ""
+		}
+		return *resp.Response.InstanceSet[0].InstanceState
+	}
+
+	s.mu.Lock()
+	ins := &instance{Instance: &cvm.Instance{InstanceId: common.StringPtr("ins-test0001")}}
+	s.transitInstance(ins, "PENDING", "RUNNING")
+	s.region("ap-guangzhou").instances["ins-test0001"] = ins
+	s.mu.Unlock()
+
+	for _, want := range []string{"PENDING", "RUNNING"} {
+		if got := state("ins-test0001"); got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	}
+
+	req := cvm.NewStopInstancesRequest()
+	req.InstanceIds = []*string{common.StringPtr("ins-test0001")}
+	if _, err := client.StopInstances(req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.StopInstances(req); errorCode(err) != "OperationDenied.InstanceOperationInProgress" {
+		t.Fatalf("expected operation in progress, got %v", err)
+	}
+	for _, want := range []string{"STOPPING", "STOPPED"} {
+		if got := state("ins-test0001"); got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	}
+
+	terminateReq := cvm.NewTerminateInstancesRequest()
+	terminateReq.InstanceIds = []*string{common.StringPtr("ins-test0001")}
+	if _, err := client.TerminateInstances(terminateReq); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"TERMINATING", ""} {
+		if got := state("ins-test0001"); got != want {
+			t.Fatalf("expected %q, got %q", want, got)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package mockapi
+
+func init() {
+	register("sts", map[string]handler{
+		"GetCallerIdentity": getCallerIdentity,
+	})
+	register("tag", map[string]handler{
+		"TagResources": tagResources,
+	})
+}
+
+func getCallerIdentity(s *Server, r *region, body []byte) (interface{}, error) {
+	return map[string]interface{}{
+		"AccountId":   s.AccountId,
+		"PrincipalId": s.AccountId,
+		"Type":        "RootAccount",
+	}, nil
+}
+
+func tagResources(s *Server, r *region, body []byte) (interface{}, error) {
+	var req struct {
+		ResourceList []string
+		Tags         []struct {
+			TagKey   string
+			TagValue string
+		}
+	}
+	if err := decode(body, &req); err != nil {
+		return nil, err
+	}
+	if len(req.ResourceList) == 0 || len(req.Tags) == 0 {
+		return nil, errorf("MissingParameter", "ResourceList and Tags are required")
+	}
+	for _, resource := range req.ResourceList {
+		tags := s.tags[resource]
+		if tags == nil {
+			tags = make(map[string]string)
+			s.tags[resource] = tags
+		}
+		for _, tag := range req.Tags {
+			tags[tag.TagKey] = tag.TagValue
+		}
+	}
+	return map[string]interface{}{}, nil
+}
@@ -0,0 +1,400 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package mockapi
+
+import (
+	"sort"
+
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	vpc "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/vpc/v20170312"
+)
+
+func init() {
+	register("vpc", map[string]handler{
+		"CreateVpc":                   createVpc,
+		"DescribeVpcs":                describeVpcs,
+		"DeleteVpc":                   deleteVpc,
+		"AssignIpv6CidrBlock":         assignIpv6CidrBlock,
+		"CreateSubnet":                createSubnet,
+		"DescribeSubnets":             describeSubnets,
+		"DeleteSubnet":                deleteSubnet,
+		"AssignIpv6SubnetCidrBlock":   assignIpv6SubnetCidrBlock,
+		"CreateSecurityGroup":         createSecurityGroup,
+		"DescribeSecurityGroups":      describeSecurityGroups,
+		"DeleteSecurityGroup":         deleteSecurityGroup,
+		"CreateSecurityGroupPolicies": createSecurityGroupPolicies,
+		"AllocateAddresses":           allocateAddresses,
+		"DescribeAddresses":           describeAddresses,
+		"AssociateAddress":            associateAddress,
+		"DisassociateAddress":         disassociateAddress,
+		"ReleaseAddresses":            releaseAddresses,
+	})
+}
+
+func vpcFilterValues(filters []*vpc.Filter, name string) map[string]bool {
+	for _, f := range filters {
+		if f.Name != nil && *f.Name == name {
+			values := make(map[string]bool)
+			for _, v := range f.Values {
+				values[*v] = true
+			}
+			return values
+		}
+	}
+	return nil
+}
+
+// ids returns the set of ids, nil if no id given
+func ids(list []*string) map[string]bool {
+	if len(list) == 0 {
+		return nil
+	}
+	set := make(map[string]bool)
+	for _, id := range list {
+		set[*id] = true
+	}
+	return set
+}
+
+func createVpc(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewCreateVpcRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	if req.VpcName == nil || req.CidrBlock == nil {
+		return nil, errorf("MissingParameter", "VpcName and CidrBlock are required")
+	}
+	v := &vpc.Vpc{
+		VpcId:       common.StringPtr(s.id("vpc")),
+		VpcName:     req.VpcName,
+		CidrBlock:   req.CidrBlock,
+		CreatedTime: now(),
+	}
+	r.vpcs[*v.VpcId] = v
+	return map[string]interface{}{"Vpc": v}, nil
+}
+
+func describeVpcs(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewDescribeVpcsRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	vpcIds := ids(req.VpcIds)
+	names := vpcFilterValues(req.Filters, "vpc-name")
+
+	var vpcs []*vpc.Vpc
+	for id, v := range r.vpcs {
+		if (vpcIds == nil || vpcIds[id]) && (names == nil || names[*v.VpcName]) {
+			vpcs = append(vpcs, v)
+		}
+	}
+	sort.Slice(vpcs, func(i, j int) bool { return *vpcs[i].VpcId < *vpcs[j].VpcId })
+	return map[string]interface{}{"TotalCount": len(vpcs), "VpcSet": vpcs}, nil
+}
+
+func deleteVpc(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewDeleteVpcRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	if _, ok := r.vpcs[stringValue(req.VpcId)]; !ok {
+		return nil, errorf("ResourceNotFound", "vpc %s not found", stringValue(req.VpcId))
+	}
+	for _, subnet := range r.subnets {
+		if *subnet.VpcId == *req.VpcId {
+			return nil, errorf("ResourceInUse", "vpc %s has subnet %s", *req.VpcId, *subnet.SubnetId)
+		}
+	}
+	delete(r.vpcs, *req.VpcId)
+	return map[string]interface{}{}, nil
+}
+
+func assignIpv6CidrBlock(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewAssignIpv6CidrBlockRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	v, ok := r.vpcs[stringValue(req.VpcId)]
+	if !ok {
+		return nil, errorf("ResourceNotFound", "vpc %s not found", stringValue(req.VpcId))
+	}
+	if v.Ipv6CidrBlock != nil && *v.Ipv6CidrBlock != "" {
+		return nil, errorf("LimitExceeded", "vpc %s already has ipv6 cidr block", *v.VpcId)
+	}
+	v.Ipv6CidrBlock = common.StringPtr("2402:4e00:1000::/56")
+	return map[string]interface{}{"Ipv6CidrBlock": v.Ipv6CidrBlock}, nil
+}
+
+func createSubnet(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewCreateSubnetRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	if _, ok := r.vpcs[stringValue(req.VpcId)]; !ok {
+		return nil, errorf("ResourceNotFound", "vpc %s not found", stringValue(req.VpcId))
+	}
+	if !r.validZone(stringValue(req.Zone)) {
+		return nil, errorf("InvalidParameterValue.Range", "invalid zone %s", stringValue(req.Zone))
+	}
+	subnet := &vpc.Subnet{
+		SubnetId:    common.StringPtr(s.id("subnet")),
+		SubnetName:  req.SubnetName,
+		VpcId:       req.VpcId,
+		CidrBlock:   req.CidrBlock,
+		Zone:        req.Zone,
+		CreatedTime: now(),
+	}
+	r.subnets[*subnet.SubnetId] = subnet
+	return map[string]interface{}{"Subnet": subnet}, nil
+}
+
+func describeSubnets(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewDescribeSubnetsRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	subnetIds := ids(req.SubnetIds)
+	vpcIds := vpcFilterValues(req.Filters, "vpc-id")
+	zones := vpcFilterValues(req.Filters, "zone")
+	names := vpcFilterValues(req.Filters, "subnet-name")
+
+	var subnets []*vpc.Subnet
+	for id, subnet := range r.subnets {
+		if (subnetIds == nil || subnetIds[id]) && (vpcIds == nil || vpcIds[*subnet.VpcId]) &&
+			(zones == nil || zones[*subnet.Zone]) && (names == nil || names[stringValue(subnet.SubnetName)]) {
+			subnets = append(subnets, subnet)
+		}
+	}
+	sort.Slice(subnets, func(i, j int) bool { return *subnets[i].SubnetId < *subnets[j].SubnetId })
+	return map[string]interface{}{"TotalCount": len(subnets), "SubnetSet": subnets}, nil
+}
+
+func deleteSubnet(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewDeleteSubnetRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	if _, ok := r.subnets[stringValue(req.SubnetId)]; !ok {
+		return nil, errorf("ResourceNotFound", "subnet %s not found", stringValue(req.SubnetId))
+	}
+	for _, ins := range r.instances {
+		if ins.terminating() {
+			continue
+		}
+		if ins.VirtualPrivateCloud != nil && stringValue(ins.VirtualPrivateCloud.SubnetId) == *req.SubnetId {
+			return nil, errorf("ResourceInUse", "subnet %s is used by instance %s", *req.SubnetId, *ins.InstanceId)
+		}
+	}
+	delete(r.subnets, *req.SubnetId)
+	return map[string]interface{}{}, nil
+}
+
+func assignIpv6SubnetCidrBlock(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewAssignIpv6SubnetCidrBlockRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	v, ok := r.vpcs[stringValue(req.VpcId)]
+	if !ok {
+		return nil, errorf("ResourceNotFound", "vpc %s not found", stringValue(req.VpcId))
+	}
+	if v.Ipv6CidrBlock == nil || *v.Ipv6CidrBlock == "" {
+		return nil, errorf("UnsupportedOperation", "vpc %s has no ipv6 cidr block", *v.VpcId)
+	}
+	for _, block := range req.Ipv6SubnetCidrBlocks {
+		subnet, ok := r.subnets[stringValue(block.SubnetId)]
+		if !ok {
+			return nil, errorf("ResourceNotFound", "subnet %s not found", stringValue(block.SubnetId))
+		}
+		subnet.Ipv6CidrBlock = block.Ipv6CidrBlock
+	}
+	return map[string]interface{}{"Ipv6SubnetCidrBlockSet": req.Ipv6SubnetCidrBlocks}, nil
+}
+
+func createSecurityGroup(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewCreateSecurityGroupRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	sg := &vpc.SecurityGroup{
+		SecurityGroupId:   common.StringPtr(s.id("sg")),
+		SecurityGroupName: req.GroupName,
+		SecurityGroupDesc: req.GroupDescription,
+		ProjectId:         req.ProjectId,
+		CreatedTime:       now(),
+	}
+	r.securityGroups[*sg.SecurityGroupId] = sg
+	return map[string]interface{}{"SecurityGroup": sg}, nil
+}
+
+func describeSecurityGroups(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewDescribeSecurityGroupsRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	sgIds := ids(req.SecurityGroupIds)
+	names := vpcFilterValues(req.Filters, "security-group-name")
+
+	var sgs []*vpc.SecurityGroup
+	for id, sg := range r.securityGroups {
+		if (sgIds == nil || sgIds[id]) && (names == nil || names[stringValue(sg.SecurityGroupName)]) {
+			sgs = append(sgs, sg)
+		}
+	}
+	sort.Slice(sgs, func(i, j int) bool { return *sgs[i].SecurityGroupId < *sgs[j].SecurityGroupId })
+	return map[string]interface{}{"TotalCount": len(sgs), "SecurityGroupSet": sgs}, nil
+}
+
+func deleteSecurityGroup(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewDeleteSecurityGroupRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	if _, ok := r.securityGroups[stringValue(req.SecurityGroupId)]; !ok {
+		return nil, errorf("ResourceNotFound", "security group %s not found", stringValue(req.SecurityGroupId))
+	}
+	for _, ins := range r.instances {
+		if ins.terminating() {
+			continue
+		}
+		if containsString(ins.SecurityGroupIds, *req.SecurityGroupId) {
+			return nil, errorf("ResourceInUse", "security group %s is used by instance %s", *req.SecurityGroupId, *ins.InstanceId)
+		}
+	}
+	delete(r.securityGroups, *req.SecurityGroupId)
+	return map[string]interface{}{}, nil
+}
+
+func createSecurityGroupPolicies(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewCreateSecurityGroupPoliciesRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	if _, ok := r.securityGroups[stringValue(req.SecurityGroupId)]; !ok {
+		return nil, errorf("ResourceNotFound", "security group %s not found", stringValue(req.SecurityGroupId))
+	}
+	if req.SecurityGroupPolicySet == nil ||
+		len(req.SecurityGroupPolicySet.Ingress)+len(req.SecurityGroupPolicySet.Egress) == 0 {
+		return nil, errorf("InvalidParameter", "no security group policy")
+	}
+	return map[string]interface{}{}, nil
+}
+
+func allocateAddresses(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewAllocateAddressesRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	count := int64(1)
+	if req.AddressCount != nil {
+		count = *req.AddressCount
+	}
+	var addressIds []*string
+	for i := int64(0); i < count; i++ {
+		addr := &address{Address: &vpc.Address{
+			AddressId:     common.StringPtr(s.id("eip")),
+			AddressName:   req.AddressName,
+			AddressIp:     common.StringPtr(hostIp("198.51.100.0/24", s.nextId)),
+			AddressStatus: common.StringPtr("CREATING"),
+			CreatedTime:   now(),
+		}}
+		addr.t = &transition{next: "UNBIND", polls: s.Polls}
+		r.addresses[*addr.AddressId] = addr
+		addressIds = append(addressIds, addr.AddressId)
+	}
+	return map[string]interface{}{"AddressSet": addressIds, "TaskId": s.id("task")}, nil
+}
+
+func describeAddresses(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewDescribeAddressesRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	r.settleAddresses()
+
+	addressIds := ids(req.AddressIds)
+	ips := vpcFilterValues(req.Filters, "address-ip")
+
+	var addresses []*vpc.Address
+	for id, addr := range r.addresses {
+		if (addressIds == nil || addressIds[id]) && (ips == nil || ips[*addr.AddressIp]) {
+			addresses = append(addresses, addr.Address)
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool { return *addresses[i].AddressId < *addresses[j].AddressId })
+	return map[string]interface{}{"TotalCount": len(addresses), "AddressSet": addresses}, nil
+}
+
+// getAddress returns the address which has no operation in progress
+func (r *region) getAddress(id string) (*address, error) {
+	addr, ok := r.addresses[id]
+	if !ok {
+		return nil, errorf("InvalidAddressId.NotFound", "address %s not found", id)
+	}
+	if addr.t != nil {
+		return nil, errorf("InvalidAddressIdStatus.NotPermit", "address %s is %s", id, *addr.AddressStatus)
+	}
+	return addr, nil
+}
+
+func associateAddress(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewAssociateAddressRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	addr, err := r.getAddress(stringValue(req.AddressId))
+	if err != nil {
+		return nil, err
+	}
+	if *addr.AddressStatus != "UNBIND" {
+		return nil, errorf("InvalidAddressIdStatus.NotPermit", "address %s is %s", *addr.AddressId, *addr.AddressStatus)
+	}
+	if _, ok := r.instances[stringValue(req.InstanceId)]; !ok {
+		return nil, errorf("InvalidInstanceId.NotFound", "instance %s not found", stringValue(req.InstanceId))
+	}
+	addr.InstanceId = req.InstanceId
+	addr.AddressStatus = common.StringPtr("BINDING")
+	addr.t = &transition{next: "BIND", polls: s.Polls}
+	return map[string]interface{}{"TaskId": s.id("task")}, nil
+}
+
+func disassociateAddress(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewDisassociateAddressRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	addr, err := r.getAddress(stringValue(req.AddressId))
+	if err != nil {
+		return nil, err
+	}
+	if *addr.AddressStatus != "BIND" {
+		return nil, errorf("InvalidAddressIdStatus.NotPermit", "address %s is %s", *addr.AddressId, *addr.AddressStatus)
+	}
+	addr.InstanceId = nil
+	addr.AddressStatus = common.StringPtr("UNBINDING")
+	addr.t = &transition{next: "UNBIND", polls: s.Polls}
+	return map[string]interface{}{"TaskId": s.id("task")}, nil
+}
+
+func releaseAddresses(s *Server, r *region, body []byte) (interface{}, error) {
+	req := vpc.NewReleaseAddressesRequest()
+	if err := decode(body, req); err != nil {
+		return nil, err
+	}
+	var addresses []*address
+	for _, id := range req.AddressIds {
+		addr, err := r.getAddress(*id)
+		if err != nil {
+			return nil, err
+		}
+		if *addr.AddressStatus != "UNBIND" {
+			return nil, errorf("InvalidAddressIdStatus.NotPermit", "address %s is %s", *addr.AddressId, *addr.AddressStatus)
+		}
+		addresses = append(addresses, addr)
+	}
+	for _, addr := range addresses {
+		delete(r.addresses, *addr.AddressId)
+	}
+	return map[string]interface{}{"TaskId": s.id("task")}, nil
+}
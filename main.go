@@ -9,13 +9,23 @@ import (
 
 	"github.com/hashicorp/packer-plugin-sdk/plugin"
 
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cbs"
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/chroot"
 	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/lighthouse"
 	"github.com/hashicorp/packer-plugin-tencentcloud/version"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "sweep" {
+		os.Exit(runSweep(os.Args[2:]))
+	}
+
 	pps := plugin.NewSet()
 	pps.RegisterBuilder("cvm", new(cvm.Builder))
+	pps.RegisterBuilder("chroot", new(chroot.Builder))
+	pps.RegisterBuilder("cbs", new(cbs.Builder))
+	pps.RegisterBuilder("lighthouse", new(lighthouse.Builder))
 	pps.SetVersion(version.PluginVersion)
 	err := pps.Run()
 	if err != nil {
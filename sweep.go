@@ -0,0 +1,94 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/hashicorp/packer-plugin-tencentcloud/builder/tencentcloud/cvm"
+)
+
+const sweepUsage = `Usage: packer-plugin-tencentcloud sweep [options]
+
+  Finds the instances, key pairs, security groups, subnets and vpcs left by
+  interrupted builds, which are tagged with packer-build-id or named with
+  prefix packer_, prints them and deletes them in dependency order.
+
+  The credentials are read from TENCENTCLOUD_SECRET_ID and
+  TENCENTCLOUD_SECRET_KEY.
+
+Options:
+`
+
+// runSweep runs the sweep subcommand and returns the exit code
+func runSweep(args []string) int {
+	flags := flag.NewFlagSet("sweep", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), sweepUsage)
+		flags.PrintDefaults()
+	}
+	regions := flags.String("regions", "", "Comma separated regions to sweep, all regions by default")
+	olderThan := flags.Duration("older-than", 6*time.Hour, "Only sweep resources created before this duration")
+	dryRun := flags.Bool("dry-run", false, "Only print the resources to be deleted")
+	internalEndpoint := flags.Bool("internal-endpoint", false, "Call the apis through the private network endpoints")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	ui := &packersdk.BasicUi{
+		Reader:      os.Stdin,
+		Writer:      os.Stdout,
+		ErrorWriter: os.Stderr,
+	}
+
+	sweeper := &cvm.Sweeper{
+		SecretId:  os.Getenv("TENCENTCLOUD_SECRET_ID"),
+		SecretKey: os.Getenv("TENCENTCLOUD_SECRET_KEY"),
+		OlderThan: *olderThan,
+		Ui:        ui,
+
+		UseInternalEndpoint: *internalEndpoint,
+	}
+	if sweeper.SecretId == "" || sweeper.SecretKey == "" {
+		ui.Error("TENCENTCLOUD_SECRET_ID and TENCENTCLOUD_SECRET_KEY must be set")
+		return 1
+	}
+	if *regions != "" {
+		sweeper.Regions = strings.Split(*regions, ",")
+	}
+
+	ctx := context.Background()
+	resources, err := sweeper.Find(ctx)
+	if err != nil {
+		ui.Error(err.Error())
+		return 1
+	}
+	if len(resources) == 0 {
+		ui.Say("No orphaned resources found")
+		return 0
+	}
+
+	ui.Say(fmt.Sprintf("Found %d orphaned resources:", len(resources)))
+	for _, r := range resources {
+		ui.Message(r.String())
+	}
+	if *dryRun {
+		return 0
+	}
+
+	if err := sweeper.Sweep(ctx, resources); err != nil {
+		ui.Error(err.Error())
+		return 1
+	}
+	ui.Say("All orphaned resources deleted")
+
+	return 0
+}